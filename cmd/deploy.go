@@ -1,8 +1,17 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/spf13/cobra"
 
@@ -12,11 +21,9 @@ import (
 	"github.com/Shopify/themekit/src/shopify"
 )
 
-const settingsDataKey = "config/settings_data.json"
-
 var (
 	deployCmd = &cobra.Command{
-		Use:   "deploy <filenames>",
+		Use:   "deploy <filenames|->",
 		Short: "deploy files to shopify",
 		Long: `Deploy will overwrite specific files if provided with file names.
  If deploy is not provided with file names then it will deploy all
@@ -24,6 +31,13 @@ var (
  exist on your local machine will be removed from shopify unless the --soft
  flag is passed
 
+ config/settings_data.json is not deployed unless --settings-data is set
+ to merge or overwrite, or the file is named on its own, so that the
+ settings on shopify are kept.
+
+ If the filename is - then a single file is read from stdin and deployed
+ with the key set by --key, as text unless --binary is passed.
+
  For more documentation please see http://shopify.github.io/themekit/commands/#deploy
  `,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -68,11 +82,37 @@ var (
 			return cmdutil.ForEachClient(flags, args, deploy)
 		},
 	}
+
+	stdin = &stdinBuffer{r: os.Stdin}
 )
 
+// stdinBuffer reads stdin once so that the same contents can be deployed to every
+// environment.
+type stdinBuffer struct {
+	r    io.Reader
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func (s *stdinBuffer) Reader() (io.Reader, error) {
+	s.once.Do(func() { s.data, s.err = ioutil.ReadAll(s.r) })
+	return bytes.NewReader(s.data), s.err
+}
+
 func deploy(ctx *cmdutil.Ctx) error {
 	if ctx.Env.ReadOnly {
 		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	} else if err := cmdutil.CheckTheme(ctx); err != nil {
+		return err
+	}
+
+	for _, arg := range ctx.Args {
+		if arg == "-" && len(ctx.Args) > 1 {
+			return fmt.Errorf("[%s] - cannot be deployed with other files", colors.Green(ctx.Env.Name))
+		} else if arg == "-" {
+			return deployStdin(ctx)
+		}
 	}
 
 	assetsActions, err := generateActions(ctx)
@@ -80,24 +120,207 @@ func deploy(ctx *cmdutil.Ctx) error {
 		return err
 	}
 
-	var deployGroup sync.WaitGroup
+	if ctx.Flags.CheckReferences {
+		if err := checkReferences(ctx, assetsActions); err != nil {
+			return err
+		}
+	}
+
+	var state *deployState
+	if ctx.Flags.Resume && !ctx.Env.DryRun {
+		if state, err = loadDeployState(ctx.Env); err != nil {
+			return fmt.Errorf("[%s] could not load the deploy state: %s", colors.Green(ctx.Env.Name), err)
+		}
+	}
+
+	checksums, err := ctx.Client.GetAssetChecksums()
+	if err != nil {
+		// without checksums every file will be uploaded
+		checksums = map[string]string{}
+	}
+
+	var failed int32
 	ctx.StartProgress(len(assetsActions))
+	for _, group := range deployGroups(assetsActions) {
+		var deployGroup sync.WaitGroup
+		for _, path := range group {
+			deployGroup.Add(1)
+			go func(path string, op file.Op) {
+				defer deployGroup.Done()
+				if !deployPath(ctx, path, op, checksums, state) {
+					atomic.AddInt32(&failed, 1)
+				}
+			}(path, assetsActions[path])
+		}
+		deployGroup.Wait()
+	}
+
+	if state != nil && failed == 0 {
+		// the deploy is complete so there is nothing left to resume
+		if err := state.clear(); err != nil {
+			return fmt.Errorf("[%s] could not remove the deploy state: %s", colors.Green(ctx.Env.Name), err)
+		}
+	}
+	return nil
+}
+
+// deployStdin will deploy the contents of stdin as the file set by the key flag.
+func deployStdin(ctx *cmdutil.Ctx) error {
+	if ctx.Flags.Key == "" {
+		return fmt.Errorf("[%s] --key is required to deploy a file from stdin", colors.Green(ctx.Env.Name))
+	}
+
+	r, err := stdin.Reader()
+	if err != nil {
+		return fmt.Errorf("[%s] could not read stdin: %s", colors.Green(ctx.Env.Name), err)
+	}
+
+	if err := ctx.Client.UpdateAssetFromReaderAs(ctx.Flags.Key, r, ctx.Flags.Binary); err != nil {
+		return fmt.Errorf("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(ctx.Flags.Key), err)
+	}
+	ctx.Logf(cmdutil.LevelVerbose, "[%s] Updated %s", colors.Green(ctx.Env.Name), colors.Blue(ctx.Flags.Key))
+	return nil
+}
+
+// checkReferences will make sure that the liquid files being deployed do not refer
+// to snippets or sections that will not exist on shopify once the deploy is done.
+// Every missing reference is reported before the deploy is stopped.
+func checkReferences(ctx *cmdutil.Ctx, assetsActions map[string]file.Op) error {
+	remoteFiles, err := ctx.Client.GetAllAssets()
+	if err != nil {
+		return err
+	}
+
+	existing := []string{}
+	for _, key := range remoteFiles {
+		if op, found := assetsActions[key]; !found || op != file.Remove {
+			existing = append(existing, key)
+		}
+	}
+
+	local := map[string]shopify.Asset{}
 	for path, op := range assetsActions {
-		if path == settingsDataKey {
-			defer perform(ctx, path, op)
+		if op != file.Update || !strings.HasSuffix(path, ".liquid") {
 			continue
 		}
-		deployGroup.Add(1)
-		go func(path string, op file.Op) {
-			defer deployGroup.Done()
-			perform(ctx, path, op)
-		}(path, op)
+		asset, err := shopify.ReadAsset(ctx.Env, path)
+		if err != nil {
+			return err
+		}
+		local[path] = asset
 	}
 
-	deployGroup.Wait()
+	missing := shopify.FindMissingReferences(local, existing...)
+	for _, ref := range missing {
+		ctx.Err("[%s] %s", colors.Green(ctx.Env.Name), ref)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("[%s] not deploying because of %d missing references", colors.Green(ctx.Env.Name), len(missing))
+	}
 	return nil
 }
 
+// deployGroups will split the paths into groups that have to be deployed one after
+// the other. Updates are grouped by their upload priority so that the files that
+// other files depend on exist first, and removals are done once everything is
+// uploaded.
+func deployGroups(assetsActions map[string]file.Op) [][]string {
+	byPriority := map[int][]string{}
+	priorities := []int{}
+	for path, op := range assetsActions {
+		priority := shopify.UploadPriority(path)
+		if op == file.Remove {
+			// removals go after every update
+			priority = shopify.UploadPriority(shopify.SettingsDataKey) + 1
+		}
+		if _, ok := byPriority[priority]; !ok {
+			priorities = append(priorities, priority)
+		}
+		byPriority[priority] = append(byPriority[priority], path)
+	}
+
+	sort.Ints(priorities)
+	groups := make([][]string, 0, len(priorities))
+	for _, priority := range priorities {
+		sort.Strings(byPriority[priority])
+		groups = append(groups, byPriority[priority])
+	}
+	return groups
+}
+
+// deployPath will perform the operation on the path unless it is an update to
+// a file that has the same content as the remote file, or that was already
+// uploaded by the deploy being resumed. It reports if the operation succeeded.
+func deployPath(ctx *cmdutil.Ctx, path string, op file.Op, checksums map[string]string, state *deployState) bool {
+	if path == shopify.SettingsDataKey && op == file.Update && ctx.Env.SettingsData == "merge" {
+		return mergeSettingsData(ctx)
+	} else if op != file.Update {
+		return perform(ctx, path, op)
+	}
+
+	checksum := localChecksum(ctx, path)
+	if remoteChecksum, found := checksums[path]; found && checksum != "" && checksum == remoteChecksum {
+		ctx.DoneTask()
+		ctx.Logf(cmdutil.LevelVerbose, "[%s] Skipped %s (unchanged)", colors.Green(ctx.Env.Name), colors.Blue(path))
+		return true
+	} else if state == nil || checksum == "" {
+		return perform(ctx, path, op)
+	} else if state.uploaded(path, checksum) {
+		ctx.DoneTask()
+		ctx.Logf(cmdutil.LevelVerbose, "[%s] Skipped %s (already deployed)", colors.Green(ctx.Env.Name), colors.Blue(path))
+		return true
+	} else if !perform(ctx, path, op) {
+		return false
+	} else if err := state.record(path, checksum); err != nil {
+		ctx.Err("[%s] could not save the deploy state: %s", colors.Green(ctx.Env.Name), err)
+	}
+	return true
+}
+
+// mergeSettingsData will update the settings data with the local file while keeping
+// the current settings from shopify. It reports if the update succeeded.
+func mergeSettingsData(ctx *cmdutil.Ctx) bool {
+	defer ctx.DoneTask()
+
+	local, err := shopify.ReadAsset(ctx.Env, shopify.SettingsDataKey)
+	if err != nil {
+		ctx.Err("[%s] error loading %s: %s", colors.Green(ctx.Env.Name), colors.Green(shopify.SettingsDataKey), colors.Red(err))
+		return false
+	}
+
+	asset := local
+	if remote, err := ctx.Client.GetAsset(shopify.SettingsDataKey); err == nil {
+		if asset, err = shopify.MergeSettingsData(local, remote); err != nil {
+			ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(shopify.SettingsDataKey), err)
+			return false
+		}
+	} else if !errors.Is(err, shopify.ErrNotPartOfTheme) {
+		ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(shopify.SettingsDataKey), err)
+		return false
+	}
+
+	if err := ctx.Client.UpdateAsset(asset); err != nil {
+		ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key), err)
+		return false
+	}
+	ctx.Logf(cmdutil.LevelVerbose, "[%s] Merged %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+	return true
+}
+
+// localChecksum will return the checksum of the local file, or an empty string if
+// it could not be read.
+func localChecksum(ctx *cmdutil.Ctx, path string) string {
+	asset, err := shopify.ReadAsset(ctx.Env, path)
+	if err != nil {
+		return ""
+	}
+	checksum, err := asset.ComputeChecksum()
+	if err != nil {
+		return ""
+	}
+	return checksum
+}
+
 func generateActions(ctx *cmdutil.Ctx) (map[string]file.Op, error) {
 	assetsActions := map[string]file.Op{}
 
@@ -111,13 +334,51 @@ func generateActions(ctx *cmdutil.Ctx) (map[string]file.Op, error) {
 		}
 	}
 
-	localAssets, err := shopify.FindAssets(ctx.Env, ctx.Args...)
+	localAssets, err := shopify.FindAssets(ctx.Env)
 	if err != nil {
 		return assetsActions, err
 	}
+	if len(ctx.Args) > 0 {
+		localAssets = shopify.SelectAssets(ctx.Env.Directory, localAssets, ctx.Args...)
+		if len(localAssets) == 0 {
+			return assetsActions, fmt.Errorf("[%s] no files to deploy match %s", colors.Green(ctx.Env.Name), strings.Join(ctx.Args, ", "))
+		}
+	}
 
 	for _, path := range localAssets {
 		assetsActions[path] = file.Update
 	}
+
+	op, found := assetsActions[shopify.SettingsDataKey]
+	switch ctx.Env.SettingsData {
+	case "overwrite":
+	case "merge":
+		if found && op == file.Remove {
+			delete(assetsActions, shopify.SettingsDataKey)
+		}
+	case "", "skip":
+		if found && (op != file.Update || !namesSettingsData(ctx)) {
+			delete(assetsActions, shopify.SettingsDataKey)
+			if op == file.Update {
+				ctx.Logf(cmdutil.LevelNormal, "[%s] Skipped %s to keep the theme settings, use --settings-data=merge or --settings-data=overwrite to deploy it", colors.Green(ctx.Env.Name), colors.Blue(shopify.SettingsDataKey))
+			}
+		}
+	default:
+		return assetsActions, fmt.Errorf("[%s] invalid settings_data %s, it must be 'skip', 'merge' or 'overwrite'", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.SettingsData))
+	}
+
 	return assetsActions, nil
 }
+
+// namesSettingsData reports if the settings data was named as an argument on its own
+// rather than as part of a directory, which is a request to deploy it.
+func namesSettingsData(ctx *cmdutil.Ctx) bool {
+	for _, arg := range ctx.Args {
+		if filepath.Base(arg) != filepath.Base(shopify.SettingsDataKey) {
+			continue
+		} else if len(shopify.SelectAssets(ctx.Env.Directory, []string{shopify.SettingsDataKey}, arg)) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Shopify/themekit/src/env"
+)
+
+// deployState records the files that a deploy uploaded so that a deploy that was
+// interrupted can skip them when it is run again. Every file is recorded with the
+// checksum of the local contents that were uploaded so that a file that changed
+// since is uploaded again. The state belongs to a single theme.
+type deployState struct {
+	ThemeID  string            `json:"theme_id"`
+	Uploaded map[string]string `json:"uploaded"`
+	path     string
+	mu       sync.Mutex
+}
+
+// deployStatePath will return where the state of deploys to the theme of the
+// environment is kept, in the cache directory or the user cache directory.
+func deployStatePath(e *env.Env) (string, error) {
+	dir := e.CacheDir
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(userDir, "themekit")
+	}
+	themeID := e.ThemeID
+	if themeID == "" {
+		themeID = "live"
+	}
+	return filepath.Join(dir, "deploys", e.Domain, themeID+".json"), nil
+}
+
+// loadDeployState will read the state of the last deploy to the theme. A missing or
+// unreadable state, or the state of another theme, starts a new one.
+func loadDeployState(e *env.Env) (*deployState, error) {
+	path, err := deployStatePath(e)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &deployState{}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(data, state)
+	}
+	if state.ThemeID != e.ThemeID || state.Uploaded == nil {
+		state.ThemeID, state.Uploaded = e.ThemeID, map[string]string{}
+	}
+	state.path = path
+	return state, nil
+}
+
+// uploaded reports if the file was already uploaded with the same contents.
+func (state *deployState) uploaded(key, checksum string) bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	uploaded, found := state.Uploaded[key]
+	return found && uploaded == checksum
+}
+
+// record will add the uploaded file to the state and save it, replacing the state
+// file at once so that an interrupted save does not leave a partial state behind.
+func (state *deployState) record(key, checksum string) error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.Uploaded[key] = checksum
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(state.path), 0755); err != nil {
+		return err
+	}
+	tmp := state.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, state.path)
+}
+
+// clear will remove the state once the deploy is complete.
+func (state *deployState) clear() error {
+	if err := os.Remove(state.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
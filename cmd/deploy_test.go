@@ -1,13 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/cmdutil/_mocks"
 	"github.com/Shopify/themekit/src/file"
 	"github.com/Shopify/themekit/src/shopify"
 )
@@ -30,31 +39,323 @@ func TestUpload(t *testing.T) {
 		assert.Contains(t, err.Error(), "environment is readonly")
 	}
 
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Args = []string{"templates/layout.liquid"}
+	ctx.Env.ThemeID = "123"
+	ctx.Themes = []shopify.Theme{{ID: 456}}
+	err = deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "theme get --list")
+	}
+	client.AssertNotCalled(t, "UpdateAsset", shopify.Asset{Key: "templates/layout.liquid"})
+
 	ctx, client, _, stdOut, _ := createTestCtx()
 	ctx.Args = []string{"assets/app.js"}
 	ctx.Flags.NoDelete = true
 	ctx.Env.Directory = "_testdata/projectdir"
 	ctx.Flags.Verbose = true
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
 	client.On("UpdateAsset", shopify.Asset{Key: "assets/app.js"}).Return(nil)
 	err = deploy(ctx)
 	assert.Nil(t, err)
 	assert.Contains(t, stdOut.String(), "Updated assets/app.js")
+	assert.False(t, ctx.Failed())
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	ctx.Env.Directory = "_testdata/projectdir"
+	ctx.Flags.Verbose = true
+	ctx.Flags.NoDelete = true
+	ctx.Env.SettingsData = "overwrite"
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("UpdateAsset", shopify.Asset{Key: "assets/app.js"}).Return(fmt.Errorf("server error"))
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool { return a.Key == "config/settings_data.json" })).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdErr.String(), "server error")
+	assert.True(t, ctx.Failed())
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = "_testdata/projectdir"
+	ctx.Flags.Verbose = true
+	ctx.Flags.NoDelete = true
+	ctx.Env.SettingsData = "overwrite"
+	client.On("GetAssetChecksums").Return(map[string]string{"assets/app.js": "d41d8cd98f00b204e9800998ecf8427e", "config/settings_data.json": "outdated"}, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool { return a.Key == "config/settings_data.json" })).Return(nil)
+	err = deploy(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, stdOut.String(), "Skipped assets/app.js (unchanged)")
+	assert.Contains(t, stdOut.String(), "Updated config/settings_data.json")
+	client.AssertExpectations(t)
 
 	ctx, client, _, stdOut, _ = createTestCtx()
 	ctx.Env.Directory = "_testdata/projectdir"
 	ctx.Flags.Verbose = true
 	ctx.Flags.NoDelete = true
+	ctx.Env.SettingsData = "overwrite"
+	client.On("GetAssetChecksums").Return(map[string]string{}, fmt.Errorf("server error"))
 	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool { return true })).Return(nil)
 	err = deploy(ctx)
 	assert.Nil(t, err)
 	assert.Contains(t, stdOut.String(), "Updated config/settings_data.json")
 }
 
+func TestDeployStdin(t *testing.T) {
+	defer func(original *stdinBuffer) { stdin = original }(stdin)
+	stdin = &stdinBuffer{r: strings.NewReader("{{ content_for_layout }}")}
+
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Args = []string{"-"}
+	ctx.Flags.Key = "layout/generated.liquid"
+	ctx.Flags.Verbose = true
+	client.On("UpdateAssetFromReaderAs", "layout/generated.liquid", mock.MatchedBy(func(r io.Reader) bool {
+		data, _ := ioutil.ReadAll(r)
+		return string(data) == "{{ content_for_layout }}"
+	}), false).Return(nil).Twice()
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdOut.String(), "Updated layout/generated.liquid")
+	assert.Nil(t, deploy(ctx))
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "GetAllAssets")
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Args = []string{"-"}
+	ctx.Flags.Key = "assets/data.bin"
+	ctx.Flags.Binary = true
+	client.On("UpdateAssetFromReaderAs", "assets/data.bin", mock.Anything, true).Return(fmt.Errorf("server error"))
+	err := deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "server error")
+	}
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Args = []string{"-"}
+	err = deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "--key is required")
+	}
+
+	ctx.Args = []string{"-", "assets/app.js"}
+	ctx.Flags.Key = "assets/other.js"
+	err = deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "cannot be deployed with other files")
+	}
+	client.AssertNotCalled(t, "UpdateAssetFromReaderAs", mock.Anything, mock.Anything, mock.Anything)
+
+	stdin = &stdinBuffer{r: iotest.ErrReader(fmt.Errorf("closed pipe"))}
+	ctx.Args = []string{"-"}
+	err = deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not read stdin: closed pipe")
+	}
+}
+
+func TestDeployResume(t *testing.T) {
+	project, err := ioutil.TempDir("", "project")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(project)
+	cache, err := ioutil.TempDir("", "cache")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(cache)
+	os.MkdirAll(filepath.Join(project, "assets"), 0755)
+	for _, name := range []string{"a.js", "b.js", "c.js"} {
+		ioutil.WriteFile(filepath.Join(project, "assets", name), []byte("alert('"+name+"')"), 0644)
+	}
+	statePath := filepath.Join(cache, "deploys", "shop.myshopify.com", "123.json")
+	newCtx := func() (*cmdutil.Ctx, *mocks.ShopifyClient) {
+		ctx, client, _, _, _ := createTestCtx()
+		ctx.Env.Directory = project
+		ctx.Env.CacheDir = cache
+		ctx.Env.Domain = "shop.myshopify.com"
+		ctx.Env.ThemeID = "123"
+		ctx.Flags.NoDelete = true
+		ctx.Flags.Resume = true
+		ctx.Themes = []shopify.Theme{{ID: 123}, {ID: 456}}
+		client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+		return ctx, client
+	}
+	key := func(key string) interface{} {
+		return mock.MatchedBy(func(a shopify.Asset) bool { return a.Key == key })
+	}
+
+	ctx, client := newCtx()
+	client.On("UpdateAsset", key("assets/a.js")).Return(nil)
+	client.On("UpdateAsset", key("assets/b.js")).Return(fmt.Errorf("connection reset"))
+	client.On("UpdateAsset", key("assets/c.js")).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	state, err := loadDeployState(ctx.Env)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"assets/a.js", "assets/c.js"}, sortedKeys(state.Uploaded))
+	}
+
+	ioutil.WriteFile(filepath.Join(project, "assets", "c.js"), []byte("alert('changed')"), 0644)
+	ctx, client = newCtx()
+	ctx.Flags.Verbose = true
+	stdOut := ctx.Log.Writer().(*bytes.Buffer)
+	client.On("UpdateAsset", key("assets/b.js")).Return(nil)
+	client.On("UpdateAsset", key("assets/c.js")).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	client.AssertNotCalled(t, "UpdateAsset", key("assets/a.js"))
+	client.AssertNumberOfCalls(t, "UpdateAsset", 2)
+	assert.Contains(t, stdOut.String(), "Skipped assets/a.js (already deployed)")
+	_, err = os.Stat(statePath)
+	assert.True(t, os.IsNotExist(err))
+
+	ctx, client = newCtx()
+	client.On("UpdateAsset", mock.Anything).Return(nil)
+	state, _ = loadDeployState(ctx.Env)
+	state.record("assets/a.js", localChecksum(ctx, "assets/a.js"))
+	ctx.Env.ThemeID = "456"
+	assert.Nil(t, deploy(ctx))
+	client.AssertNumberOfCalls(t, "UpdateAsset", 3)
+	_, err = os.Stat(statePath)
+	assert.Nil(t, err)
+
+	ctx, client = newCtx()
+	ctx.Flags.Resume = false
+	client.On("UpdateAsset", mock.Anything).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	client.AssertNumberOfCalls(t, "UpdateAsset", 3)
+	_, err = os.Stat(statePath)
+	assert.Nil(t, err)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestDeploySubset(t *testing.T) {
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Args = []string{filepath.Join("_testdata", "projectdir", "assets")}
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Env.IgnoredFiles = []string{".gitkeep"}
+	ctx.Flags.Verbose = true
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("UpdateAsset", shopify.Asset{Key: "assets/app.js"}).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdOut.String(), "Updated assets/app.js")
+	client.AssertNotCalled(t, "GetAllAssets")
+	client.AssertNumberOfCalls(t, "UpdateAsset", 1)
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Args = []string{"assets/nope.js"}
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	err := deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no files to deploy match assets/nope.js")
+	}
+	client.AssertNotCalled(t, "UpdateAsset", mock.Anything)
+}
+
+func TestDeployCheckReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themekit-references")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	os.MkdirAll(filepath.Join(dir, "snippets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "templates", "page.liquid"), []byte("{% render 'title' %}\n{% render 'icon' %}\n{% section 'footer' %}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "title.liquid"), []byte("title"), 0644)
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.CheckReferences = true
+	client.On("GetAllAssets").Return([]string{"snippets/icon.liquid", "sections/footer.liquid"}, nil)
+	err = deploy(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "not deploying because of 2 missing references")
+	}
+	assert.Contains(t, stdErr.String(), "templates/page.liquid:2 refers to snippets/icon.liquid which does not exist")
+	assert.Contains(t, stdErr.String(), "templates/page.liquid:3 refers to sections/footer.liquid which does not exist")
+	client.AssertNotCalled(t, "UpdateAsset", mock.Anything)
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"templates"}
+	ctx.Flags.CheckReferences = true
+	client.On("GetAllAssets").Return([]string{"snippets/icon.liquid", "snippets/title.liquid", "sections/footer.liquid"}, nil)
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("UpdateAsset", mock.Anything).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	client.AssertNumberOfCalls(t, "UpdateAsset", 1)
+}
+
+func TestDeploySettingsData(t *testing.T) {
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = "_testdata/projectdir"
+	ctx.Flags.Verbose = true
+	ctx.Flags.NoDelete = true
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("UpdateAsset", shopify.Asset{Key: "assets/app.js"}).Return(nil)
+	err := deploy(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, stdOut.String(), "Skipped config/settings_data.json to keep the theme settings")
+	client.AssertNumberOfCalls(t, "UpdateAsset", 1)
+
+	dir, err := ioutil.TempDir("", "themekit-settings")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "config"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "config", "settings_data.json"), []byte(`{"current":"Default","presets":{"Default":{}}}`), 0644)
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Env.SettingsData = "merge"
+	ctx.Flags.Verbose = true
+	ctx.Flags.NoDelete = true
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{Key: "config/settings_data.json", Value: `{"current":{"color":"red"}}`}, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool {
+		return a.Key == "config/settings_data.json" && strings.Contains(a.Value, `"color": "red"`) && strings.Contains(a.Value, `"presets"`)
+	})).Return(nil)
+	err = deploy(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, stdOut.String(), "Merged config/settings_data.json")
+	client.AssertExpectations(t)
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Env.SettingsData = "merge"
+	ctx.Flags.Verbose = true
+	ctx.Flags.NoDelete = true
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{}, &shopify.APIError{StatusCode: 404, Err: shopify.ErrNotPartOfTheme})
+	client.On("UpdateAsset", shopify.Asset{Key: "config/settings_data.json", Value: `{"current":"Default","presets":{"Default":{}}}`}).Return(nil)
+	err = deploy(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, stdOut.String(), "Merged config/settings_data.json")
+	client.AssertExpectations(t)
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Env.SettingsData = "merge"
+	ctx.Flags.NoDelete = true
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{}, fmt.Errorf("server error"))
+	err = deploy(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, stdErr.String(), "server error")
+	client.AssertNotCalled(t, "UpdateAsset", mock.Anything)
+}
+
 func TestReplace(t *testing.T) {
 	ctx, client, _, stdOut, _ := createTestCtx()
 	ctx.Flags.Verbose = true
 	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Env.SettingsData = "overwrite"
 	client.On("GetAllAssets").Return([]string{"assets/logo.png"}, nil)
+	client.On("GetAssetChecksums").Return(map[string]string{}, nil)
 	client.On("UpdateAsset", mock.MatchedBy(func(shopify.Asset) bool { return true })).Return(nil).Times(2)
 	client.On("DeleteAsset", mock.MatchedBy(func(shopify.Asset) bool { return true })).Return(nil).Once()
 	err := deploy(ctx)
@@ -70,9 +371,35 @@ func TestReplace(t *testing.T) {
 	}
 }
 
+func TestDeployGroups(t *testing.T) {
+	groups := deployGroups(map[string]file.Op{
+		"layout/theme.liquid":         file.Update,
+		"templates/index.liquid":      file.Update,
+		"templates/old.liquid":        file.Remove,
+		"sections/header.liquid":      file.Update,
+		"sections/footer.liquid":      file.Update,
+		"snippets/icon.liquid":        file.Update,
+		"config/settings_data.json":   file.Update,
+		"config/settings_schema.json": file.Update,
+		"assets/app.js":               file.Update,
+	})
+
+	assert.Equal(t, [][]string{
+		{"config/settings_schema.json"},
+		{"assets/app.js"},
+		{"snippets/icon.liquid"},
+		{"sections/footer.liquid", "sections/header.liquid"},
+		{"templates/index.liquid"},
+		{"layout/theme.liquid"},
+		{"config/settings_data.json"},
+		{"templates/old.liquid"},
+	}, groups)
+}
+
 func TestGenerateActions(t *testing.T) {
 	ctx, client, _, _, _ := createTestCtx()
 	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Env.SettingsData = "overwrite"
 	client.On("GetAllAssets").Return([]string{"assets/logo.png"}, nil)
 	actions, err := generateActions(ctx)
 	assert.Nil(t, err)
@@ -83,6 +410,56 @@ func TestGenerateActions(t *testing.T) {
 	_, found := actions["assets/.gitkeep"]
 	assert.False(t, found)
 
+	for _, mode := range []string{"", "skip"} {
+		ctx, client, _, _, _ = createTestCtx()
+		ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+		ctx.Env.SettingsData = mode
+		ctx.Args = []string{"config/settings_data.json"}
+		client.On("GetAllAssets").Return([]string{}, nil)
+		actions, err = generateActions(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]file.Op{"config/settings_data.json": file.Update}, actions, mode)
+
+		ctx, client, _, _, _ = createTestCtx()
+		ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+		ctx.Env.SettingsData = mode
+		ctx.Args = []string{"config"}
+		client.On("GetAllAssets").Return([]string{}, nil)
+		actions, err = generateActions(ctx)
+		assert.Nil(t, err)
+		_, found = actions["config/settings_data.json"]
+		assert.False(t, found, mode)
+	}
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Env.SettingsData = "keep"
+	client.On("GetAllAssets").Return([]string{}, nil)
+	_, err = generateActions(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid settings_data")
+	}
+
+	for _, mode := range []string{"", "skip", "merge"} {
+		ctx, client, _, _, _ = createTestCtx()
+		ctx.Env.Directory = filepath.Join("_testdata", "projectdir", "assets")
+		ctx.Env.SettingsData = mode
+		client.On("GetAllAssets").Return([]string{"config/settings_data.json"}, nil)
+		actions, err = generateActions(ctx)
+		assert.Nil(t, err)
+		_, found = actions["config/settings_data.json"]
+		assert.False(t, found, mode)
+	}
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	client.On("GetAllAssets").Return([]string{"assets/logo.png"}, nil)
+	actions, err = generateActions(ctx)
+	assert.Nil(t, err)
+	_, found = actions["config/settings_data.json"]
+	assert.False(t, found)
+	assert.Equal(t, len(actions), 2)
+
 	ctx, client, _, _, _ = createTestCtx()
 	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
 	client.On("GetAllAssets").Return([]string{}, fmt.Errorf("server error"))
@@ -37,18 +37,26 @@ func download(ctx *cmdutil.Ctx) error {
 		return fmt.Errorf("No files to download")
 	}
 
+	checksums := map[string]string{}
+	if ctx.Env.CacheDir != "" {
+		if checksums, err = ctx.Client.GetAssetChecksums(); err != nil {
+			// without checksums every file will be downloaded
+			checksums = map[string]string{}
+		}
+	}
+
 	ctx.StartProgress(len(filenames))
 	for _, filename := range filenames {
 		downloadGroup.Add(1)
 		go func(filename string) {
 			defer ctx.DoneTask()
 			defer downloadGroup.Done()
-			if asset, err := ctx.Client.GetAsset(filename); err != nil {
+			if asset, err := ctx.Client.GetCachedAsset(filename, checksums[filename]); err != nil {
 				ctx.Err("[%s] error downloading asset: %s", colors.Green(ctx.Env.Name), err)
 			} else if err = asset.Write(ctx.Env.Directory); err != nil {
 				ctx.Err("[%s] error writing asset: %s", colors.Green(ctx.Env.Name), err)
-			} else if ctx.Flags.Verbose {
-				ctx.Log.Printf("[%s] Successfully wrote %s to disk", colors.Green(ctx.Env.Name), colors.Blue(filename))
+			} else {
+				ctx.Logf(cmdutil.LevelVerbose, "[%s] Successfully wrote %s to disk", colors.Green(ctx.Env.Name), colors.Blue(filename))
 			}
 		}(filename)
 	}
@@ -15,7 +15,7 @@ func TestDownload(t *testing.T) {
 
 	ctx, client, _, _, stdErr := createTestCtx()
 	client.On("GetAllAssets").Return(allFilenames, nil)
-	client.On("GetAsset", mock.MatchedBy(func(string) bool { return true })).Return(shopify.Asset{}, nil).Times(len(allFilenames))
+	client.On("GetCachedAsset", mock.Anything, "").Return(shopify.Asset{}, nil).Times(len(allFilenames))
 	err := download(ctx)
 	assert.Nil(t, err)
 	assert.Contains(t, stdErr.String(), "error writing asset")
@@ -29,11 +29,30 @@ func TestDownload(t *testing.T) {
 
 	ctx, client, _, _, stdErr = createTestCtx()
 	client.On("GetAllAssets").Return(allFilenames, nil)
-	client.On("GetAsset", mock.MatchedBy(func(string) bool { return true })).Return(shopify.Asset{}, fmt.Errorf("asset err"))
+	client.On("GetCachedAsset", mock.Anything, "").Return(shopify.Asset{}, fmt.Errorf("asset err"))
 	assert.Nil(t, download(ctx))
 	assert.Contains(t, stdErr.String(), "error downloading asset")
 }
 
+func TestDownloadCached(t *testing.T) {
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.CacheDir = "cache"
+	client.On("GetAllAssets").Return([]string{"assets/app.js", "assets/new.js"}, nil)
+	client.On("GetAssetChecksums").Return(map[string]string{"assets/app.js": "abc"}, nil)
+	client.On("GetCachedAsset", "assets/app.js", "abc").Return(shopify.Asset{}, nil)
+	client.On("GetCachedAsset", "assets/new.js", "").Return(shopify.Asset{}, nil)
+	assert.Nil(t, download(ctx))
+	client.AssertExpectations(t)
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.CacheDir = "cache"
+	client.On("GetAllAssets").Return([]string{"assets/app.js"}, nil)
+	client.On("GetAssetChecksums").Return(nil, fmt.Errorf("server error"))
+	client.On("GetCachedAsset", "assets/app.js", "").Return(shopify.Asset{}, nil)
+	assert.Nil(t, download(ctx))
+	client.AssertExpectations(t)
+}
+
 func TestFilesToDownload(t *testing.T) {
 	allFilenames := []string{"assets/logo.png", "templates/customers/test.liquid", "config/test.liquid", "layout/test.liquid", "snippets/test.liquid", "templates/test.liquid", "locales/test.liquid", "sections/test.liquid"}
 
@@ -2,22 +2,38 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"text/template"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/shopify"
 )
 
 var (
 	errNoThemes     = errors.New("No available themes")
 	availableThemes = template.Must(template.New("availableThemes").Parse(`Available theme versions:
   {{- range . }}
-  [{{ .ID }}]{{if eq .Role "main"}}[live]{{ end }} {{ .Name }}
+  [{{ .ID }}]{{if eq .Role "main"}}[live]{{else if eq .Role "development"}}[development]{{ end }} {{ .Name }}
   {{- end }}`))
 )
 
+// themeJSON is the shape of a theme in the json theme listing. The field names are
+// part of the output format so they should not change.
+type themeJSON struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	Previewable bool   `json:"previewable"`
+	Processing  bool   `json:"processing"`
+	// timestamps are null if shopify did not provide them
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
 var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Get a theme and config from shopify",
@@ -34,9 +50,16 @@ var getCmd = &cobra.Command{
 
 func getTheme(ctx *cmdutil.Ctx) error {
 	if ctx.Flags.List {
-		themes, err := ctx.Client.Themes()
+		themes, err := listThemes(ctx)
 		if err != nil {
 			return err
+		} else if ctx.Flags.JSON {
+			data, err := themesToJSON(themes)
+			if err != nil {
+				return err
+			}
+			ctx.Log.Println(string(data))
+			return nil
 		} else if len(themes) == 0 {
 			return errNoThemes
 		}
@@ -53,3 +76,35 @@ func getTheme(ctx *cmdutil.Ctx) error {
 
 	return download(ctx)
 }
+
+// listThemes will return the themes that match the role and name flags, or all of
+// the themes if neither is set.
+func listThemes(ctx *cmdutil.Ctx) ([]shopify.Theme, error) {
+	if ctx.Flags.Role == "" && ctx.Flags.NameFilter == "" {
+		return ctx.Client.Themes()
+	}
+	return ctx.Client.FilterThemes(shopify.ThemeFilter{Role: ctx.Flags.Role, Name: ctx.Flags.NameFilter})
+}
+
+func themesToJSON(themes []shopify.Theme) ([]byte, error) {
+	list := []themeJSON{}
+	for _, theme := range themes {
+		list = append(list, themeJSON{
+			ID:          theme.ID,
+			Name:        theme.Name,
+			Role:        theme.Role,
+			Previewable: theme.Previewable,
+			Processing:  theme.Processing,
+			CreatedAt:   timestampOrNil(theme.CreatedAt),
+			UpdatedAt:   timestampOrNil(theme.UpdatedAt),
+		})
+	}
+	return json.MarshalIndent(list, "", "  ")
+}
+
+func timestampOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -56,4 +58,50 @@ func TestGet(t *testing.T) {
 	client.On("Themes").Return([]shopify.Theme{{ID: 1234, Role: "main", Name: "test"}}, nil)
 	assert.Nil(t, getTheme(ctx))
 	assert.Contains(t, stdOut.String(), "[1234][live] test")
+
+	ctx, client, conf, stdOut, _ = createTestCtx()
+	ctx.Flags.List = true
+	client.On("Themes").Return([]shopify.Theme{{ID: 1234, Role: "main", Name: "test"}, {ID: 5678, Role: "development", Name: "dev"}}, nil)
+	assert.Nil(t, getTheme(ctx))
+	assert.Contains(t, stdOut.String(), "[5678][development] dev")
+
+	ctx, client, conf, stdOut, _ = createTestCtx()
+	ctx.Flags.List = true
+	ctx.Flags.Role = "unpublished"
+	ctx.Flags.NameFilter = "staging"
+	client.On("FilterThemes", shopify.ThemeFilter{Role: "unpublished", Name: "staging"}).Return([]shopify.Theme{{ID: 5678, Role: "unpublished", Name: "Staging"}}, nil)
+	assert.Nil(t, getTheme(ctx))
+	assert.Contains(t, stdOut.String(), "[5678] Staging")
+	client.AssertNotCalled(t, "Themes")
+
+	ctx, client, conf, _, _ = createTestCtx()
+	ctx.Flags.List = true
+	ctx.Flags.Role = "demo"
+	client.On("FilterThemes", shopify.ThemeFilter{Role: "demo"}).Return([]shopify.Theme{}, nil)
+	assert.EqualError(t, getTheme(ctx), errNoThemes.Error())
+
+	ctx, client, conf, stdOut, _ = createTestCtx()
+	ctx.Flags.List = true
+	ctx.Flags.JSON = true
+	client.On("Themes").Return([]shopify.Theme{}, nil)
+	assert.Nil(t, getTheme(ctx))
+	assert.Equal(t, "[]\n", stdOut.String())
+
+	ctx, client, conf, stdOut, _ = createTestCtx()
+	ctx.Flags.List = true
+	ctx.Flags.JSON = true
+	updatedAt := time.Date(2018, 6, 1, 16, 30, 0, 0, time.UTC)
+	client.On("Themes").Return([]shopify.Theme{{ID: 1234, Role: "main", Name: "test", Source: "http://example.com/theme.zip", Previewable: true, UpdatedAt: updatedAt}}, nil)
+	assert.Nil(t, getTheme(ctx))
+	var themes []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(stdOut.Bytes(), &themes))
+	assert.Equal(t, []map[string]interface{}{{
+		"id":          float64(1234),
+		"name":        "test",
+		"role":        "main",
+		"previewable": true,
+		"processing":  false,
+		"created_at":  nil,
+		"updated_at":  "2018-06-01T16:30:00Z",
+	}}, themes)
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,9 +10,13 @@ import (
 
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/shopify"
 	"github.com/Shopify/themekit/src/timber"
 )
 
+// defaultProcessingTimeout is how long to wait for a new theme to be processed
+const defaultProcessingTimeout = 5 * time.Minute
+
 var bootstrapCmd = &cobra.Command{
 	Use:   "bootstrap",
 	Short: "Bootstrap will create theme using Shopify Timber",
@@ -27,11 +32,13 @@ var bootstrapCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return cmdutil.ForDefaultClient(flags, args, func(ctx *cmdutil.Ctx) error {
 			ctx.Log.Printf("[%s] bootstrap has been deprecated please use `new` instead", colors.Yellow("WARN"))
-			name, url, err := getNewThemeDetails(flags, timber.GetVersionPath)
+			name, url, err := getNewThemeDetails(flags, getTimberVersionPath)
 			if err != nil {
 				return err
+			} else if err := newTheme(ctx, name, url); err != nil {
+				return err
 			}
-			return newTheme(ctx, name, url)
+			return openNewTheme(ctx, cmdutil.OpenURL)
 		})
 	},
 }
@@ -47,11 +54,13 @@ var newCmd = &cobra.Command{
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return cmdutil.ForDefaultClient(flags, args, func(ctx *cmdutil.Ctx) error {
-			name, url, err := getNewThemeDetails(flags, timber.GetVersionPath)
+			name, url, err := getNewThemeDetails(flags, getTimberVersionPath)
 			if err != nil {
 				return err
+			} else if err := newTheme(ctx, name, url); err != nil {
+				return err
 			}
-			return newTheme(ctx, name, url)
+			return openNewTheme(ctx, cmdutil.OpenURL)
 		})
 	},
 }
@@ -73,21 +82,56 @@ func newTheme(ctx *cmdutil.Ctx, name, url string) error {
 
 	ctx.Log.Printf("[%s] created config", colors.Yellow(ctx.Env.Domain))
 
-	for {
-		if theme, err := ctx.Client.GetInfo(); err != nil {
-			ctx.Err("Encountered an error while checking new theme. Please run `theme download` to complete the setup.")
-			return err
-		} else if theme.Previewable {
-			ctx.Log.Println("downloading...")
-			break
-		}
-		ctx.Log.Println("processing...")
-		time.Sleep(500 * time.Millisecond)
+	if err := waitForProcessing(ctx, theme.ID); err != nil {
+		return err
 	}
 
+	ctx.Log.Printf("[%s] preview the new theme at %s", colors.Yellow(ctx.Env.Domain), colors.Green(previewURL(ctx, theme.ID)))
+	ctx.Log.Println("downloading...")
 	return download(ctx)
 }
 
+// openNewTheme will open the preview of the new theme, or its editor, if the open
+// flag was passed.
+func openNewTheme(ctx *cmdutil.Ctx, open openFunc) error {
+	if !ctx.Flags.OpenPreview {
+		return nil
+	}
+	url, err := themeURL(ctx, ctx.Flags.ThemeID)
+	if err != nil {
+		return err
+	}
+	return openTheme(ctx, url, open)
+}
+
+// waitForProcessing will wait until shopify is done processing the new theme. It
+// gives up once the processing timeout has passed so that a theme that never
+// becomes ready does not hang the command.
+func waitForProcessing(ctx *cmdutil.Ctx, themeID int64) error {
+	timeout := ctx.Flags.ProcessingTimeout
+	if timeout <= 0 {
+		timeout = defaultProcessingTimeout
+	}
+
+	ctx.Log.Println("processing...")
+	err := ctx.Client.WaitForProcessing(themeID, timeout)
+	if errors.Is(err, shopify.ErrThemeProcessing) {
+		return fmt.Errorf("[%s] the new theme is still processing after %s, please run `theme download` once it is ready to complete the setup", colors.Yellow(ctx.Env.Domain), timeout)
+	} else if err != nil {
+		ctx.Err("Encountered an error while checking new theme. Please run `theme download` to complete the setup.")
+		return err
+	}
+	return nil
+}
+
+func getTimberVersionPath(version string) (string, error) {
+	return timber.GetVersionPathWithOptions(version, timber.Options{
+		Offline:  flags.Offline,
+		CacheTTL: flags.VersionCacheTTL,
+		Token:    flags.GithubToken,
+	})
+}
+
 func getNewThemeDetails(flags cmdutil.Flags, getVer func(string) (string, error)) (name, url string, err error) {
 	name, url = flags.Name, flags.URL
 
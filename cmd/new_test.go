@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -14,14 +15,18 @@ import (
 func TestNewTheme(t *testing.T) {
 	name, url := "name", "https://download.com/1.2.4.zip"
 
-	ctx, client, conf, _, _ := createTestCtx()
-	client.On("CreateNewTheme", name, url).Return(shopify.Theme{}, nil)
-	client.On("GetInfo").Return(shopify.Theme{Previewable: true}, nil)
+	ctx, client, conf, stdOut, _ := createTestCtx()
+	client.On("CreateNewTheme", name, url).Return(shopify.Theme{ID: 123}, nil)
+	client.On("WaitForProcessing", int64(123), defaultProcessingTimeout).Return(nil)
+	client.On("PreviewURL", int64(123)).Return("https://shop.example.com/?preview_theme_id=123", nil)
 	client.On("GetAllAssets").Return([]string{}, nil)
 	conf.On("Set", "development", env.Env{}).Return(nil, nil)
 	conf.On("Save").Return(nil)
 	err := newTheme(ctx, name, url)
 	assert.Error(t, err)
+	assert.Contains(t, stdOut.String(), "processing...")
+	assert.Contains(t, stdOut.String(), "preview the new theme at https://shop.example.com/?preview_theme_id=123")
+	client.AssertCalled(t, "WaitForProcessing", int64(123), defaultProcessingTimeout)
 
 	ctx, client, _, _, _ = createTestCtx()
 	client.On("CreateNewTheme", name, url).Return(shopify.Theme{}, fmt.Errorf("can't create theme"))
@@ -40,7 +45,7 @@ func TestNewTheme(t *testing.T) {
 
 	ctx, client, conf, _, _ = createTestCtx()
 	client.On("CreateNewTheme", name, url).Return(shopify.Theme{}, nil)
-	client.On("GetInfo").Return(shopify.Theme{}, fmt.Errorf("oh no"))
+	client.On("WaitForProcessing", int64(0), defaultProcessingTimeout).Return(fmt.Errorf("oh no"))
 	client.On("GetAllAssets").Return([]string{}, nil)
 	conf.On("Set", "development", env.Env{}).Return(nil, nil)
 	conf.On("Save").Return(nil)
@@ -50,6 +55,26 @@ func TestNewTheme(t *testing.T) {
 	}
 }
 
+func TestWaitForProcessing(t *testing.T) {
+	ctx, client, _, _, _ := createTestCtx()
+	client.On("WaitForProcessing", int64(123), defaultProcessingTimeout).Return(nil)
+	assert.Nil(t, waitForProcessing(ctx, 123))
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.ProcessingTimeout = 25 * time.Second
+	client.On("WaitForProcessing", int64(123), 25*time.Second).Return(fmt.Errorf("%w after 25s", shopify.ErrThemeProcessing))
+	err := waitForProcessing(ctx, 123)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "still processing after 25s")
+		assert.Contains(t, err.Error(), "theme download")
+	}
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	client.On("WaitForProcessing", int64(123), defaultProcessingTimeout).Return(fmt.Errorf("server error"))
+	assert.EqualError(t, waitForProcessing(ctx, 123), "server error")
+	assert.Contains(t, stdErr.String(), "error while checking new theme")
+}
+
 func TestGetNewThemeDetails(t *testing.T) {
 	getVerGood := func(string) (string, error) { return "https://download.com", nil }
 	getVerBad := func(string) (string, error) { return "", fmt.Errorf("cant fetch releases") }
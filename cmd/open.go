@@ -1,17 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 
-	"github.com/skratchdot/open-golang/open"
 	"github.com/spf13/cobra"
 
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/shopify"
 )
 
-type runWithFunc func(url, prog string) error
-type runFunc func(url string) error
+type openFunc func(url, browser string) error
 
 var openCmd = &cobra.Command{
 	Use:   "open",
@@ -20,23 +21,50 @@ var openCmd = &cobra.Command{
 url for your reference`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return cmdutil.ForSingleClient(flags, args, func(ctx *cmdutil.Ctx) error {
-			return preview(ctx, open.Run, open.RunWith)
+			return preview(ctx, cmdutil.OpenURL)
 		})
 	},
 }
 
-func preview(ctx *cmdutil.Ctx, run runFunc, runWith runWithFunc) error {
-	url := fmt.Sprintf("https://%s?preview_theme_id=%s", ctx.Env.Domain, ctx.Env.ThemeID)
-	if ctx.Flags.Edit {
-		url = fmt.Sprintf("https://%s/admin/themes/%s/editor", ctx.Env.Domain, ctx.Env.ThemeID)
+func preview(ctx *cmdutil.Ctx, open openFunc) error {
+	url, err := themeURL(ctx, ctx.Env.ThemeID)
+	if err != nil {
+		return err
 	}
+	return openTheme(ctx, url, open)
+}
+
+// themeURL will return the url of the preview of the theme, or of its web editor if
+// the edit flag is set.
+func themeURL(ctx *cmdutil.Ctx, themeID string) (string, error) {
+	id, err := strconv.ParseInt(themeID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("[%s] invalid theme id %s", colors.Green(ctx.Env.Name), colors.Yellow(themeID))
+	} else if ctx.Flags.Edit {
+		return ctx.Client.EditorURL(id), nil
+	}
+	return previewURL(ctx, id), nil
+}
+
+// previewURL will return the url to preview the theme on the shop's primary domain,
+// or on the configured domain if the shop could not be looked up.
+func previewURL(ctx *cmdutil.Ctx, themeID int64) string {
+	url, err := ctx.Client.PreviewURL(themeID)
+	if err != nil {
+		return shopify.ThemePreviewURL(ctx.Env.Domain, themeID)
+	}
+	return url
+}
+
+// openTheme will open the url in the browser. If there is no browser to open it
+// with the url is only printed so that it can be opened elsewhere.
+func openTheme(ctx *cmdutil.Ctx, url string, open openFunc) error {
 	ctx.Log.Printf("[%s] opening %s", colors.Green(ctx.Env.Name), colors.Green(url))
 
-	if ctx.Flags.With == "" {
-		if err := run(url); err != nil {
-			return fmt.Errorf("[%s] Error opening: %s", colors.Green(ctx.Env.Name), colors.Red(err))
-		}
-	} else if err := runWith(url, ctx.Flags.With); err != nil {
+	err := open(url, ctx.Flags.With)
+	if errors.Is(err, cmdutil.ErrNoBrowser) {
+		ctx.Log.Printf("[%s] no browser available, please open %s to see the theme", colors.Green(ctx.Env.Name), colors.Green(url))
+	} else if err != nil {
 		return fmt.Errorf("[%s] Error opening: %s", colors.Green(ctx.Env.Name), colors.Red(err))
 	}
 
@@ -5,48 +5,107 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/cmdutil"
 )
 
 func TestOpen(t *testing.T) {
-	r := func(path string) error { panic("should not have been called") }
-	rw := func(path, with string) error { panic("should not have been called") }
+	preview123 := "https://shop.example.com/?preview_theme_id=123"
 
-	ctx, _, _, _, _ := createTestCtx()
-	ctx.Env.Domain = "my.test.domain"
+	ctx, client, _, _, _ := createTestCtx()
 	ctx.Env.ThemeID = "123"
-	assert.Nil(t, preview(ctx, func(path string) error {
-		assert.Equal(t, path, "https://my.test.domain?preview_theme_id=123")
+	client.On("PreviewURL", int64(123)).Return(preview123, nil)
+	assert.Nil(t, preview(ctx, func(path, with string) error {
+		assert.Equal(t, path, preview123)
+		assert.Equal(t, with, "")
 		return nil
-	}, rw))
+	}))
 
-	ctx, _, _, _, _ = createTestCtx()
-	ctx.Env.Domain = "my.test.domain"
+	ctx, client, _, _, _ = createTestCtx()
 	ctx.Env.ThemeID = "123"
 	ctx.Flags.Edit = true
-	assert.Nil(t, preview(ctx, func(path string) error {
-		assert.Equal(t, path, "https://my.test.domain/admin/themes/123/editor")
+	client.On("EditorURL", int64(123)).Return("https://my-shop.myshopify.com/admin/themes/123/editor")
+	assert.Nil(t, preview(ctx, func(path, with string) error {
+		assert.Equal(t, path, "https://my-shop.myshopify.com/admin/themes/123/editor")
 		return nil
-	}, rw))
+	}))
+	client.AssertNotCalled(t, "PreviewURL", int64(123))
 
-	ctx, _, _, stdOut, _ := createTestCtx()
-	ctx.Env.Domain = "my.test.domain"
+	ctx, client, _, stdOut, _ := createTestCtx()
 	ctx.Env.ThemeID = "123"
-	err := preview(ctx, func(path string) error {
-		assert.Equal(t, path, "https://my.test.domain?preview_theme_id=123")
+	client.On("PreviewURL", int64(123)).Return(preview123, nil)
+	err := preview(ctx, func(path, with string) error {
+		assert.Equal(t, path, preview123)
 		return fmt.Errorf("fake error")
-	}, rw)
+	})
 	assert.Contains(t, stdOut.String(), "opening")
 	assert.Contains(t, err.Error(), "Error opening:")
 
-	ctx, _, _, stdOut, _ = createTestCtx()
-	ctx.Env.Domain = "my.test.domain"
+	ctx, client, _, stdOut, _ = createTestCtx()
 	ctx.Env.ThemeID = "123"
 	ctx.Flags.With = "chrome"
-	err = preview(ctx, r, func(path, with string) error {
-		assert.Equal(t, path, "https://my.test.domain?preview_theme_id=123")
+	client.On("PreviewURL", int64(123)).Return(preview123, nil)
+	err = preview(ctx, func(path, with string) error {
+		assert.Equal(t, path, preview123)
 		assert.Equal(t, with, "chrome")
 		return fmt.Errorf("fake error")
 	})
 	assert.Contains(t, stdOut.String(), "opening")
 	assert.Contains(t, err.Error(), "Error opening:")
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.ThemeID = "123"
+	client.On("PreviewURL", int64(123)).Return(preview123, nil)
+	err = preview(ctx, func(path, with string) error {
+		return fmt.Errorf("%w: xdg-open not found", cmdutil.ErrNoBrowser)
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, stdOut.String(), "no browser available, please open "+preview123)
+
+	ctx, _, _, _, _ = createTestCtx()
+	err = preview(ctx, func(path, with string) error {
+		t.Errorf("nothing should be opened without a theme id")
+		return nil
+	})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid theme id")
+	}
+}
+
+func TestPreviewURL(t *testing.T) {
+	ctx, client, _, _, _ := createTestCtx()
+	client.On("PreviewURL", int64(123)).Return("https://shop.example.com/?preview_theme_id=123", nil)
+	assert.Equal(t, "https://shop.example.com/?preview_theme_id=123", previewURL(ctx, 123))
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Domain = "https://my-shop.myshopify.com/"
+	client.On("PreviewURL", int64(123)).Return("", fmt.Errorf("server error"))
+	assert.Equal(t, "https://my-shop.myshopify.com/?preview_theme_id=123", previewURL(ctx, 123))
+}
+
+func TestOpenNewTheme(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	assert.Nil(t, openNewTheme(ctx, func(path, with string) error {
+		t.Errorf("the theme should not be opened without the open flag")
+		return nil
+	}))
+
+	var opened string
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Flags.ThemeID = "456"
+	ctx.Flags.OpenPreview = true
+	client.On("PreviewURL", int64(456)).Return("https://shop.example.com/?preview_theme_id=456", nil)
+	client.On("EditorURL", int64(456)).Return("https://my-shop.myshopify.com/admin/themes/456/editor")
+	assert.Nil(t, openNewTheme(ctx, func(path, with string) error {
+		opened = path
+		return nil
+	}))
+	assert.Equal(t, "https://shop.example.com/?preview_theme_id=456", opened)
+
+	ctx.Flags.Edit = true
+	assert.Nil(t, openNewTheme(ctx, func(path, with string) error {
+		opened = path
+		return nil
+	}))
+	assert.Equal(t, "https://my-shop.myshopify.com/admin/themes/456/editor", opened)
 }
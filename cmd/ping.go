@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Check that your config can connect to shopify",
+	Long: `Ping will connect to shopify with your config and print the shop, its plan
+ and the theme that commands will change. If anything in the config is wrong,
+ like the store, password or theme id, ping will fail and say what was rejected.
+ Ping does not change anything, so it is the first thing to run when a command
+ does not work.
+
+ For more documentation please see http://shopify.github.io/themekit/commands/#ping
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmdutil.ForEachClient(flags, args, ping); err != nil {
+			return fmt.Errorf("%s %s", colors.Red("FAIL"), err)
+		}
+		return nil
+	},
+}
+
+func ping(ctx *cmdutil.Ctx) error {
+	shop, err := ctx.Client.GetShop()
+	var apiErr *shopify.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("[%s] the password was rejected by %s: %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.Domain), err)
+	} else if err != nil {
+		return fmt.Errorf("[%s] could not connect to %s: %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.Domain), err)
+	}
+
+	themes, err := ctx.Client.Themes()
+	if err != nil {
+		return fmt.Errorf("[%s] could not list the themes of %s: %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.Domain), err)
+	}
+	theme, found := pingTheme(ctx.Env.ThemeID, themes)
+	if !found {
+		return fmt.Errorf("[%s] theme %s was not found on %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.ThemeID), colors.Yellow(ctx.Env.Domain))
+	}
+
+	plan := shop.PlanDisplayName
+	if plan == "" {
+		plan = shop.PlanName
+	}
+	ctx.Log.Printf("[%s] %s connected to %s", colors.Green(ctx.Env.Name), colors.Green("OK"), colors.Blue(shop.MyshopifyDomain))
+	ctx.Log.Printf("[%s]   shop: %s (%s)", colors.Green(ctx.Env.Name), shop.Name, shop.Domain)
+	ctx.Log.Printf("[%s]   plan: %s", colors.Green(ctx.Env.Name), plan)
+	ctx.Log.Printf("[%s]   theme: %s (%d, %s)", colors.Green(ctx.Env.Name), theme.Name, theme.ID, theme.Role)
+	return nil
+}
+
+// pingTheme will find the theme with the id, or the published theme if there is
+// no id, which is the theme commands change.
+func pingTheme(themeID string, themes []shopify.Theme) (shopify.Theme, bool) {
+	for _, theme := range themes {
+		if fmt.Sprintf("%d", theme.ID) == themeID || (themeID == "" && theme.Role == "main") {
+			return theme, true
+		}
+	}
+	return shopify.Theme{}, false
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestPing(t *testing.T) {
+	shop := shopify.Shop{Name: "My Shop", Domain: "myshop.com", MyshopifyDomain: "my-shop.myshopify.com", PlanName: "basic", PlanDisplayName: "Basic Shopify"}
+	themes := []shopify.Theme{{ID: 1, Name: "Debut", Role: "main"}, {ID: 2, Name: "Dev", Role: "unpublished"}}
+
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.ThemeID = "2"
+	client.On("GetShop").Return(shop, nil)
+	client.On("Themes").Return(themes, nil)
+	assert.Nil(t, ping(ctx))
+	assert.Contains(t, stdOut.String(), "OK connected to my-shop.myshopify.com")
+	assert.Contains(t, stdOut.String(), "shop: My Shop (myshop.com)")
+	assert.Contains(t, stdOut.String(), "plan: Basic Shopify")
+	assert.Contains(t, stdOut.String(), "theme: Dev (2, unpublished)")
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	client.On("GetShop").Return(shopify.Shop{PlanName: "basic"}, nil)
+	client.On("Themes").Return(themes, nil)
+	assert.Nil(t, ping(ctx))
+	assert.Contains(t, stdOut.String(), "plan: basic")
+	assert.Contains(t, stdOut.String(), "theme: Debut (1, main)")
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Domain = "my-shop.myshopify.com"
+	client.On("GetShop").Return(shopify.Shop{}, &shopify.APIError{StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("Invalid API key or access token")})
+	err := ping(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "the password was rejected by my-shop.myshopify.com")
+		assert.Contains(t, err.Error(), "Invalid API key or access token")
+	}
+	client.AssertNotCalled(t, "Themes")
+
+	ctx, client, _, _, _ = createTestCtx()
+	client.On("GetShop").Return(shopify.Shop{}, fmt.Errorf("no such host"))
+	err = ping(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not connect")
+	}
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.ThemeID = "3"
+	client.On("GetShop").Return(shop, nil)
+	client.On("Themes").Return(themes, nil)
+	err = ping(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "theme 3 was not found")
+	}
+	assert.NotContains(t, stdOut.String(), "OK")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Find or remove theme files that are not used",
+	Long: `Prune will list the snippets and assets that no other file in the theme
+ refers to. With the --delete flag the unused files are removed from shopify and
+ the project.
+
+ Files that are referred to by a name that is built while rendering, like
+ {% render block.type %}, cannot be found so in that case no files of that kind
+ are reported.
+
+ For more documentation please see http://shopify.github.io/themekit/commands/#prune
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmdutil.ForEachClient(flags, args, func(ctx *cmdutil.Ctx) error {
+			return prune(ctx, os.Remove)
+		})
+	},
+}
+
+func prune(ctx *cmdutil.Ctx, removeFile func(string) error) error {
+	if ctx.Flags.Delete && ctx.Env.ReadOnly {
+		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	} else if ctx.Flags.Delete {
+		if err := cmdutil.CheckTheme(ctx); err != nil {
+			return err
+		}
+	}
+
+	unused, err := ctx.Client.UnusedAssets()
+	if err != nil {
+		return err
+	} else if len(unused) == 0 {
+		ctx.Log.Printf("[%s] No unused files found", colors.Green(ctx.Env.Name))
+		return nil
+	}
+
+	if !ctx.Flags.Delete {
+		for _, key := range unused {
+			ctx.Log.Printf("[%s] %s is not used", colors.Green(ctx.Env.Name), colors.Blue(key))
+		}
+		ctx.Log.Printf("[%s] Found %d unused files, run prune with --delete to remove them", colors.Green(ctx.Env.Name), len(unused))
+		return nil
+	}
+
+	var pruneGroup sync.WaitGroup
+	ctx.StartProgress(len(unused))
+	for _, key := range unused {
+		pruneGroup.Add(1)
+		go func(key string) {
+			defer pruneGroup.Done()
+			perform(ctx, key, file.Remove)
+			removeFile(filepath.Join(ctx.Env.Directory, key))
+		}(key)
+	}
+
+	pruneGroup.Wait()
+	return nil
+}
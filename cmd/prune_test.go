@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestPrune(t *testing.T) {
+	ctx, client, _, stdOut, _ := createTestCtx()
+	client.On("UnusedAssets").Return([]string{"assets/old.png", "snippets/old.liquid"}, nil)
+	assert.Nil(t, prune(ctx, func(string) error {
+		t.Error("files should only be listed")
+		return nil
+	}))
+	assert.Contains(t, stdOut.String(), "snippets/old.liquid is not used")
+	assert.Contains(t, stdOut.String(), "Found 2 unused files")
+	client.AssertNotCalled(t, "DeleteAsset", shopify.Asset{Key: "assets/old.png"})
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.Delete = true
+	ctx.Env.Directory = "project"
+	client.On("UnusedAssets").Return([]string{"assets/old.png", "snippets/old.liquid"}, nil)
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/old.png"}).Return(nil)
+	client.On("DeleteAsset", shopify.Asset{Key: "snippets/old.liquid"}).Return(nil)
+	removed := make(chan string, 2)
+	assert.Nil(t, prune(ctx, func(path string) error {
+		removed <- path
+		return nil
+	}))
+	close(removed)
+	paths := []string{}
+	for path := range removed {
+		paths = append(paths, path)
+	}
+	assert.ElementsMatch(t, []string{filepath.Join("project", "assets", "old.png"), filepath.Join("project", "snippets", "old.liquid")}, paths)
+	client.AssertExpectations(t)
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	client.On("UnusedAssets").Return([]string{}, nil)
+	assert.Nil(t, prune(ctx, nil))
+	assert.Contains(t, stdOut.String(), "No unused files found")
+
+	ctx, client, _, _, _ = createTestCtx()
+	client.On("UnusedAssets").Return(nil, fmt.Errorf("server error"))
+	assert.EqualError(t, prune(ctx, nil), "server error")
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.Delete = true
+	ctx.Env.ReadOnly = true
+	err := prune(ctx, nil)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "environment is readonly")
+	}
+	client.AssertNotCalled(t, "UnusedAssets")
+}
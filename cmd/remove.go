@@ -32,6 +32,8 @@ func remove(ctx *cmdutil.Ctx, removeFile func(string) error) error {
 		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
 	} else if len(ctx.Args) == 0 {
 		return fmt.Errorf("[%s] please specify file(s) to be removed", colors.Green(ctx.Env.Name))
+	} else if err := cmdutil.CheckTheme(ctx); err != nil {
+		return err
 	}
 
 	var removeGroup sync.WaitGroup
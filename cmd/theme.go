@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -36,10 +37,16 @@ Theme Kit is a fast and cross platform tool that enables you to build shopify th
 Complete documentation is available at https://shopify.github.io/themekit/`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if mode, err := flags.ColorMode(); err != nil {
+				return err
+			} else if err := colors.SetMode(mode); err != nil {
+				return err
+			}
 			if !flags.DisableUpdateNotifier && release.IsUpdateAvailable() {
 				colors.ColorStdOut.Print(colors.Yellow("An update for Themekit is available. To update please run `theme update`"))
 			}
+			return nil
 		},
 	}
 
@@ -81,14 +88,25 @@ func init() {
 	ThemeCmd.PersistentFlags().StringVarP(&flags.Domain, "store", "s", "", "your shopify domain. This will override what is in your config.yml")
 	ThemeCmd.PersistentFlags().StringVar(&flags.Proxy, "proxy", "", "proxy for all theme requests. This will override what is in your config.yml")
 	ThemeCmd.PersistentFlags().DurationVar(&flags.Timeout, "timeout", 0, "the timeout to kill any stalled processes. This will override what is in your config.yml")
+	ThemeCmd.PersistentFlags().Var(&flags.MaxRetries, "max-retries", "how many times a failed request is retried, 0 to fail right away. This will override what is in your config.yml")
 	ThemeCmd.PersistentFlags().BoolVarP(&flags.Verbose, "verbose", "v", false, "Enable more verbose output from the running command.")
+	ThemeCmd.PersistentFlags().BoolVarP(&flags.Quiet, "quiet", "q", false, "Only output errors, no progress or per-file messages.")
+	ThemeCmd.PersistentFlags().BoolVar(&flags.Debug, "debug", false, "Enable verbose output and log every request made to shopify.")
 	ThemeCmd.PersistentFlags().BoolVarP(&flags.DisableUpdateNotifier, "no-update-notifier", "", false, "Stop theme kit from notifying about updates.")
 	ThemeCmd.PersistentFlags().Var(&flags.IgnoredFiles, "ignored-file", "A single file to ignore, use the flag multiple times to add multiple.")
 	ThemeCmd.PersistentFlags().Var(&flags.Ignores, "ignores", "A path to a file that contains ignore patterns.")
 	ThemeCmd.PersistentFlags().BoolVar(&flags.DisableIgnore, "no-ignore", false, "Will disable config ignores so that all files can be changed")
+	ThemeCmd.PersistentFlags().BoolVar(&flags.DryRun, "dry-run", false, "Log the changes that would be made to the theme without making them.")
+	ThemeCmd.PersistentFlags().StringVar(&flags.Color, "color", colors.Auto, "when to color the output: auto, always or never. auto only colors output to a terminal when NO_COLOR is not set.")
+	ThemeCmd.PersistentFlags().BoolVar(&flags.NoColor, "no-color", false, "do not color the output, the same as --color=never.")
+	ThemeCmd.PersistentFlags().BoolVar(&flags.ForceColor, "force-color", false, "color the output even if it is not a terminal, like in CI, the same as --color=always.")
+	ThemeCmd.PersistentFlags().StringVar(&flags.LogFormat, "log-format", "text", "the format of the output, text or json. json writes every message as a json object on its own line.")
+	ThemeCmd.PersistentFlags().BoolVar(&flags.SkipThemeCheck, "skip-theme-check", false, "Do not check that the theme exists before changing it.")
+	ThemeCmd.PersistentFlags().BoolVar(&flags.RequireUnpublished, "require-unpublished", false, "Refuse to change the theme if it is the published theme.")
 
 	watchCmd.Flags().StringVarP(&flags.NotifyFile, "notify", "n", "", "file to touch when workers have gone idle")
 	watchCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
+	watchCmd.Flags().DurationVar(&flags.PollInterval, "poll-interval", 0, "poll the project for changes at this interval instead of using filesystem events")
 	removeCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
 	replaceCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
 	uploadCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
@@ -104,10 +122,34 @@ func init() {
 	bootstrapCmd.Flags().StringVar(&flags.Prefix, "prefix", "", "prefix to the Timber theme being created")
 	bootstrapCmd.Flags().StringVar(&flags.URL, "url", "", "a url to pull a project theme zip file from.")
 	bootstrapCmd.Flags().StringVar(&flags.Name, "name", "", "a name to define your theme on your shopify admin")
+	newCmd.Flags().DurationVar(&flags.ProcessingTimeout, "processing-timeout", defaultProcessingTimeout, "how long to wait for shopify to process the new theme before giving up.")
+	newCmd.Flags().BoolVar(&flags.OpenPreview, "open", false, "open the preview of the new theme in the browser once it is set up.")
+	newCmd.Flags().BoolVarP(&flags.Edit, "edit", "E", false, "with --open, open the web editor for the new theme instead of the preview.")
+	bootstrapCmd.Flags().BoolVar(&flags.OpenPreview, "open", false, "open the preview of the new theme in the browser once it is set up.")
+	bootstrapCmd.Flags().BoolVarP(&flags.Edit, "edit", "E", false, "with --open, open the web editor for the new theme instead of the preview.")
+	bootstrapCmd.Flags().DurationVar(&flags.ProcessingTimeout, "processing-timeout", defaultProcessingTimeout, "how long to wait for shopify to process the new theme before giving up.")
+	newCmd.Flags().BoolVar(&flags.Offline, "offline", false, "use the requested Timber release tag without checking the releases on github.")
+	newCmd.Flags().DurationVar(&flags.VersionCacheTTL, "version-cache-ttl", time.Hour, "how long the Timber releases fetched from github are reused.")
+	bootstrapCmd.Flags().BoolVar(&flags.Offline, "offline", false, "use the requested Timber release tag without checking the releases on github.")
+	bootstrapCmd.Flags().DurationVar(&flags.VersionCacheTTL, "version-cache-ttl", time.Hour, "how long the Timber releases fetched from github are reused.")
+	newCmd.Flags().StringVar(&flags.GithubToken, "github-token", "", "github token used to fetch the Timber releases. (default $GITHUB_TOKEN)")
+	bootstrapCmd.Flags().StringVar(&flags.GithubToken, "github-token", "", "github token used to fetch the Timber releases. (default $GITHUB_TOKEN)")
 	openCmd.Flags().BoolVarP(&flags.Edit, "edit", "E", false, "open the web editor for the theme.")
 	openCmd.Flags().StringVarP(&flags.With, "browser", "b", "", "name of the browser to open the url. the name should match the name of browser on your system.")
 	getCmd.Flags().BoolVarP(&flags.List, "list", "l", false, "list available themes.")
+	getCmd.Flags().BoolVar(&flags.JSON, "json", false, "print the list of themes as json.")
+	getCmd.Flags().StringVar(&flags.Role, "role", "", "only list the themes with this role: main, unpublished, demo or development.")
+	getCmd.Flags().StringVar(&flags.NameFilter, "name", "", "only list the themes with a name that contains this text, regardless of case.")
 	deployCmd.Flags().BoolVarP(&flags.NoDelete, "nodelete", "n", false, "do no delete file on shopify diring deploy.")
-
-	ThemeCmd.AddCommand(openCmd, versionCmd, bootstrapCmd, newCmd, configureCmd, downloadCmd, removeCmd, updateCmd, uploadCmd, replaceCmd, watchCmd, getCmd, deployCmd)
+	pruneCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
+	pingCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
+	pruneCmd.Flags().BoolVar(&flags.Delete, "delete", false, "remove the unused files from shopify and the project instead of listing them.")
+	deployCmd.Flags().StringVar(&flags.SettingsData, "settings-data", "", "how to deploy config/settings_data.json: skip, merge or overwrite. (default skip)")
+	deployCmd.Flags().BoolVar(&flags.CheckReferences, "check-references", false, "stop before deploying if a file renders a snippet or section that does not exist.")
+	deployCmd.Flags().StringVar(&flags.Key, "key", "", "the key of the file read from stdin when the filename is -.")
+	deployCmd.Flags().BoolVar(&flags.Binary, "binary", false, "deploy the file read from stdin as binary instead of text.")
+	deployCmd.Flags().BoolVar(&flags.Resume, "resume", false, "record the files that are deployed so that running the deploy again with --resume after it was interrupted skips them.")
+	deployCmd.Flags().IntVar(&flags.ParallelEnvs, "parallel-envs", 4, "how many environments are deployed at the same time, 0 to deploy all of them at once.")
+
+	ThemeCmd.AddCommand(openCmd, versionCmd, bootstrapCmd, newCmd, configureCmd, downloadCmd, removeCmd, updateCmd, uploadCmd, replaceCmd, watchCmd, getCmd, deployCmd, pruneCmd, pingCmd)
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -18,6 +19,10 @@ import (
 // need to be tweaked in the future.
 const assetLimit = 100
 
+// This is how often the project is scanned for changes when filesystem events
+// are not available and no poll interval was given.
+const defaultPollInterval = time.Second
+
 // This sets a hard limit on how many assets are loaded at a single time before
 // being uploaded. This is to protect from memory errors when very large themes
 // are uploaded.
@@ -34,29 +39,57 @@ var watchCmd = &cobra.Command{
  `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return cmdutil.ForEachClient(flags, args, func(ctx *cmdutil.Ctx) error {
-			watcher, err := file.NewWatcher(ctx.Env, ctx.Flags.ConfigPath)
-			if err != nil {
-				return err
-			}
-			watchEvents, err := watcher.Watch()
+			watcher, watchEvents, err := startWatcher(ctx)
 			if err != nil {
 				return err
 			}
 			defer watcher.Stop()
 
-			signalChan := make(chan os.Signal)
+			signalChan := make(chan os.Signal, 1)
 			signal.Notify(signalChan, os.Interrupt)
 			return watch(ctx, watchEvents, signalChan)
 		})
 	},
 }
 
+// startWatcher will poll the project when a poll interval is given and otherwise
+// use filesystem events, falling back to polling if they are not available.
+func startWatcher(ctx *cmdutil.Ctx) (*file.Watcher, chan file.Event, error) {
+	watcher, err := file.NewWatcher(ctx.Env, ctx.Flags.ConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ctx.Flags.PollInterval > 0 {
+		events, err := watcher.Poll(ctx.Flags.PollInterval)
+		return watcher, events, err
+	}
+
+	events, err := watcher.Watch()
+	if err == nil {
+		return watcher, events, nil
+	}
+	watcher.Stop()
+
+	ctx.Log.Printf("[%s] filesystem events unavailable (%s), polling for changes every %s", colors.Green(ctx.Env.Name), err, defaultPollInterval)
+	if watcher, err = file.NewWatcher(ctx.Env, ctx.Flags.ConfigPath); err != nil {
+		return nil, nil, err
+	}
+	events, err = watcher.Poll(defaultPollInterval)
+	return watcher, events, err
+}
+
 func watch(ctx *cmdutil.Ctx, events chan file.Event, sig chan os.Signal) error {
-	ctx.Flags.Verbose = true
+	if !ctx.Flags.Quiet {
+		// there is no progress bar so every change is logged
+		ctx.Flags.Verbose = true
+	}
 	ctx.Log.SetFlags(log.Ltime)
 
 	if ctx.Env.ReadOnly {
 		return fmt.Errorf("[%s] environment is reaonly", colors.Green(ctx.Env.Name))
+	} else if err := cmdutil.CheckTheme(ctx); err != nil {
+		return err
 	}
 
 	ctx.Log.Printf(
@@ -80,29 +113,32 @@ func watch(ctx *cmdutil.Ctx, events chan file.Event, sig chan os.Signal) error {
 	}
 }
 
-func perform(ctx *cmdutil.Ctx, path string, op file.Op) {
+// perform will delete or update the file on shopify and report if it succeeded.
+func perform(ctx *cmdutil.Ctx, path string, op file.Op) bool {
 	defer ctx.DoneTask()
 
 	if op == file.Remove {
 		if err := ctx.Client.DeleteAsset(shopify.Asset{Key: path}); err != nil {
 			ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
-		} else if ctx.Flags.Verbose {
-			ctx.Log.Printf("[%s] Deleted %s", colors.Green(ctx.Env.Name), colors.Blue(path))
-		}
-	} else {
-		assetLimitSemaphore <- struct{}{}
-		defer func() { <-assetLimitSemaphore }()
-
-		asset, err := shopify.ReadAsset(ctx.Env, path)
-		if err != nil {
-			ctx.Err("[%s] error loading %s: %s", colors.Green(ctx.Env.Name), colors.Green(path), colors.Red(err))
-			return
+			return false
 		}
+		ctx.Logf(cmdutil.LevelVerbose, "[%s] Deleted %s", colors.Green(ctx.Env.Name), colors.Blue(path))
+		return true
+	}
 
-		if err := ctx.Client.UpdateAsset(asset); err != nil {
-			ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key), err)
-		} else if ctx.Flags.Verbose {
-			ctx.Log.Printf("[%s] Updated %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
-		}
+	assetLimitSemaphore <- struct{}{}
+	defer func() { <-assetLimitSemaphore }()
+
+	asset, err := shopify.ReadAsset(ctx.Env, path)
+	if err != nil {
+		ctx.Err("[%s] error loading %s: %s", colors.Green(ctx.Env.Name), colors.Green(path), colors.Red(err))
+		return false
+	}
+
+	if err := ctx.Client.UpdateAsset(asset); err != nil {
+		ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key), err)
+		return false
 	}
+	ctx.Logf(cmdutil.LevelVerbose, "[%s] Updated %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+	return true
 }
@@ -1,8 +1,10 @@
 // Code generated by mockery v1.0.0. DO NOT EDIT.
 package mocks
 
+import io "io"
 import mock "github.com/stretchr/testify/mock"
 import shopify "github.com/Shopify/themekit/src/shopify"
+import time "time"
 
 // ShopifyClient is an autogenerated mock type for the ShopifyClient type
 type ShopifyClient struct {
@@ -32,6 +34,64 @@ func (_m *ShopifyClient) Themes() ([]shopify.Theme, error) {
 	return r0, r1
 }
 
+// FilterThemes provides a mock function with given fields: _a0
+func (_m *ShopifyClient) FilterThemes(_a0 shopify.ThemeFilter) ([]shopify.Theme, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []shopify.Theme
+	if rf, ok := ret.Get(0).(func(shopify.ThemeFilter) []shopify.Theme); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]shopify.Theme)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(shopify.ThemeFilter) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PreviewURL provides a mock function with given fields: _a0
+func (_m *ShopifyClient) PreviewURL(_a0 int64) (string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(int64) string); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EditorURL provides a mock function with given fields: _a0
+func (_m *ShopifyClient) EditorURL(_a0 int64) string {
+	ret := _m.Called(_a0)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(int64) string); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // CreateNewTheme provides a mock function with given fields: _a0, _a1
 func (_m *ShopifyClient) CreateNewTheme(_a0 string, _a1 string) (shopify.Theme, error) {
 	ret := _m.Called(_a0, _a1)
@@ -90,6 +150,29 @@ func (_m *ShopifyClient) GetAllAssets() ([]string, error) {
 	return r0, r1
 }
 
+// GetAssetChecksums provides a mock function with given fields:
+func (_m *ShopifyClient) GetAssetChecksums() (map[string]string, error) {
+	ret := _m.Called()
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func() map[string]string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAsset provides a mock function with given fields: _a0
 func (_m *ShopifyClient) GetAsset(_a0 string) (shopify.Asset, error) {
 	ret := _m.Called(_a0)
@@ -166,3 +249,75 @@ func (_m *ShopifyClient) UpdateAsset(_a0 shopify.Asset) error {
 
 	return r0
 }
+
+// UpdateAssetFromReaderAs provides a mock function with given fields: _a0, _a1, _a2
+func (_m *ShopifyClient) UpdateAssetFromReaderAs(_a0 string, _a1 io.Reader, _a2 bool) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, io.Reader, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetCachedAsset provides a mock function with given fields: _a0, _a1
+func (_m *ShopifyClient) GetCachedAsset(_a0 string, _a1 string) (shopify.Asset, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 shopify.Asset
+	if rf, ok := ret.Get(0).(func(string, string) shopify.Asset); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(shopify.Asset)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WaitForProcessing provides a mock function with given fields: _a0, _a1
+func (_m *ShopifyClient) WaitForProcessing(_a0 int64, _a1 time.Duration) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, time.Duration) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnusedAssets provides a mock function with given fields:
+func (_m *ShopifyClient) UnusedAssets() ([]string, error) {
+	ret := _m.Called()
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
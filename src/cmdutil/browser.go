@@ -0,0 +1,47 @@
+package cmdutil
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/skratchdot/open-golang/open"
+)
+
+var (
+	openURL     = open.Run
+	openURLWith = open.RunWith
+
+	// ErrNoBrowser is returned by OpenURL when there is no browser on the system to
+	// open the url with, like on a server, so that the url can be printed instead.
+	ErrNoBrowser = errors.New("no browser available")
+)
+
+// OpenURL will open the url in the default browser of the system, or in the named
+// browser if one is given. The name should match the name of the browser on the
+// system, like "Google Chrome" on macOS or "chrome" on windows and linux. The url is
+// opened with open on macOS, start on windows and xdg-open everywhere else.
+func OpenURL(url, browser string) error {
+	var err error
+	if browser == "" {
+		err = openURL(url)
+	} else {
+		err = openURLWith(url, browser)
+	}
+	if noBrowser(err) {
+		return fmt.Errorf("%w: %s", ErrNoBrowser, err)
+	}
+	return err
+}
+
+// noBrowser reports if opening a url failed because the command to open it does
+// not exist, or because xdg-open could not find a browser, which it reports with
+// the exit status 3.
+func noBrowser(err error) bool {
+	var exitErr *exec.ExitError
+	if errors.Is(err, exec.ErrNotFound) {
+		return true
+	}
+	return runtime.GOOS != "darwin" && runtime.GOOS != "windows" && errors.As(err, &exitErr) && exitErr.ExitCode() == 3
+}
@@ -0,0 +1,40 @@
+package cmdutil
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenURL(t *testing.T) {
+	defer func(run func(string) error, runWith func(string, string) error) {
+		openURL, openURLWith = run, runWith
+	}(openURL, openURLWith)
+
+	var opened, with string
+	openURL = func(url string) error {
+		opened, with = url, ""
+		return nil
+	}
+	openURLWith = func(url, browser string) error {
+		opened, with = url, browser
+		return errors.New("browser not found")
+	}
+
+	assert.Nil(t, OpenURL("https://shop.myshopify.com/admin/themes/123/editor", ""))
+	assert.Equal(t, "https://shop.myshopify.com/admin/themes/123/editor", opened)
+	assert.Equal(t, "", with)
+
+	assert.EqualError(t, OpenURL("https://shop.myshopify.com/?preview_theme_id=123", "firefox"), "browser not found")
+	assert.Equal(t, "https://shop.myshopify.com/?preview_theme_id=123", opened)
+	assert.Equal(t, "firefox", with)
+
+	openURL = func(url string) error {
+		return &exec.Error{Name: "xdg-open", Err: exec.ErrNotFound}
+	}
+	err := OpenURL("https://shop.myshopify.com/?preview_theme_id=123", "")
+	assert.True(t, errors.Is(err, ErrNoBrowser))
+	assert.Contains(t, err.Error(), "xdg-open")
+}
@@ -1,7 +1,12 @@
 package cmdutil
 
 import (
+	"io"
+	"log"
+	"time"
+
 	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/httpify"
 	"github.com/Shopify/themekit/src/shopify"
 )
 
@@ -9,11 +14,34 @@ type shopifyClient interface {
 	GetShop() (shopify.Shop, error)
 	CreateNewTheme(string, string) (shopify.Theme, error)
 	GetInfo() (shopify.Theme, error)
+	WaitForProcessing(int64, time.Duration) error
 	Themes() ([]shopify.Theme, error)
+	FilterThemes(shopify.ThemeFilter) ([]shopify.Theme, error)
+	PreviewURL(int64) (string, error)
+	EditorURL(int64) string
 	GetAllAssets() ([]string, error)
+	GetAssetChecksums() (map[string]string, error)
 	GetAsset(string) (shopify.Asset, error)
+	GetCachedAsset(string, string) (shopify.Asset, error)
 	UpdateAsset(shopify.Asset) error
+	UpdateAssetFromReaderAs(string, io.Reader, bool) error
 	DeleteAsset(shopify.Asset) error
+	UnusedAssets() ([]string, error)
+}
+
+// retryReporter is implemented by clients that can report retried requests
+type retryReporter interface {
+	SetRetryObserver(func(httpify.RetryEvent))
+}
+
+// requestReporter is implemented by clients that can report every request
+type requestReporter interface {
+	SetRequestObserver(func(httpify.RequestEvent))
+}
+
+// loggerSetter is implemented by clients that log messages of their own
+type loggerSetter interface {
+	SetLogger(*log.Logger)
 }
 
 type config interface {
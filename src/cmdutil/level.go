@@ -0,0 +1,37 @@
+package cmdutil
+
+// Level is how much a command logs
+type Level int
+
+const (
+	// LevelQuiet only logs errors and the output a command was asked for
+	LevelQuiet Level = iota
+	// LevelNormal shows a progress bar instead of a line for every file
+	LevelNormal
+	// LevelVerbose logs a line for every file that is changed or skipped
+	LevelVerbose
+	// LevelDebug also logs every request made to shopify
+	LevelDebug
+)
+
+// Level will return the level that the flags ask for. If several levels were
+// requested the most verbose one is used.
+func (flags Flags) Level() Level {
+	switch {
+	case flags.Debug:
+		return LevelDebug
+	case flags.Verbose:
+		return LevelVerbose
+	case flags.Quiet:
+		return LevelQuiet
+	}
+	return LevelNormal
+}
+
+// Logf acts like Printf on the context's Log but the message is only logged if
+// the context is running at the level or higher.
+func (ctx *Ctx) Logf(level Level, msg string, inter ...interface{}) {
+	if ctx.Flags.Level() >= level {
+		ctx.Log.Printf(msg, inter...)
+	}
+}
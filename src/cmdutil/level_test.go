@@ -0,0 +1,87 @@
+package cmdutil
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vbauerster/mpb"
+
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/httpify"
+)
+
+func TestFlags_Level(t *testing.T) {
+	assert.Equal(t, LevelNormal, Flags{}.Level())
+	assert.Equal(t, LevelQuiet, Flags{Quiet: true}.Level())
+	assert.Equal(t, LevelVerbose, Flags{Verbose: true}.Level())
+	assert.Equal(t, LevelVerbose, Flags{Verbose: true, Quiet: true}.Level())
+	assert.Equal(t, LevelDebug, Flags{Debug: true, Verbose: true}.Level())
+}
+
+func TestCtx_Levels(t *testing.T) {
+	retry := httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 1, Wait: time.Second, Err: errors.New("502 Bad Gateway")}
+	failed := httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 4, Err: errors.New("timeout"), Final: true}
+	request := httpify.RequestEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 1, Status: "200 OK", RequestID: "abc", CallLimit: "1/40", BytesReceived: 512, Duration: 120 * time.Millisecond}
+
+	testcases := []struct {
+		flags           Flags
+		shown, hidden   []string
+		errors, noError []string
+		progress        bool
+	}{
+		{
+			flags:   Flags{Quiet: true},
+			hidden:  []string{"normal message", "verbose message", "GET /admin/themes.json 200 OK"},
+			errors:  []string{"failed after 4 attempts: timeout"},
+			noError: []string{"retrying in 1s"},
+		},
+		{
+			flags:    Flags{},
+			shown:    []string{"normal message"},
+			hidden:   []string{"verbose message", "GET /admin/themes.json 200 OK"},
+			errors:   []string{"retrying in 1s", "failed after 4 attempts: timeout"},
+			progress: true,
+		},
+		{
+			flags:  Flags{Verbose: true},
+			shown:  []string{"normal message", "verbose message"},
+			hidden: []string{"GET /admin/themes.json 200 OK"},
+			errors: []string{"retrying in 1s", "failed after 4 attempts: timeout"},
+		},
+		{
+			flags:  Flags{Debug: true},
+			shown:  []string{"normal message", "verbose message", "[development] GET /admin/themes.json 200 OK in 120ms (attempt 1, request id: abc, api calls: 1/40, received 512 bytes)"},
+			errors: []string{"retrying in 1s", "failed after 4 attempts: timeout"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		stdOut, stdErr := bytes.NewBufferString(""), bytes.NewBufferString("")
+		ctx := Ctx{Env: &env.Env{Name: "development"}, Flags: testcase.flags, progress: mpb.New(nil), Log: log.New(stdOut, "", 0), ErrLog: log.New(stdErr, "", 0)}
+
+		ctx.Logf(LevelNormal, "normal message")
+		ctx.Logf(LevelVerbose, "verbose message")
+		ctx.logRetry(retry)
+		ctx.logRetry(failed)
+		ctx.logRequest(request)
+		ctx.StartProgress(1)
+
+		for _, msg := range testcase.shown {
+			assert.Contains(t, stdOut.String(), msg)
+		}
+		for _, msg := range testcase.hidden {
+			assert.NotContains(t, stdOut.String(), msg)
+		}
+		for _, msg := range testcase.errors {
+			assert.Contains(t, stdErr.String(), msg)
+		}
+		for _, msg := range testcase.noError {
+			assert.NotContains(t, stdErr.String(), msg)
+		}
+		assert.Equal(t, testcase.progress, ctx.Bar != nil)
+	}
+}
@@ -0,0 +1,81 @@
+package cmdutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/themekit/src/colors"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// ansiRegexp matches the escape codes used to color text
+var ansiRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// jsonLogLine is a single line of output in the json log format
+type jsonLogLine struct {
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	Domain    string `json:"domain,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// jsonLogWriter will write every message it receives as a json object on its own
+// line. A log.Logger writes each message with a single call to Write so a logger
+// using this writer will output one line per message.
+type jsonLogWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  string
+	domain string
+	now    func() time.Time
+}
+
+func (w jsonLogWriter) Write(p []byte) (int, error) {
+	line, err := json.Marshal(jsonLogLine{
+		Level:     w.level,
+		Message:   strings.TrimRight(ansiRegexp.ReplaceAllString(string(p), ""), "\r\n"),
+		Domain:    w.domain,
+		Timestamp: w.now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newLoggers will create the loggers for the output and error output in the
+// requested format. In the json format colors are disabled and every message is
+// written as a json object with its level, the shop domain and a timestamp.
+func newLoggers(format, domain string, stdOut, stdErr io.Writer) (*log.Logger, *log.Logger, error) {
+	switch format {
+	case "", logFormatText:
+		return log.New(stdOut, "", 0), log.New(stdErr, "", 0), nil
+	case logFormatJSON:
+		colors.Disable()
+		mu := &sync.Mutex{}
+		return newJSONLogger(mu, stdOut, "info", domain),
+			newJSONLogger(mu, stdErr, "error", domain),
+			nil
+	}
+	return nil, nil, fmt.Errorf("invalid log format %s, it should be %s or %s", format, logFormatText, logFormatJSON)
+}
+
+func newJSONLogger(mu *sync.Mutex, out io.Writer, level, domain string) *log.Logger {
+	return log.New(jsonLogWriter{mu: mu, out: out, level: level, domain: domain, now: time.Now}, "", 0)
+}
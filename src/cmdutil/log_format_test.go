@@ -0,0 +1,58 @@
+package cmdutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLogWriter(t *testing.T) {
+	out := bytes.NewBufferString("")
+	now := func() time.Time { return time.Date(2019, 3, 4, 5, 6, 7, 0, time.FixedZone("EST", -5*60*60)) }
+	logger := log.New(jsonLogWriter{mu: &sync.Mutex{}, out: out, level: "info", domain: "shop.myshopify.com", now: now}, "", 0)
+
+	logger.Printf("[%s] Updated %s", "\x1b[32mdevelopment\x1b[0m", "assets/app.js")
+	logger.Println("multi\nline \"quoted\"")
+	logger.Print("")
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if assert.Equal(t, 3, len(lines)) {
+		for _, line := range lines {
+			var parsed jsonLogLine
+			assert.Nil(t, json.Unmarshal([]byte(line), &parsed), line)
+			assert.Equal(t, "info", parsed.Level)
+			assert.Equal(t, "shop.myshopify.com", parsed.Domain)
+			assert.Equal(t, "2019-03-04T10:06:07Z", parsed.Timestamp)
+		}
+		assert.Equal(t, `{"level":"info","message":"[development] Updated assets/app.js","domain":"shop.myshopify.com","timestamp":"2019-03-04T10:06:07Z"}`, lines[0])
+		assert.Contains(t, lines[1], `"message":"multi\nline \"quoted\""`)
+	}
+}
+
+func TestNewLoggers(t *testing.T) {
+	out, errOut := bytes.NewBufferString(""), bytes.NewBufferString("")
+	stdOut, stdErr, err := newLoggers("", "shop.myshopify.com", out, errOut)
+	assert.Nil(t, err)
+	stdOut.Print("updated")
+	stdErr.Print("failed")
+	assert.Equal(t, "updated\n", out.String())
+	assert.Equal(t, "failed\n", errOut.String())
+
+	stdOut, stdErr, err = newLoggers("json", "shop.myshopify.com", out, errOut)
+	assert.Nil(t, err)
+	if assert.IsType(t, jsonLogWriter{}, stdOut.Writer()) {
+		assert.Equal(t, "info", stdOut.Writer().(jsonLogWriter).level)
+		assert.Equal(t, out, stdOut.Writer().(jsonLogWriter).out)
+		assert.Equal(t, "error", stdErr.Writer().(jsonLogWriter).level)
+		assert.Equal(t, errOut, stdErr.Writer().(jsonLogWriter).out)
+	}
+
+	_, _, err = newLoggers("xml", "shop.myshopify.com", out, errOut)
+	assert.EqualError(t, err, "invalid log format xml, it should be text or json")
+}
@@ -0,0 +1,37 @@
+package cmdutil
+
+import (
+	"strconv"
+)
+
+// optionalInt is an int flag that remembers if it was set so that a zero value
+// given on the command line can be told apart from the flag not being used.
+type optionalInt struct {
+	value int
+	set   bool
+}
+
+func (oi *optionalInt) String() string {
+	if !oi.set {
+		return ""
+	}
+	return strconv.Itoa(oi.value)
+}
+
+func (oi *optionalInt) Set(value string) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	oi.value, oi.set = parsed, true
+	return nil
+}
+
+func (oi *optionalInt) Type() string {
+	return "int"
+}
+
+// Value will return the value of the flag and if it was set
+func (oi *optionalInt) Value() (int, bool) {
+	return oi.value, oi.set
+}
@@ -0,0 +1,30 @@
+package cmdutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionalInt(t *testing.T) {
+	oi := optionalInt{}
+	assert.Equal(t, "", oi.String())
+	assert.Equal(t, "int", oi.Type())
+	value, set := oi.Value()
+	assert.Equal(t, 0, value)
+	assert.False(t, set)
+
+	assert.Nil(t, oi.Set("0"))
+	assert.Equal(t, "0", oi.String())
+	value, set = oi.Value()
+	assert.Equal(t, 0, value)
+	assert.True(t, set)
+
+	assert.Nil(t, oi.Set("5"))
+	value, _ = oi.Value()
+	assert.Equal(t, 5, value)
+
+	assert.NotNil(t, oi.Set("many"))
+	value, _ = oi.Value()
+	assert.Equal(t, 5, value)
+}
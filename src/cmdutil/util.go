@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ryanuber/go-glob"
@@ -16,6 +20,7 @@ import (
 
 	"github.com/Shopify/themekit/src/colors"
 	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/httpify"
 	"github.com/Shopify/themekit/src/shopify"
 )
 
@@ -33,26 +38,59 @@ type Flags struct {
 	Domain                string
 	Proxy                 string
 	Timeout               time.Duration
+	MaxRetries            optionalInt
 	Verbose               bool
+	Quiet                 bool
+	Debug                 bool
 	DisableUpdateNotifier bool
 	IgnoredFiles          stringArgArray
 	Ignores               stringArgArray
 	DisableIgnore         bool
 	NotifyFile            string
 	AllEnvs               bool
+	PollInterval          time.Duration
 	Version               string
 	Prefix                string
 	URL                   string
 	Name                  string
 	Edit                  bool
+	OpenPreview           bool
 	With                  string
 	List                  bool
+	Role                  string
+	NameFilter            string
 	NoDelete              bool
+	Delete                bool
+	SettingsData          string
+	CheckReferences       bool
+	ParallelEnvs          int
+	Resume                bool
+	SkipThemeCheck        bool
+	Key                   string
+	Binary                bool
+	DryRun                bool
+	RequireUnpublished    bool
+	Offline               bool
+	VersionCacheTTL       time.Duration
+	ProcessingTimeout     time.Duration
+	GithubToken           string
+	JSON                  bool
+	LogFormat             string
+	Color                 string
+	NoColor               bool
+	ForceColor            bool
+	// Stdout and Stderr are where commands write their output and errors instead
+	// of the standard output and error, so that programs running the commands
+	// can capture or redirect what they print. They cannot be set from the
+	// command line.
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
 // Ctx is a specific context that a command will run in
 type Ctx struct {
 	Shop     shopify.Shop
+	Themes   []shopify.Theme
 	Conf     config
 	Client   shopifyClient
 	Flags    Flags
@@ -60,7 +98,11 @@ type Ctx struct {
 	Args     []string
 	Log      *log.Logger
 	ErrLog   *log.Logger
+	Stdout   io.Writer
+	Stderr   io.Writer
 	errBuff  []string
+	warnBuff []string
+	errCount int
 	progress *mpb.Progress
 	Bar      *mpb.Bar
 	mu       sync.RWMutex
@@ -69,14 +111,35 @@ type Ctx struct {
 type clientFact func(*env.Env) (shopifyClient, error)
 
 func createCtx(newClient clientFact, conf env.Conf, e *env.Env, flags Flags, args []string, progress *mpb.Progress, setTheme bool) (*Ctx, error) {
-	if e.Proxy != "" {
-		colors.ColorStdOut.Printf(
+	outWriter, errWriter := flags.outputs()
+	stdOut, stdErr, err := newLoggers(flags.LogFormat, e.Domain, outWriter, errWriter)
+	if err != nil {
+		return &Ctx{}, err
+	}
+
+	if e.Proxy != "" && e.CAFile != "" {
+		stdOut.Printf(
+			"[%s] Proxy URL detected from Configuration [%s] SSL Certificates will be verified against the CA bundle [%s]",
+			colors.Green(e.Name),
+			colors.Yellow(e.Proxy),
+			colors.Yellow(e.CAFile),
+		)
+	} else if e.Proxy != "" {
+		stdOut.Printf(
 			"[%s] Proxy URL detected from Configuration [%s] SSL Certificate Validation will be disabled!",
 			colors.Green(e.Name),
 			colors.Yellow(e.Proxy),
 		)
 	}
 
+	if e.InsecureSkipVerify {
+		stdOut.Printf(
+			"[%s] %s insecure_skip_verify is set, SSL Certificate Validation is disabled and the connection to shopify could be intercepted!",
+			colors.Green(e.Name),
+			colors.Yellow("WARN"),
+		)
+	}
+
 	if flags.DisableIgnore {
 		e.IgnoredFiles = []string{}
 		e.Ignores = []string{}
@@ -87,15 +150,35 @@ func createCtx(newClient clientFact, conf env.Conf, e *env.Env, flags Flags, arg
 		return &Ctx{}, err
 	}
 
-	shop, err := client.GetShop()
-	if err != nil && err == shopify.ErrShopDomainNotFound {
-		colors.ColorStdErr.Printf(
-			"[%s] invalid credentials, the domain %s is not found",
-			colors.Green(e.Name),
-			colors.Yellow(e.Domain),
-		)
-		return &Ctx{}, fmt.Errorf("%s is an invalid domain", e.Domain)
-	} else if err != nil {
+	ctx := &Ctx{
+		Conf:     &conf,
+		Client:   client,
+		Env:      e,
+		Flags:    flags,
+		Args:     args,
+		progress: progress,
+		Log:      stdOut,
+		ErrLog:   stdErr,
+		Stdout:   outWriter,
+		Stderr:   errWriter,
+		errBuff:  []string{},
+	}
+	if flags.LogFormat == logFormatJSON {
+		// a progress bar would break up the json lines
+		ctx.progress = nil
+	}
+	if reporter, ok := client.(retryReporter); ok {
+		reporter.SetRetryObserver(ctx.logRetry)
+	}
+	if reporter, ok := client.(requestReporter); ok && flags.Level() >= LevelDebug {
+		reporter.SetRequestObserver(ctx.logRequest)
+	}
+	if setter, ok := client.(loggerSetter); ok {
+		setter.SetLogger(stdOut)
+	}
+
+	shop, err := checkCredentials(client, e, stdErr)
+	if err != nil {
 		return &Ctx{}, err
 	}
 
@@ -109,7 +192,7 @@ func createCtx(newClient clientFact, conf env.Conf, e *env.Env, flags Flags, arg
 			if theme.Role == "main" {
 				if fmt.Sprintf("%v", theme.ID) == e.ThemeID || e.ThemeID == "" {
 					e.ThemeID = fmt.Sprintf("%v", theme.ID) // record the theme id for the live id
-					colors.ColorStdOut.Printf(
+					stdOut.Printf(
 						"[%s] Warning, this is the live theme on %s.",
 						colors.Yellow(e.Name),
 						colors.Yellow(shop.Name),
@@ -120,27 +203,69 @@ func createCtx(newClient clientFact, conf env.Conf, e *env.Env, flags Flags, arg
 		}
 	}
 
-	return &Ctx{
-		Shop:     shop,
-		Conf:     &conf,
-		Client:   client,
-		Env:      e,
-		Flags:    flags,
-		Args:     args,
-		progress: progress,
-		Log:      colors.ColorStdOut,
-		ErrLog:   colors.ColorStdErr,
-		errBuff:  []string{},
-	}, nil
+	ctx.Shop = shop
+	ctx.Themes = themes
+	return ctx, nil
+}
+
+// checkCredentials will look up the shop to make sure that the domain exists and
+// that the password is accepted, so that a wrong domain and a wrong password can be
+// told apart before anything is changed or saved.
+func checkCredentials(client shopifyClient, e *env.Env, errLog *log.Logger) (shopify.Shop, error) {
+	shop, err := client.GetShop()
+	var apiErr *shopify.APIError
+	if err != nil && errors.Is(err, shopify.ErrShopDomainNotFound) {
+		errLog.Printf(
+			"[%s] invalid credentials, the domain %s is not found",
+			colors.Green(e.Name),
+			colors.Yellow(e.Domain),
+		)
+		return shop, fmt.Errorf("%s is an invalid domain", e.Domain)
+	} else if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		errLog.Printf(
+			"[%s] invalid credentials, the password was rejected by %s",
+			colors.Green(e.Name),
+			colors.Yellow(e.Domain),
+		)
+		return shop, fmt.Errorf("the password for %s is invalid: %s", e.Domain, err)
+	}
+	return shop, err
+}
+
+// CheckTheme will make sure that the theme of the environment exists on shopify so
+// that a command that changes the theme fails right away with a theme id that is no
+// longer valid, instead of part way through. The themes that were listed when the
+// context was created are used so that no extra request is made. The check is
+// skipped if the skip theme check flag is set.
+func CheckTheme(ctx *Ctx) error {
+	if ctx.Flags.SkipThemeCheck || ctx.Env.ThemeID == "" {
+		return nil
+	}
+
+	for _, theme := range ctx.Themes {
+		if fmt.Sprintf("%v", theme.ID) == ctx.Env.ThemeID {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"[%s] %w: there is no theme with the id %s on %s, run `theme get --list` to see the available themes",
+		colors.Green(ctx.Env.Name),
+		shopify.ErrThemeNotFound,
+		colors.Yellow(ctx.Env.ThemeID),
+		colors.Yellow(ctx.Env.Domain),
+	)
 }
 
 // StartProgress will create a new progress bar for the running context with the
 // total amount of tasks as the count
 func (ctx *Ctx) StartProgress(count int) {
-	if !ctx.Flags.Verbose && ctx.progress != nil {
+	if ctx.Flags.Level() == LevelNormal && ctx.progress != nil {
 		barErrors := func(w io.Writer, completed bool) {
 			ctx.mu.RLock()
 			defer ctx.mu.RUnlock()
+			for _, msg := range ctx.warnBuff {
+				io.WriteString(w, msg+"\r\n")
+			}
 			for _, msg := range ctx.errBuff {
 				io.WriteString(w, msg+"\r\n")
 			}
@@ -155,17 +280,78 @@ func (ctx *Ctx) StartProgress(count int) {
 	}
 }
 
-// Err acts like Printf but will display error messages better
+// Err acts like Printf but will display error messages better. Every error is
+// counted, whether it is shown with the progress bar or not, see Failed.
 func (ctx *Ctx) Err(msg string, inter ...interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.errCount++
+	if ctx.progress != nil && ctx.Bar != nil {
+		ctx.errBuff = append(ctx.errBuff, fmt.Sprintf(msg, inter...))
+	} else {
+		ctx.ErrLog.Printf(msg, inter...)
+	}
+}
+
+// Failed reports if any errors were reported with Err
+func (ctx *Ctx) Failed() bool {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return ctx.errCount > 0
+}
+
+// Warn acts like Err but for problems that the command recovers from, so they are
+// not counted as errors of the command.
+func (ctx *Ctx) Warn(msg string, inter ...interface{}) {
 	if ctx.progress != nil && ctx.Bar != nil {
 		ctx.mu.Lock()
 		defer ctx.mu.Unlock()
-		ctx.errBuff = append(ctx.errBuff, fmt.Sprintf(msg, inter...))
+		ctx.warnBuff = append(ctx.warnBuff, fmt.Sprintf(msg, inter...))
 	} else {
 		ctx.ErrLog.Printf(msg, inter...)
 	}
 }
 
+// logRetry will report a request that had to be retried. Retries are shown as
+// warnings so that they do not break up the progress bar, a request that failed
+// after being retried is shown as an error and a request that succeeded after
+// being retried is only logged in verbose mode. Retries are not shown when quiet.
+func (ctx *Ctx) logRetry(event httpify.RetryEvent) {
+	request := fmt.Sprintf("%s %s", event.Method, event.Path)
+	if !event.Final {
+		if ctx.Flags.Level() == LevelQuiet {
+			return
+		}
+		ctx.Warn("[%s] %s failed (%s), retrying in %s (attempt %d)", colors.Green(ctx.Env.Name), colors.Blue(request), colors.Yellow(event.Err), event.Wait, event.Attempt)
+	} else if event.Err != nil {
+		ctx.Err("[%s] %s failed after %d attempts: %s", colors.Green(ctx.Env.Name), colors.Blue(request), event.Attempt, colors.Red(event.Err))
+	} else {
+		ctx.Logf(LevelVerbose, "[%s] %s succeeded after %d attempts", colors.Green(ctx.Env.Name), colors.Blue(request), event.Attempt)
+	}
+}
+
+// logRequest will log the metadata of a request to shopify in debug mode
+func (ctx *Ctx) logRequest(event httpify.RequestEvent) {
+	result := event.Status
+	if event.Err != nil {
+		result = colors.Red(event.Err)
+	}
+	details := []string{fmt.Sprintf("attempt %d", event.Attempt)}
+	if event.RequestID != "" {
+		details = append(details, "request id: "+event.RequestID)
+	}
+	if event.CallLimit != "" {
+		details = append(details, "api calls: "+event.CallLimit)
+	}
+	if event.BytesSent > 0 {
+		details = append(details, fmt.Sprintf("sent %d bytes", event.BytesSent))
+	}
+	if event.BytesReceived > 0 {
+		details = append(details, fmt.Sprintf("received %d bytes", event.BytesReceived))
+	}
+	ctx.Logf(LevelDebug, "[%s] %s %s %s in %s (%s)", colors.Green(ctx.Env.Name), event.Method, colors.Blue(event.Path), result, event.Duration.Round(time.Millisecond), strings.Join(details, ", "))
+}
+
 // DoneTask will mark one unit of work complete. If the context has a progress bar
 // then it will increment it.
 func (ctx *Ctx) DoneTask() {
@@ -186,11 +372,12 @@ func generateContexts(newClient clientFact, progress *mpb.Progress, flags Flags,
 		return ctxs, err
 	}
 
-	for name := range config.Envs {
-		if !shouldUseEnvironment(flags, name) {
-			continue
-		}
+	names, err := envNames(config, flags)
+	if err != nil {
+		return ctxs, err
+	}
 
+	for _, name := range names {
 		e, err := config.Get(name, flagEnv)
 		if err != nil {
 			return ctxs, err
@@ -211,6 +398,32 @@ func generateContexts(newClient clientFact, progress *mpb.Progress, flags Flags,
 	return ctxs, nil
 }
 
+// envNames will return the names of all the environments that were requested
+// with any environment groups expanded to their members.
+func envNames(config env.Conf, flags Flags) ([]string, error) {
+	names := []string{}
+	seen := map[string]bool{}
+	for name := range config.Envs {
+		if !shouldUseEnvironment(flags, name) {
+			continue
+		}
+
+		expanded, err := config.Expand(name)
+		if err != nil {
+			return names, err
+		}
+
+		for _, envName := range expanded {
+			if !seen[envName] {
+				seen[envName] = true
+				names = append(names, envName)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func getFlagEnv(flags Flags) env.Env {
 	flagEnv := env.Env{
 		Directory: flags.Directory,
@@ -220,6 +433,10 @@ func getFlagEnv(flags Flags) env.Env {
 		Proxy:     flags.Proxy,
 		Timeout:   flags.Timeout,
 		Notify:    flags.NotifyFile,
+		DryRun:    flags.DryRun,
+
+		RequireUnpublished: flags.RequireUnpublished,
+		SettingsData:       flags.SettingsData,
 	}
 
 	if !flags.DisableIgnore {
@@ -227,9 +444,31 @@ func getFlagEnv(flags Flags) env.Env {
 		flagEnv.Ignores = flags.Ignores.Value()
 	}
 
+	if retries, set := flags.MaxRetries.Value(); set {
+		// a zero would be replaced by the config or the default
+		flagEnv.MaxRetries = retries
+		if retries <= 0 {
+			flagEnv.MaxRetries = env.NoRetries
+		}
+	}
+
 	return flagEnv
 }
 
+// ColorMode will return the color mode that the color flags ask for. --no-color
+// and --force-color are shorthands for --color=never and --color=always.
+func (flags Flags) ColorMode() (string, error) {
+	switch {
+	case flags.NoColor && flags.ForceColor:
+		return "", errors.New("--no-color and --force-color cannot be used together")
+	case flags.NoColor:
+		return colors.Never, nil
+	case flags.ForceColor:
+		return colors.Always, nil
+	}
+	return flags.Color, nil
+}
+
 func shouldUseEnvironment(flags Flags, envName string) bool {
 	flagEnvs := flags.Environments.Value()
 	if flags.AllEnvs || (len(flagEnvs) == 0 && envName == env.Default.Name) {
@@ -243,6 +482,26 @@ func shouldUseEnvironment(flags Flags, envName string) bool {
 	return false
 }
 
+// outputs will return where commands write their output and errors, the standard
+// output and error unless other writers were set.
+func (flags Flags) outputs() (io.Writer, io.Writer) {
+	stdOut, stdErr := flags.Stdout, flags.Stderr
+	if stdOut == nil {
+		stdOut = colors.ColorStdOut.Writer()
+	}
+	if stdErr == nil {
+		stdErr = colors.ColorStdErr.Writer()
+	}
+	return stdOut, stdErr
+}
+
+// newProgress will create the group of progress bars that writes to the output of
+// the commands.
+func newProgress(flags Flags) *mpb.Progress {
+	stdOut, _ := flags.outputs()
+	return mpb.New(mpb.WithOutput(stdOut))
+}
+
 // ForEachClient will generate a command context for all the available environments
 // and run a command in each of those contexts
 func ForEachClient(flags Flags, args []string, handler func(*Ctx) error) error {
@@ -250,15 +509,31 @@ func ForEachClient(flags Flags, args []string, handler func(*Ctx) error) error {
 }
 
 func forEachClient(newClient clientFact, flags Flags, args []string, handler func(*Ctx) error) error {
-	progressBarGroup := mpb.New(nil)
+	progressBarGroup := newProgress(flags)
 	ctxs, err := generateContexts(newClient, progressBarGroup, flags, args)
 	if err != nil {
 		return err
 	}
+	// environments run at the same time unless the parallel flag bounds them,
+	// each one has its own client and so its own rate limit for its store
+	limit := len(ctxs)
+	if flags.ParallelEnvs > 0 && flags.ParallelEnvs < limit {
+		limit = flags.ParallelEnvs
+	}
+	running := make(chan struct{}, limit)
+
 	var handlerGroup errgroup.Group
-	for _, ctx := range ctxs {
-		ctx := ctx
-		handlerGroup.Go(func() error { return handler(ctx) })
+	results := make([]envResult, len(ctxs))
+	for i, ctx := range ctxs {
+		i, ctx := i, ctx
+		handlerGroup.Go(func() error {
+			running <- struct{}{}
+			defer func() { <-running }()
+			start := time.Now()
+			results[i] = envResult{ctx: ctx, err: handler(ctx)}
+			results[i].duration = time.Since(start)
+			return results[i].err
+		})
 	}
 	err = handlerGroup.Wait()
 	if err == nil {
@@ -268,14 +543,61 @@ func forEachClient(newClient clientFact, flags Flags, args []string, handler fun
 		return forEachClient(newClient, flags, args, handler)
 	}
 	for _, ctx := range ctxs {
-		if len(ctx.errBuff) > 0 {
+		if ctx.Failed() {
 			ctx.ErrLog.Println("finished command with errors")
 			break
 		}
 	}
+	if len(ctxs) > 1 {
+		return reportResults(results)
+	}
 	return err
 }
 
+// envResult is how a command went for a single environment
+type envResult struct {
+	ctx      *Ctx
+	err      error
+	duration time.Duration
+}
+
+// failed reports if the command returned an error or reported errors for some
+// of the files in the environment.
+func (result envResult) failed() bool {
+	return result.err != nil || result.ctx.Failed()
+}
+
+// reportResults will print a summary of how the command went for every environment
+// so that the outcome is clear when running a command on several environments at
+// once. An error naming the environments that failed is returned if any did.
+func reportResults(results []envResult) error {
+	var table strings.Builder
+	w := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ENVIRONMENT\tSTATUS\tDURATION\tDETAILS")
+	failed := []string{}
+	for _, result := range results {
+		status, details := colors.Green("succeeded"), ""
+		if result.err != nil {
+			status, details = colors.Red("failed"), result.err.Error()
+		} else if result.ctx.Failed() {
+			status, details = colors.Red("failed"), fmt.Sprintf("%d errors", result.ctx.errCount)
+		}
+		if result.failed() {
+			failed = append(failed, result.ctx.Env.Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.ctx.Env.Name, status, result.duration.Round(time.Millisecond), details)
+	}
+	w.Flush()
+
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		results[0].ctx.Logf(LevelNormal, "%s", line)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d environments failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // ForSingleClient will generate a command context for all the available environments,
 // and run a command for the first context. If more than one environment was specified,
 // then an error will be returned.
@@ -284,7 +606,7 @@ func ForSingleClient(flags Flags, args []string, handler func(*Ctx) error) error
 }
 
 func forSingleClient(newClient clientFact, flags Flags, args []string, handler func(*Ctx) error) error {
-	progressBarGroup := mpb.New(nil)
+	progressBarGroup := newProgress(flags)
 	ctxs, err := generateContexts(newClient, progressBarGroup, flags, args)
 	if err != nil {
 		return err
@@ -298,7 +620,7 @@ func forSingleClient(newClient clientFact, flags Flags, args []string, handler f
 	if err == ErrReload {
 		return forSingleClient(newClient, flags, args, handler)
 	}
-	if len(ctxs[0].errBuff) > 0 {
+	if ctxs[0].Failed() {
 		ctxs[0].ErrLog.Println("finished command with errors")
 	}
 	return err
@@ -311,7 +633,7 @@ func ForDefaultClient(flags Flags, args []string, handler func(*Ctx) error) erro
 }
 
 func forDefaultClient(newClient clientFact, flags Flags, args []string, handler func(*Ctx) error) error {
-	progressBarGroup := mpb.New(nil)
+	progressBarGroup := newProgress(flags)
 	config, err := env.Load(flags.ConfigPath)
 	if err != nil && os.IsNotExist(err) {
 		config = env.New(flags.ConfigPath)
@@ -342,7 +664,7 @@ func forDefaultClient(newClient clientFact, flags Flags, args []string, handler
 	if err == nil {
 		progressBarGroup.Wait()
 	}
-	if len(ctx.errBuff) > 0 {
+	if ctx.Failed() {
 		ctx.ErrLog.Println("finished command with errors")
 	}
 	return err
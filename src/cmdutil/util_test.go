@@ -2,15 +2,21 @@ package cmdutil
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vbauerster/mpb"
 
 	"github.com/Shopify/themekit/src/cmdutil/_mocks"
+	"github.com/Shopify/themekit/src/colors"
 	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/httpify"
 	"github.com/Shopify/themekit/src/shopify"
 )
 
@@ -24,6 +30,16 @@ func TestCreateCtx(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid domain")
 	}
 
+	e = &env.Env{Domain: "shop.myshopify.com"}
+	client = new(mocks.ShopifyClient)
+	factory = func(*env.Env) (shopifyClient, error) { return client, nil }
+	client.On("GetShop").Return(shopify.Shop{}, &shopify.APIError{StatusCode: 401, ShopifyMessage: "[API] Invalid API key or access token (unrecognized login or wrong password)"})
+	_, err = createCtx(factory, env.Conf{}, e, Flags{}, []string{}, nil, false)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "the password for shop.myshopify.com is invalid: [API] Invalid API key or access token (unrecognized login or wrong password)", err.Error())
+	}
+	client.AssertNotCalled(t, "Themes")
+
 	e = &env.Env{Domain: "this is not a url%@#$@#"}
 	client = new(mocks.ShopifyClient)
 	factory = func(*env.Env) (shopifyClient, error) { return client, nil }
@@ -50,13 +66,82 @@ func TestCreateCtx(t *testing.T) {
 		assert.Contains(t, err.Error(), "[API] Invalid API key or access token (unrecognized login or wrong password)")
 	}
 
+	_, err = createCtx(factory, env.Conf{}, &env.Env{}, Flags{LogFormat: "xml"}, []string{}, nil, true)
+	assert.EqualError(t, err, "invalid log format xml, it should be text or json")
+
+	client = new(mocks.ShopifyClient)
+	client.On("GetShop").Return(shopify.Shop{}, nil)
+	client.On("Themes").Return([]shopify.Theme{}, nil)
+	ctx, err := createCtx(factory, env.Conf{}, &env.Env{Domain: "shop.myshopify.com"}, Flags{LogFormat: "json"}, []string{}, mpb.New(nil), true)
+	if assert.Nil(t, err) {
+		assert.Nil(t, ctx.progress)
+		assert.IsType(t, jsonLogWriter{}, ctx.Log.Writer())
+	}
+
 	e = &env.Env{Proxy: "http://localhost:3000"}
 	client = new(mocks.ShopifyClient)
 	client.On("GetShop").Return(shopify.Shop{}, nil)
 	client.On("Themes").Return([]shopify.Theme{{ID: 65443, Role: "unpublished"}, {ID: 1234, Role: "main"}}, nil)
-	_, err = createCtx(factory, env.Conf{}, e, Flags{DisableIgnore: true}, []string{}, nil, true)
+	ctx, err = createCtx(factory, env.Conf{}, e, Flags{DisableIgnore: true}, []string{}, nil, true)
 	assert.Nil(t, err)
 	assert.Equal(t, e.ThemeID, "1234")
+	assert.Equal(t, 2, len(ctx.Themes))
+	assert.Nil(t, CheckTheme(ctx))
+	client.AssertNotCalled(t, "GetInfo")
+
+	out, errOut := bytes.NewBufferString(""), bytes.NewBufferString("")
+	e = &env.Env{Name: "development", Proxy: "http://localhost:3000"}
+	client = new(mocks.ShopifyClient)
+	client.On("GetShop").Return(shopify.Shop{}, nil)
+	client.On("Themes").Return([]shopify.Theme{}, nil)
+	ctx, err = createCtx(factory, env.Conf{}, e, Flags{Stdout: out, Stderr: errOut}, []string{}, nil, true)
+	if assert.Nil(t, err) {
+		assert.Equal(t, out, ctx.Stdout)
+		assert.Equal(t, errOut, ctx.Stderr)
+		assert.Contains(t, out.String(), "Proxy URL detected")
+		assert.Contains(t, out.String(), "SSL Certificate Validation will be disabled!")
+		ctx.Err("could not update %s", "assets/app.js")
+		assert.Equal(t, "could not update assets/app.js\n", errOut.String())
+	}
+
+	out = bytes.NewBufferString("")
+	e.CAFile = "proxy-ca.pem"
+	_, err = createCtx(factory, env.Conf{}, e, Flags{Stdout: out}, []string{}, nil, true)
+	if assert.Nil(t, err) {
+		assert.Contains(t, out.String(), "SSL Certificates will be verified against the CA bundle [proxy-ca.pem]")
+		assert.NotContains(t, out.String(), "SSL Certificate Validation will be disabled!")
+	}
+	e.CAFile = ""
+
+	ctx, err = createCtx(factory, env.Conf{}, e, Flags{}, []string{}, nil, true)
+	if assert.Nil(t, err) {
+		assert.Equal(t, colors.ColorStdOut.Writer(), ctx.Stdout)
+		assert.Equal(t, colors.ColorStdErr.Writer(), ctx.Stderr)
+	}
+}
+
+func TestFlags_ColorMode(t *testing.T) {
+	testcases := []struct {
+		flags Flags
+		mode  string
+		err   string
+	}{
+		{flags: Flags{}, mode: ""},
+		{flags: Flags{Color: "never"}, mode: "never"},
+		{flags: Flags{Color: "auto", NoColor: true}, mode: "never"},
+		{flags: Flags{Color: "auto", ForceColor: true}, mode: "always"},
+		{flags: Flags{NoColor: true, ForceColor: true}, err: "--no-color and --force-color cannot be used together"},
+	}
+
+	for _, testcase := range testcases {
+		mode, err := testcase.flags.ColorMode()
+		assert.Equal(t, testcase.mode, mode)
+		if testcase.err != "" {
+			assert.EqualError(t, err, testcase.err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
 }
 
 func TestCtx_StartProgress(t *testing.T) {
@@ -83,6 +168,80 @@ func TestCtx_Err(t *testing.T) {
 	assert.NotContains(t, stdErr.String(), "[production] this is err")
 }
 
+func TestCheckTheme(t *testing.T) {
+	client := new(mocks.ShopifyClient)
+	ctx := &Ctx{Env: &env.Env{Name: "development", Domain: "shop.myshopify.com"}, Client: client}
+	ctx.Themes = []shopify.Theme{{ID: 123, Role: "main"}, {ID: 456, Role: "unpublished"}}
+	assert.Nil(t, CheckTheme(ctx))
+
+	ctx.Env.ThemeID = "456"
+	assert.Nil(t, CheckTheme(ctx))
+
+	ctx.Env.ThemeID = "789"
+	err := CheckTheme(ctx)
+	assert.True(t, errors.Is(err, shopify.ErrThemeNotFound))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "789")
+		assert.Contains(t, err.Error(), "theme get --list")
+	}
+
+	ctx.Flags.SkipThemeCheck = true
+	assert.Nil(t, CheckTheme(ctx))
+	client.AssertNotCalled(t, "GetInfo")
+}
+
+type retryingClient struct {
+	*mocks.ShopifyClient
+	observer        func(httpify.RetryEvent)
+	requestObserver func(httpify.RequestEvent)
+}
+
+func (client *retryingClient) SetRetryObserver(observer func(httpify.RetryEvent)) {
+	client.observer = observer
+}
+
+func (client *retryingClient) SetRequestObserver(observer func(httpify.RequestEvent)) {
+	client.requestObserver = observer
+}
+
+func TestCtx_logRetry(t *testing.T) {
+	stdOut, stdErr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	ctx := Ctx{Env: &env.Env{Name: "development"}, Log: log.New(stdOut, "", 0), ErrLog: log.New(stdErr, "", 0)}
+
+	ctx.logRetry(httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 1, Wait: time.Second, Err: errors.New("502 Bad Gateway")})
+	assert.Contains(t, stdErr.String(), "[development] GET /admin/themes.json failed (502 Bad Gateway), retrying in 1s (attempt 1)")
+
+	ctx.logRetry(httpify.RetryEvent{Method: "PUT", Path: "/admin/themes/1/assets.json", Attempt: 4, Err: errors.New("timeout"), Final: true})
+	assert.Contains(t, stdErr.String(), "[development] PUT /admin/themes/1/assets.json failed after 4 attempts: timeout")
+
+	ctx.logRetry(httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 2, Final: true})
+	assert.Equal(t, "", stdOut.String())
+	ctx.Flags.Verbose = true
+	ctx.logRetry(httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 2, Final: true})
+	assert.Contains(t, stdOut.String(), "[development] GET /admin/themes.json succeeded after 2 attempts")
+
+	ctx = Ctx{Env: &env.Env{Name: "development"}, progress: mpb.New(nil), Log: log.New(stdOut, "", 0), ErrLog: log.New(stdErr, "", 0)}
+	ctx.StartProgress(1)
+	ctx.logRetry(httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 1, Wait: time.Second, Err: errors.New("502 Bad Gateway")})
+	ctx.logRetry(httpify.RetryEvent{Method: "GET", Path: "/admin/themes.json", Attempt: 2, Final: true})
+	assert.Equal(t, 1, len(ctx.warnBuff))
+	assert.Equal(t, 0, len(ctx.errBuff))
+	assert.False(t, envResult{ctx: &ctx}.failed())
+
+	client := &retryingClient{ShopifyClient: new(mocks.ShopifyClient)}
+	client.On("GetShop").Return(shopify.Shop{}, nil)
+	client.On("Themes").Return([]shopify.Theme{}, nil)
+	factory := func(*env.Env) (shopifyClient, error) { return client, nil }
+	_, err := createCtx(factory, env.Conf{}, &env.Env{}, Flags{}, []string{}, nil, false)
+	assert.Nil(t, err)
+	assert.NotNil(t, client.observer)
+	assert.Nil(t, client.requestObserver)
+
+	_, err = createCtx(factory, env.Conf{}, &env.Env{}, Flags{Debug: true}, []string{}, nil, false)
+	assert.Nil(t, err)
+	assert.NotNil(t, client.requestObserver)
+}
+
 func TestCtx_DoneTask(t *testing.T) {
 	ctx := Ctx{Env: &env.Env{}, Flags: Flags{}, progress: mpb.New(nil)}
 	assert.NotPanics(t, ctx.DoneTask)
@@ -117,6 +276,23 @@ func TestGenerateContexts(t *testing.T) {
 	assert.EqualError(t, err, "not today")
 }
 
+func TestGenerateContextsGroups(t *testing.T) {
+	client := new(mocks.ShopifyClient)
+	factory := func(*env.Env) (shopifyClient, error) { return client, nil }
+	client.On("GetShop").Return(shopify.Shop{}, nil)
+	client.On("Themes").Return([]shopify.Theme{}, nil)
+
+	ctxs, err := generateContexts(factory, nil, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"both", "staging"}}}, []string{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(ctxs)) {
+		assert.Equal(t, "preview", ctxs[0].Env.Name)
+		assert.Equal(t, "staging", ctxs[1].Env.Name)
+	}
+
+	_, err = generateContexts(factory, nil, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"loop"}}}, []string{})
+	assert.True(t, errors.Is(err, env.ErrEnvGroupCycle))
+}
+
 func TestGetFlagEnv(t *testing.T) {
 	flags := Flags{
 		Directory:    "d",
@@ -126,8 +302,11 @@ func TestGetFlagEnv(t *testing.T) {
 		Proxy:        "r",
 		Timeout:      1,
 		NotifyFile:   "n",
+		DryRun:       true,
 		IgnoredFiles: stringArgArray{[]string{"i"}},
 		Ignores:      stringArgArray{[]string{"c"}},
+
+		RequireUnpublished: true,
 	}
 
 	e := env.Env{
@@ -138,8 +317,11 @@ func TestGetFlagEnv(t *testing.T) {
 		Proxy:        "r",
 		Timeout:      1,
 		Notify:       "n",
+		DryRun:       true,
 		IgnoredFiles: []string{"i"},
 		Ignores:      []string{"c"},
+
+		RequireUnpublished: true,
 	}
 
 	assert.Equal(t, e, getFlagEnv(flags))
@@ -155,11 +337,44 @@ func TestGetFlagEnv(t *testing.T) {
 		Proxy:     "r",
 		Timeout:   1,
 		Notify:    "n",
+		DryRun:    true,
+
+		RequireUnpublished: true,
 	}
 
 	assert.Equal(t, e, getFlagEnv(flags))
 }
 
+func TestGetFlagEnv_MaxRetries(t *testing.T) {
+	base := env.Env{Domain: "shop.myshopify.com", Password: "abc123"}
+	configured := base
+	configured.MaxRetries = 5
+
+	testcases := []struct {
+		flag     string
+		config   env.Env
+		expected int
+	}{
+		{config: base, expected: env.Default.MaxRetries},
+		{config: configured, expected: 5},
+		{flag: "7", config: configured, expected: 7},
+		{flag: "0", config: configured, expected: env.NoRetries},
+		{flag: "1", config: base, expected: 1},
+	}
+
+	for _, testcase := range testcases {
+		flags := Flags{}
+		if testcase.flag != "" {
+			assert.Nil(t, flags.MaxRetries.Set(testcase.flag))
+		}
+		conf := env.New("")
+		e, err := conf.Set("development", testcase.config, getFlagEnv(flags))
+		if assert.Nil(t, err) {
+			assert.Equal(t, testcase.expected, e.MaxRetries, testcase.flag)
+		}
+	}
+}
+
 func TestShouldUseEnvironment(t *testing.T) {
 	testcases := []struct {
 		envs, should, shouldNot []string
@@ -240,6 +455,80 @@ func TestForEachClient(t *testing.T) {
 	client.On("Themes").Return([]shopify.Theme{}, nil)
 	forEachClient(factory, Flags{ConfigPath: "_testdata/config.yml"}, []string{}, handler)
 	assert.Contains(t, stdErr.String(), "finished command with errors")
+
+	stdOut := bytes.NewBufferString("")
+	stdErr = bytes.NewBufferString("")
+	handler = func(ctx *Ctx) error {
+		ctx.Log = log.New(stdOut, "", 0)
+		ctx.ErrLog = log.New(stdErr, "", 0)
+		if ctx.Env.Name == "preview" {
+			return gandalfErr
+		}
+		return nil
+	}
+	client = new(mocks.ShopifyClient)
+	factory = func(*env.Env) (shopifyClient, error) { return client, nil }
+	client.On("GetShop").Return(shopify.Shop{}, nil)
+	client.On("Themes").Return([]shopify.Theme{}, nil)
+	err = forEachClient(factory, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"both"}}}, []string{}, handler)
+	assert.EqualError(t, err, "1 of 2 environments failed: preview")
+	assert.Regexp(t, `ENVIRONMENT\s+STATUS\s+DURATION\s+DETAILS`, stdOut.String())
+	assert.Regexp(t, `staging\s+succeeded`, stdOut.String())
+	assert.Regexp(t, `preview\s+failed\s+\S+\s+`+gandalfErr.Error(), stdOut.String())
+
+	stdOut = bytes.NewBufferString("")
+	handler = func(ctx *Ctx) error {
+		ctx.Log = log.New(stdOut, "", 0)
+		ctx.ErrLog = log.New(ioutil.Discard, "", 0)
+		ctx.StartProgress(1)
+		if ctx.Env.Name == "staging" {
+			ctx.Err("could not update assets/app.js")
+		}
+		ctx.DoneTask()
+		return nil
+	}
+	err = forEachClient(factory, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"both"}}}, []string{}, handler)
+	assert.EqualError(t, err, "1 of 2 environments failed: staging")
+	assert.Regexp(t, `staging\s+failed\s+\S+\s+1 errors`, stdOut.String())
+	assert.Regexp(t, `preview\s+succeeded`, stdOut.String())
+
+	stdOut = bytes.NewBufferString("")
+	handler = func(ctx *Ctx) error {
+		ctx.Log = log.New(stdOut, "", 0)
+		ctx.ErrLog = log.New(ioutil.Discard, "", 0)
+		if ctx.Env.Name == "staging" {
+			ctx.Err("could not update assets/app.js")
+		}
+		return nil
+	}
+	err = forEachClient(factory, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"both"}}, Verbose: true}, []string{}, handler)
+	assert.EqualError(t, err, "1 of 2 environments failed: staging")
+	assert.Regexp(t, `staging\s+failed\s+\S+\s+1 errors`, stdOut.String())
+
+	var lock sync.Mutex
+	running, most := 0, 0
+	handler = func(ctx *Ctx) error {
+		ctx.Log = log.New(ioutil.Discard, "", 0)
+		lock.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return nil
+	}
+	err = forEachClient(factory, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"both"}}, ParallelEnvs: 1}, []string{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, most)
+
+	stdOut = bytes.NewBufferString("")
+	err = forEachClient(factory, Flags{ConfigPath: "_testdata/groups.yml", Environments: stringArgArray{[]string{"both"}}, Stdout: stdOut}, []string{}, safeHandler)
+	assert.Nil(t, err)
+	assert.Regexp(t, `staging\s+succeeded`, stdOut.String())
 }
 
 func TestForSingleClient(t *testing.T) {
@@ -354,3 +643,34 @@ func TestForDefaultClient(t *testing.T) {
 	forDefaultClient(factory, Flags{ConfigPath: "_testdata/config.yml"}, []string{}, handler)
 	assert.Contains(t, stdErr.String(), "finished command with errors")
 }
+
+func TestForDefaultClient_Credentials(t *testing.T) {
+	testcases := []struct {
+		shopErr error
+		err     string
+	}{
+		{},
+		{shopErr: shopify.ErrShopDomainNotFound, err: "shop.myshopify.com is an invalid domain"},
+		{shopErr: &shopify.APIError{StatusCode: 401, ShopifyMessage: "Invalid API key or access token"}, err: "the password for shop.myshopify.com is invalid: Invalid API key or access token"},
+		{shopErr: &shopify.APIError{StatusCode: 403, ShopifyMessage: "Forbidden"}, err: "the password for shop.myshopify.com is invalid: Forbidden"},
+	}
+
+	for _, testcase := range testcases {
+		saved := false
+		client := new(mocks.ShopifyClient)
+		factory := func(*env.Env) (shopifyClient, error) { return client, nil }
+		client.On("GetShop").Return(shopify.Shop{}, testcase.shopErr)
+		client.On("Themes").Return([]shopify.Theme{}, nil)
+		err := forDefaultClient(factory, Flags{Domain: "shop.myshopify.com", Password: "123"}, []string{}, func(*Ctx) error {
+			saved = true
+			return nil
+		})
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.True(t, saved)
+		} else {
+			assert.EqualError(t, err, testcase.err)
+			assert.False(t, saved)
+		}
+	}
+}
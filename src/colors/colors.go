@@ -1,10 +1,23 @@
 package colors
 
 import (
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/fatih/color"
 	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	// Auto uses colors if the output is a terminal or FORCE_COLOR is set, unless
+	// NO_COLOR is set
+	Auto = "auto"
+	// Always uses colors even if the output is not a terminal
+	Always = "always"
+	// Never does not use colors
+	Never = "never"
 )
 
 var (
@@ -20,4 +33,47 @@ var (
 	ColorStdOut = log.New(colorable.NewColorableStdout(), "", 0)
 	// ColorStdErr is a wrapped std err that allows colors
 	ColorStdErr = log.New(colorable.NewColorableStderr(), "", 0)
+
+	isTerminal = func() bool {
+		return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+	}
 )
+
+func init() {
+	SetMode(Auto)
+}
+
+// SetMode will set when colors are used. In the Auto mode colors are only used if
+// the standard output is a terminal, the NO_COLOR environment variable is not set
+// and the terminal is not a dumb terminal, so that output that is piped into a
+// file is not garbled by escape codes. CI systems that render colors but do not
+// run commands in a terminal can set FORCE_COLOR to keep the colors.
+func SetMode(mode string) error {
+	switch mode {
+	case "", Auto:
+		forced := os.Getenv("FORCE_COLOR") != ""
+		color.NoColor = os.Getenv("NO_COLOR") != "" || (!forced && (os.Getenv("TERM") == "dumb" || !isTerminal()))
+	case Always:
+		Enable()
+	case Never:
+		Disable()
+	default:
+		return fmt.Errorf("invalid color mode %s, it should be %s, %s or %s", mode, Auto, Always, Never)
+	}
+	return nil
+}
+
+// Enabled will return whether colors are being added to text
+func Enabled() bool {
+	return !color.NoColor
+}
+
+// Enable will add colors to text even if the output is not a terminal
+func Enable() {
+	color.NoColor = false
+}
+
+// Disable will stop any colors from being added to text
+func Disable() {
+	color.NoColor = true
+}
@@ -0,0 +1,75 @@
+package colors
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Auto)
+	defer func(original func() bool) { isTerminal = original }(isTerminal)
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+	defer os.Setenv("FORCE_COLOR", os.Getenv("FORCE_COLOR"))
+	defer os.Setenv("TERM", os.Getenv("TERM"))
+	os.Setenv("TERM", "xterm")
+
+	testcases := []struct {
+		mode, noColor string
+		forceColor    string
+		terminal      bool
+		colored       bool
+		err           string
+	}{
+		{mode: Auto, terminal: true, colored: true},
+		{mode: "", terminal: true, colored: true},
+		{mode: Auto, terminal: false, colored: false},
+		{mode: Auto, terminal: true, noColor: "1", colored: false},
+		{mode: Auto, terminal: false, forceColor: "1", colored: true},
+		{mode: Auto, terminal: false, forceColor: "1", noColor: "1", colored: false},
+		{mode: Always, terminal: false, noColor: "1", colored: true},
+		{mode: Never, terminal: true, colored: false},
+		{mode: "sometimes", terminal: true, colored: true, err: "invalid color mode sometimes, it should be auto, always or never"},
+	}
+
+	for _, testcase := range testcases {
+		isTerminal = func() bool { return testcase.terminal }
+		os.Setenv("NO_COLOR", testcase.noColor)
+		os.Setenv("FORCE_COLOR", testcase.forceColor)
+		SetMode(Always)
+
+		err := SetMode(testcase.mode)
+		if testcase.err != "" {
+			assert.EqualError(t, err, testcase.err)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, testcase.colored, Enabled(), testcase.mode)
+		assert.Equal(t, testcase.colored, strings.Contains(Green("text"), "\x1b["), testcase.mode)
+	}
+
+	isTerminal = func() bool { return true }
+	os.Setenv("NO_COLOR", "")
+	os.Setenv("FORCE_COLOR", "")
+	os.Setenv("TERM", "dumb")
+	SetMode(Auto)
+	assert.False(t, Enabled())
+	assert.Equal(t, "text", Red("text"))
+}
+
+func TestEnable(t *testing.T) {
+	defer SetMode(Auto)
+	defer func(original func() bool) { isTerminal = original }(isTerminal)
+	isTerminal = func() bool { return false }
+
+	SetMode(Auto)
+	Enable()
+	assert.True(t, Enabled())
+	assert.Equal(t, "\x1b[33mtext\x1b[0m", Yellow("text"))
+
+	Disable()
+	assert.False(t, Enabled())
+	assert.Equal(t, "text", Yellow("text"))
+}
@@ -7,12 +7,19 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode"
 
 	"encoding/json"
 	"github.com/caarlos0/env"
 	"gopkg.in/yaml.v1"
 )
 
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
 var (
 	supportedExts = []string{"yml", "yaml", "json"}
 	// ErrEnvDoesNotExist is returned when an environment that does not exist in the config is requested
@@ -23,28 +30,37 @@ var (
 	ErrNoEnvironmentsDefined = errors.New("no environments defined, nothing to write")
 	// ErrInvalidEnvironmentName is returned if an environment is trying to be set with a blank name
 	ErrInvalidEnvironmentName = errors.New("environment name cannot be blank")
+	// ErrEnvGroupCycle is returned if an environment group ends up referencing itself
+	ErrEnvGroupCycle = errors.New("environment group references itself")
 )
 
 // Conf is a map of configurations to their environment name.
 type Conf struct {
-	Envs  map[string]*Env
-	osEnv Env
-	path  string
+	Envs   map[string]*Env
+	osEnv  Env
+	path   string
+	format string
 }
 
 // New will build a new blank config
 func New(configPath string) Conf {
 	conf := Conf{
-		Envs:  make(map[string]*Env),
-		osEnv: Env{},
-		path:  configPath,
+		Envs:   make(map[string]*Env),
+		osEnv:  Env{},
+		path:   configPath,
+		format: formatYAML,
+	}
+	if filepath.Ext(configPath) == ".json" {
+		conf.format = formatJSON
 	}
 	env.Parse(&conf.osEnv)
 	return conf
 }
 
 // Load will read in the file from the configPath provided and
-// then unmarshal the data into conf.
+// then unmarshal the data into conf. The format is picked from the file
+// extension, or from the contents if the extension is not a known one, and
+// it is kept so that saving writes the config back in the same format.
 func Load(configPath string) (Conf, error) {
 	conf := New(configPath)
 	path, ext, err := searchConfigPath(configPath)
@@ -54,12 +70,13 @@ func Load(configPath string) (Conf, error) {
 
 	contents, err := ioutil.ReadFile(path)
 	if err == nil {
-		switch ext {
-		case "yml", "yaml":
+		conf.format = detectFormat(ext, contents)
+		switch conf.format {
+		case formatYAML:
 			if err = yaml.Unmarshal(contents, &conf.Envs); err != nil {
 				return conf, fmt.Errorf("Invalid yaml found while loading the config file: %v", err)
 			}
-		case "json":
+		case formatJSON:
 			if err = json.Unmarshal(contents, &conf.Envs); err != nil {
 				return conf, fmt.Errorf("Invalid json found while loading the config file: %v", err)
 			}
@@ -84,7 +101,9 @@ func (c *Conf) Set(name string, initial Env, overrides ...Env) (*Env, error) {
 }
 
 // Get will check if an environment exists and then return it. If the environment
-// does not exists it will return an error
+// does not exists it will return an error. Any ${VAR} references in the config
+// values are expanded from the process environment, the config itself is left
+// as is so that saving it will not write out the values.
 func (c *Conf) Get(name string, overrides ...Env) (*Env, error) {
 	env, exists := c.Envs[name]
 	if !exists {
@@ -92,7 +111,53 @@ func (c *Conf) Get(name string, overrides ...Env) (*Env, error) {
 	} else if env == nil {
 		return env, ErrEnvNotDefined
 	}
-	return newEnv(name, *env, append([]Env{c.osEnv}, overrides...)...)
+	initial := *env
+	if err := initial.interpolate(); err != nil {
+		return nil, fmt.Errorf("invalid environment [%s]: %v", name, err)
+	}
+	return newEnv(name, initial, append([]Env{c.osEnv}, overrides...)...)
+}
+
+// Expand will return the names of the environments that the name refers to. An
+// environment group is replaced by all of its members, which can be groups themselves,
+// and any other environment is returned as is.
+func (c *Conf) Expand(name string) ([]string, error) {
+	return c.expand(name, []string{})
+}
+
+func (c *Conf) expand(name string, path []string) ([]string, error) {
+	for _, visited := range path {
+		if visited == name {
+			return nil, fmt.Errorf("%w: %s", ErrEnvGroupCycle, strings.Join(append(path, name), " -> "))
+		}
+	}
+
+	env, exists := c.Envs[name]
+	if !exists {
+		return nil, ErrEnvDoesNotExist
+	} else if env == nil || len(env.Group) == 0 {
+		return []string{name}, nil
+	}
+
+	path = append(append([]string{}, path...), name)
+	names := []string{}
+	seen := map[string]bool{}
+	for _, member := range env.Group {
+		expanded, err := c.expand(member, path)
+		if err == ErrEnvDoesNotExist {
+			return nil, fmt.Errorf("environment group %s references %s which does not exist", name, member)
+		} else if err != nil {
+			return nil, err
+		}
+		for _, envName := range expanded {
+			if !seen[envName] {
+				seen[envName] = true
+				names = append(names, envName)
+			}
+		}
+	}
+
+	return names, nil
 }
 
 // Save will write out the config to a file.
@@ -118,6 +183,12 @@ func (c Conf) save(w io.Writer) error {
 		if env.Timeout == Default.Timeout {
 			env.Timeout = 0
 		}
+		if env.MaxRetries == Default.MaxRetries {
+			env.MaxRetries = 0
+		}
+		if env.RetryDelay == Default.RetryDelay {
+			env.RetryDelay = 0
+		}
 		c.Envs[name] = env
 	}
 
@@ -125,7 +196,15 @@ func (c Conf) save(w io.Writer) error {
 		return ErrNoEnvironmentsDefined
 	}
 
-	bytes, err := yaml.Marshal(c.Envs)
+	var bytes []byte
+	var err error
+	if c.format == formatJSON {
+		if bytes, err = json.MarshalIndent(c.Envs, "", "  "); err == nil {
+			bytes = append(bytes, '\n')
+		}
+	} else {
+		bytes, err = yaml.Marshal(c.Envs)
+	}
 	if err != nil {
 		return err
 	}
@@ -148,5 +227,24 @@ func searchConfigPath(configPath string) (string, string, error) {
 			return foundPath, ext, nil
 		}
 	}
+	if info, err := os.Stat(configPath); err == nil && !info.IsDir() {
+		return configPath, "", nil
+	}
 	return "", "", os.ErrNotExist
 }
+
+// detectFormat will return the format of a config file by its extension, or
+// by looking at its contents when the extension is not one we know.
+func detectFormat(ext string, contents []byte) string {
+	switch ext {
+	case "yml", "yaml":
+		return formatYAML
+	case "json":
+		return formatJSON
+	}
+	trimmed := strings.TrimLeftFunc(string(contents), unicode.IsSpace)
+	if strings.HasPrefix(trimmed, "{") {
+		return formatJSON
+	}
+	return formatYAML
+}
@@ -2,9 +2,11 @@ package env
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -26,6 +28,7 @@ func TestLoad(t *testing.T) {
 		{path: "_testdata/projectdir/config.json", err: ""},
 		{path: "_testdata/projectdir/bad_config.json", err: "Invalid json found while loading the config file"},
 		{path: "_testdata/projectdir/not_there.json", err: "file does not exist"},
+		{path: "_testdata/projectdir/config.conf", err: ""},
 	}
 
 	for _, testcase := range testcases {
@@ -48,6 +51,8 @@ func TestSearchConfigPath(t *testing.T) {
 		{path: "_testdata/projectdir/bad_format.yml", err: nil, ext: "yml"},
 		{path: "_testdata/projectdir/config.json", err: nil, ext: "json"},
 		{path: "_testdata/projectdir/not_there.json", err: os.ErrNotExist, ext: ""},
+		{path: "_testdata/projectdir/config.conf", err: nil, ext: ""},
+		{path: "_testdata/projectdir/not_there.conf", err: os.ErrNotExist, ext: ""},
 	}
 
 	for _, testcase := range testcases {
@@ -67,9 +72,9 @@ func TestConf_Set(t *testing.T) {
 	}{
 		{name: "", initial: Env{}, err: ErrInvalidEnvironmentName.Error()},
 		{name: "development", initial: Env{}, err: "invalid environment"},
-		{name: "development", initial: Env{Domain: "yes.myshopify.com", Password: "abc123"}, expected: Env{Name: "development", Domain: "yes.myshopify.com", Password: "abc123", Directory: Default.Directory, Timeout: Default.Timeout}},
-		{name: "development", initial: Env{Domain: "yes.myshopify.com", Password: "abc123", Directory: filepath.Join("..", "file")}, expected: Env{Name: "development", Domain: "yes.myshopify.com", Password: "abc123", Directory: filepath.Join("..", "file"), Timeout: Default.Timeout}},
-		{name: "development", initial: Env{Domain: "yes.myshopify.com", Password: "abc123"}, overrides: []Env{{ThemeID: "12345"}}, expected: Env{Name: "development", Domain: "yes.myshopify.com", Password: "abc123", ThemeID: "12345", Directory: Default.Directory, Timeout: Default.Timeout}},
+		{name: "development", initial: Env{Domain: "yes.myshopify.com", Password: "abc123"}, expected: Env{Name: "development", Domain: "yes.myshopify.com", Password: "abc123", Directory: Default.Directory, Timeout: Default.Timeout, MaxRetries: Default.MaxRetries, RetryDelay: Default.RetryDelay}},
+		{name: "development", initial: Env{Domain: "yes.myshopify.com", Password: "abc123", Directory: filepath.Join("..", "file")}, expected: Env{Name: "development", Domain: "yes.myshopify.com", Password: "abc123", Directory: filepath.Join("..", "file"), Timeout: Default.Timeout, MaxRetries: Default.MaxRetries, RetryDelay: Default.RetryDelay}},
+		{name: "development", initial: Env{Domain: "yes.myshopify.com", Password: "abc123"}, overrides: []Env{{ThemeID: "12345"}}, expected: Env{Name: "development", Domain: "yes.myshopify.com", Password: "abc123", ThemeID: "12345", Directory: Default.Directory, Timeout: Default.Timeout, MaxRetries: Default.MaxRetries, RetryDelay: Default.RetryDelay}},
 	}
 
 	for _, testcase := range testcases {
@@ -108,6 +113,87 @@ func TestConf_Get(t *testing.T) {
 	}
 }
 
+func TestConf_GetIgnores(t *testing.T) {
+	conf := New("config.yml")
+	conf.osEnv = Env{IgnoredFiles: []string{"*.png"}}
+	conf.Envs["development"] = &Env{Domain: "dev.myshopify.com", Password: "abc"}
+	conf.Envs["production"] = &Env{Domain: "prod.myshopify.com", Password: "abc", IgnoredFiles: []string{"config/settings_data.json"}}
+
+	env, err := conf.Get("development", Env{IgnoredFiles: []string{"*.jpg"}})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"*.png", "*.jpg"}, env.IgnoredFiles)
+	}
+
+	env, err = conf.Get("production", Env{IgnoredFiles: []string{"*.jpg"}})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"config/settings_data.json", "*.png", "*.jpg"}, env.IgnoredFiles)
+	}
+	assert.Equal(t, []string{"config/settings_data.json"}, conf.Envs["production"].IgnoredFiles)
+}
+
+func TestConf_GetInterpolation(t *testing.T) {
+	conf := New("")
+	conf.Envs["development"] = &Env{Password: "${THEMEKIT_TEST_PASSWORD}", Domain: "${THEMEKIT_TEST_STORE}.myshopify.com", ThemeID: "${THEMEKIT_TEST_THEME}"}
+
+	overWriteEnvVar("THEMEKIT_TEST_PASSWORD", "secret", func() {
+		overWriteEnvVar("THEMEKIT_TEST_STORE", "nope", func() {
+			overWriteEnvVar("THEMEKIT_TEST_THEME", "123", func() {
+				env, err := conf.Get("development")
+				if assert.Nil(t, err) {
+					assert.Equal(t, "secret", env.Password)
+					assert.Equal(t, "nope.myshopify.com", env.Domain)
+					assert.Equal(t, "123", env.ThemeID)
+				}
+				assert.Equal(t, "${THEMEKIT_TEST_PASSWORD}", conf.Envs["development"].Password)
+			})
+
+			os.Unsetenv("THEMEKIT_TEST_THEME")
+			_, err := conf.Get("development")
+			assert.EqualError(t, err, "invalid environment [development]: could not expand theme_id: environment variable THEMEKIT_TEST_THEME is not set")
+		})
+	})
+}
+
+func TestConf_Expand(t *testing.T) {
+	conf := New("")
+	conf.Envs = map[string]*Env{
+		"staging":    {Domain: "staging.myshopify.com"},
+		"preview":    {Domain: "preview.myshopify.com"},
+		"production": {Domain: "production.myshopify.com"},
+		"undefined":  nil,
+		"both":       {Group: []string{"staging", "preview"}},
+		"all":        {Group: []string{"both", "production", "staging"}},
+		"broken":     {Group: []string{"staging", "nope"}},
+		"loop":       {Group: []string{"staging", "loop2"}},
+		"loop2":      {Group: []string{"loop"}},
+		"self":       {Group: []string{"self"}},
+	}
+
+	testcases := []struct {
+		name, err string
+		expected  []string
+	}{
+		{name: "staging", expected: []string{"staging"}},
+		{name: "undefined", expected: []string{"undefined"}},
+		{name: "both", expected: []string{"staging", "preview"}},
+		{name: "all", expected: []string{"staging", "preview", "production"}},
+		{name: "nope", err: ErrEnvDoesNotExist.Error()},
+		{name: "broken", err: "environment group broken references nope which does not exist"},
+		{name: "loop", err: ErrEnvGroupCycle.Error() + ": loop -> loop2 -> loop"},
+		{name: "self", err: ErrEnvGroupCycle.Error() + ": self -> self"},
+	}
+
+	for _, testcase := range testcases {
+		names, err := conf.Expand(testcase.name)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expected, names)
+		} else if assert.NotNil(t, err, testcase.name) {
+			assert.Equal(t, testcase.err, err.Error())
+		}
+	}
+}
+
 func TestConf_Save(t *testing.T) {
 	conf := New("")
 	conf.Set("foobar", Env{
@@ -130,6 +216,85 @@ func TestConf_Save(t *testing.T) {
 	assert.Equal(t, err, ErrNoEnvironmentsDefined)
 }
 
+func TestConf_SaveJSON(t *testing.T) {
+	conf := New("config.json")
+	conf.Set("foobar", Env{
+		Password: "password",
+		Domain:   "nope.myshopify.com",
+	})
+
+	stringBuff := bytes.NewBufferString("")
+	err := conf.save(stringBuff)
+	assert.Nil(t, err)
+
+	expected := `{
+  "foobar": {
+    "password": "password",
+    "store": "nope.myshopify.com"
+  }
+}
+`
+	assert.Equal(t, expected, stringBuff.String())
+}
+
+func TestConf_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themekit-conf")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	initial := Env{
+		Password:     "password",
+		Domain:       "nope.myshopify.com",
+		ThemeID:      "123",
+		IgnoredFiles: []string{"*.png", "config/settings_data.json"},
+		Timeout:      time.Minute,
+		ReadOnly:     true,
+		Group:        []string{"a", "b"},
+	}
+
+	loaded := map[string]*Env{}
+	for _, name := range []string{"config.yml", "config.json"} {
+		path := filepath.Join(dir, name)
+		conf := New(path)
+		_, err := conf.Set("development", initial)
+		assert.Nil(t, err)
+		assert.Nil(t, conf.Save())
+
+		conf, err = Load(path)
+		if assert.Nil(t, err) {
+			loaded[name], err = conf.Get("development")
+			assert.Nil(t, err)
+			assert.Nil(t, conf.Save())
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, name == "config.json", detectFormat("", contents) == formatJSON)
+	}
+
+	assert.Equal(t, loaded["config.yml"], loaded["config.json"])
+	assert.Equal(t, "nope.myshopify.com", loaded["config.json"].Domain)
+	assert.Equal(t, time.Minute, loaded["config.json"].Timeout)
+}
+
+func TestDetectFormat(t *testing.T) {
+	testcases := []struct {
+		ext, contents, format string
+	}{
+		{ext: "yml", contents: "{}", format: formatYAML},
+		{ext: "yaml", contents: "", format: formatYAML},
+		{ext: "json", contents: "development:", format: formatJSON},
+		{ext: "", contents: "  \n{\"development\": {}}", format: formatJSON},
+		{ext: "", contents: "development:\n  store: shop", format: formatYAML},
+	}
+
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.format, detectFormat(testcase.ext, []byte(testcase.contents)))
+	}
+}
+
 func overWriteEnvVar(name, value string, fn func()) {
 	originalValue := os.Getenv(name)
 	os.Setenv(name, value)
@@ -2,8 +2,10 @@ package env
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -13,23 +15,62 @@ import (
 
 // Env is the structure of a configuration for an environment.
 type Env struct {
-	Name         string        `yaml:"-" json:"-" env:"-"`
-	Password     string        `yaml:"password,omitempty" json:"password,omitempty" env:"THEMEKIT_PASSWORD"`
-	ThemeID      string        `yaml:"theme_id,omitempty" json:"theme_id,omitempty" env:"THEMEKIT_THEME_ID"`
-	Domain       string        `yaml:"store" json:"store" env:"THEMEKIT_STORE"`
-	Directory    string        `yaml:"directory,omitempty" json:"directory,omitempty" env:"THEMEKIT_DIRECTORY"`
-	IgnoredFiles []string      `yaml:"ignore_files,omitempty" json:"ignore_files,omitempty" env:"THEMEKIT_IGNORE_FILES" envSeparator:":"`
-	Proxy        string        `yaml:"proxy,omitempty" json:"proxy,omitempty" env:"THEMEKIT_PROXY"`
-	Ignores      []string      `yaml:"ignores,omitempty" json:"ignores,omitempty" env:"THEMEKIT_IGNORES" envSeparator:":"`
-	Timeout      time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty" env:"THEMEKIT_TIMEOUT"`
-	ReadOnly     bool          `yaml:"readonly,omitempty" json:"readonly,omitempty" env:"-"`
-	Notify       string        `yaml:"notify,omitempty" json:"notify,omitempty" env:"THEMEKIT_NOTIFY"`
+	Name               string        `yaml:"-" json:"-" env:"-"`
+	Password           string        `yaml:"password,omitempty" json:"password,omitempty" env:"THEMEKIT_PASSWORD"`
+	APIKey             string        `yaml:"api_key,omitempty" json:"api_key,omitempty" env:"THEMEKIT_API_KEY"`
+	AuthMode           string        `yaml:"auth_mode,omitempty" json:"auth_mode,omitempty" env:"THEMEKIT_AUTH_MODE"`
+	ThemeID            string        `yaml:"theme_id,omitempty" json:"theme_id,omitempty" env:"THEMEKIT_THEME_ID"`
+	Domain             string        `yaml:"store" json:"store" env:"THEMEKIT_STORE"`
+	Directory          string        `yaml:"directory,omitempty" json:"directory,omitempty" env:"THEMEKIT_DIRECTORY"`
+	IgnoredFiles       []string      `yaml:"ignore_files,omitempty" json:"ignore_files,omitempty" env:"THEMEKIT_IGNORE_FILES" envSeparator:":"`
+	Proxy              string        `yaml:"proxy,omitempty" json:"proxy,omitempty" env:"THEMEKIT_PROXY"`
+	Ignores            []string      `yaml:"ignores,omitempty" json:"ignores,omitempty" env:"THEMEKIT_IGNORES" envSeparator:":"`
+	Timeout            time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty" env:"THEMEKIT_TIMEOUT"`
+	ConnectTimeout     time.Duration `yaml:"connect_timeout,omitempty" json:"connect_timeout,omitempty" env:"THEMEKIT_CONNECT_TIMEOUT"`
+	ReadTimeout        time.Duration `yaml:"read_timeout,omitempty" json:"read_timeout,omitempty" env:"THEMEKIT_READ_TIMEOUT"`
+	WriteTimeout       time.Duration `yaml:"write_timeout,omitempty" json:"write_timeout,omitempty" env:"THEMEKIT_WRITE_TIMEOUT"`
+	MaxIdleConns       int           `yaml:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty" env:"THEMEKIT_MAX_IDLE_CONNS"`
+	IdleConnTimeout    time.Duration `yaml:"idle_conn_timeout,omitempty" json:"idle_conn_timeout,omitempty" env:"THEMEKIT_IDLE_CONN_TIMEOUT"`
+	DisableHTTP2       bool          `yaml:"disable_http2,omitempty" json:"disable_http2,omitempty" env:"THEMEKIT_DISABLE_HTTP2"`
+	CAFile             string        `yaml:"ca_file,omitempty" json:"ca_file,omitempty" env:"THEMEKIT_CA_FILE"`
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty" env:"THEMEKIT_INSECURE_SKIP_VERIFY"`
+	ReadOnly           bool          `yaml:"readonly,omitempty" json:"readonly,omitempty" env:"-"`
+	APIVersion         string        `yaml:"api_version,omitempty" json:"api_version,omitempty" env:"THEMEKIT_API_VERSION"`
+	Group              []string      `yaml:"group,omitempty" json:"group,omitempty" env:"-"`
+	DryRun             bool          `yaml:"dry_run,omitempty" json:"dry_run,omitempty" env:"THEMEKIT_DRY_RUN"`
+	RequireUnpublished bool          `yaml:"require_unpublished,omitempty" json:"require_unpublished,omitempty" env:"THEMEKIT_REQUIRE_UNPUBLISHED"`
+	Notify             string        `yaml:"notify,omitempty" json:"notify,omitempty" env:"THEMEKIT_NOTIFY"`
+	MaxRetries         int           `yaml:"max_retries,omitempty" json:"max_retries,omitempty" env:"THEMEKIT_MAX_RETRIES"`
+	RetryDelay         time.Duration `yaml:"retry_delay,omitempty" json:"retry_delay,omitempty" env:"THEMEKIT_RETRY_DELAY"`
+	BatchSize          int           `yaml:"batch_size,omitempty" json:"batch_size,omitempty" env:"THEMEKIT_BATCH_SIZE"`
+	Concurrency        int           `yaml:"concurrency,omitempty" json:"concurrency,omitempty" env:"THEMEKIT_CONCURRENCY"`
+	MaxResponseBytes   int           `yaml:"max_response_bytes,omitempty" json:"max_response_bytes,omitempty" env:"THEMEKIT_MAX_RESPONSE_BYTES"`
+	Includes           []string      `yaml:"includes,omitempty" json:"includes,omitempty" env:"THEMEKIT_INCLUDES" envSeparator:":"`
+	ListGenerated      bool          `yaml:"list_generated,omitempty" json:"list_generated,omitempty" env:"THEMEKIT_LIST_GENERATED"`
+	CaseInsensitive    string        `yaml:"case_insensitive,omitempty" json:"case_insensitive,omitempty" env:"THEMEKIT_CASE_INSENSITIVE"`
+	UserAgentSuffix    string        `yaml:"user_agent_suffix,omitempty" json:"user_agent_suffix,omitempty" env:"THEMEKIT_USER_AGENT_SUFFIX"`
+	SettingsData       string        `yaml:"settings_data,omitempty" json:"settings_data,omitempty" env:"THEMEKIT_SETTINGS_DATA"`
+	ValidateLiquid     bool          `yaml:"validate_liquid,omitempty" json:"validate_liquid,omitempty" env:"THEMEKIT_VALIDATE_LIQUID"`
+	RetryKeys          []string      `yaml:"retry_keys,omitempty" json:"retry_keys,omitempty" env:"THEMEKIT_RETRY_KEYS" envSeparator:":"`
+	CacheDir           string        `yaml:"cache_dir,omitempty" json:"cache_dir,omitempty" env:"THEMEKIT_CACHE_DIR"`
 }
 
+// NoRetries is the max_retries that turns off retrying failed requests. A zero is
+// not used for this because it is replaced by the default.
+const NoRetries = -1
+
+// IgnoreFile is the name of the file in the project directory that holds
+// gitignore-style ignore rules.
+const IgnoreFile = ".themekitignore"
+
+var apiVersionRegexp = regexp.MustCompile(`^(\d{4}-\d{2}|unstable)$`)
+
 //Default is the default values for a environment
 var Default = Env{
-	Name:    "development",
-	Timeout: 30 * time.Second,
+	Name:       "development",
+	Timeout:    30 * time.Second,
+	MaxRetries: 3,
+	RetryDelay: 500 * time.Millisecond,
 }
 
 func init() {
@@ -43,9 +84,32 @@ func newEnv(name string, initial Env, overrides ...Env) (*Env, error) {
 	}
 	mergo.Merge(newConfig, &initial)
 	mergo.Merge(newConfig, &Default)
+
+	// the ignores of the environment are kept and the ones from the os environment
+	// and flags are added to them instead of replacing them
+	newConfig.IgnoredFiles, newConfig.Ignores = initial.IgnoredFiles, initial.Ignores
+	for _, override := range overrides {
+		newConfig.IgnoredFiles = appendMissing(newConfig.IgnoredFiles, override.IgnoredFiles...)
+		newConfig.Ignores = appendMissing(newConfig.Ignores, override.Ignores...)
+	}
 	return newConfig, newConfig.validate()
 }
 
+// appendMissing will return a copy of the list with the values that it does not
+// contain yet added to the end
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			found = found || existing == value
+		}
+		if !found {
+			list = append(list[:len(list):len(list)], value)
+		}
+	}
+	return list
+}
+
 func (env *Env) validate() error {
 	errors := []string{}
 
@@ -68,6 +132,32 @@ func (env *Env) validate() error {
 		errors = append(errors, "missing password")
 	}
 
+	if env.APIVersion != "" && !apiVersionRegexp.MatchString(env.APIVersion) {
+		errors = append(errors, "invalid api_version must be a release like '2024-01' or 'unstable'")
+	}
+
+	switch env.AuthMode {
+	case "", "token":
+	case "basic":
+		if len(env.APIKey) == 0 {
+			errors = append(errors, "missing api_key for basic auth_mode")
+		}
+	default:
+		errors = append(errors, "invalid auth_mode must be 'token' or 'basic'")
+	}
+
+	switch env.CaseInsensitive {
+	case "", "auto", "true", "false":
+	default:
+		errors = append(errors, "invalid case_insensitive must be 'auto', 'true' or 'false'")
+	}
+
+	switch env.SettingsData {
+	case "", "skip", "merge", "overwrite":
+	default:
+		errors = append(errors, "invalid settings_data must be 'skip', 'merge' or 'overwrite'")
+	}
+
 	var dirErrors []string
 	env.Directory, dirErrors = validateDirectory(env.Directory)
 	errors = append(errors, dirErrors...)
@@ -79,6 +169,85 @@ func (env *Env) validate() error {
 	return nil
 }
 
+// IgnoreRules will read the rules from the .themekitignore file in the project
+// directory, leaving out blank lines and comments. The order of the rules is kept
+// because later rules override earlier ones. There are no rules if the file does
+// not exist.
+func (env *Env) IgnoreRules() ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(env.Directory, IgnoreFile))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return []string{}, err
+	}
+
+	rules := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if len(line) > 0 && !strings.HasPrefix(line, "#") {
+			rules = append(rules, line)
+		}
+	}
+	return rules, nil
+}
+
+// interpolate will expand the ${VAR} references in the values that are likely to
+// hold secrets. A $$ is written out as a single $.
+func (env *Env) interpolate() error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"password", &env.Password},
+		{"api_key", &env.APIKey},
+		{"store", &env.Domain},
+		{"theme_id", &env.ThemeID},
+	}
+
+	for _, field := range fields {
+		expanded, err := expandVars(*field.value)
+		if err != nil {
+			return fmt.Errorf("could not expand %s: %v", field.name, err)
+		}
+		*field.value = expanded
+	}
+	return nil
+}
+
+func expandVars(value string) (string, error) {
+	var expanded strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			expanded.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			expanded.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end == -1 {
+				return "", fmt.Errorf("unterminated variable reference in %q", value)
+			}
+			name := value[i+2 : i+end]
+			if name == "" {
+				return "", fmt.Errorf("empty variable reference in %q", value)
+			}
+			envValue, set := os.LookupEnv(name)
+			if !set {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			expanded.WriteString(envValue)
+			i += end
+		default:
+			expanded.WriteByte(value[i])
+		}
+	}
+	return expanded.String(), nil
+}
+
 func validateDirectory(dir string) (finalDir string, errors []string) {
 	if fi, err := os.Lstat(filepath.Clean(dir)); err != nil {
 		errors = append(errors, fmt.Sprintf("invalid project directory %v", err))
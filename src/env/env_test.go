@@ -2,6 +2,7 @@ package env
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -24,6 +25,8 @@ func TestEnvNew(t *testing.T) {
 		Proxy:        ":3000",
 		Ignores:      []string{"four", "five", "six"},
 		Timeout:      40 * time.Second,
+		MaxRetries:   5,
+		RetryDelay:   time.Second,
 	}
 
 	env, _ = newEnv("", Env{}, osEnv)
@@ -36,6 +39,65 @@ func TestEnvNew(t *testing.T) {
 	assert.Equal(t, "flag", env.Password)
 }
 
+func TestEnvNew_Ignores(t *testing.T) {
+	initial := Env{IgnoredFiles: []string{"config/settings_data.json"}, Ignores: []string{"production.ignore"}}
+
+	env, _ := newEnv("production", initial)
+	assert.Equal(t, []string{"config/settings_data.json"}, env.IgnoredFiles)
+	assert.Equal(t, []string{"production.ignore"}, env.Ignores)
+
+	env, _ = newEnv("production", initial, Env{IgnoredFiles: []string{"*.png", "config/settings_data.json"}}, Env{IgnoredFiles: []string{"*.jpg"}, Ignores: []string{"shared.ignore"}})
+	assert.Equal(t, []string{"config/settings_data.json", "*.png", "*.jpg"}, env.IgnoredFiles)
+	assert.Equal(t, []string{"production.ignore", "shared.ignore"}, env.Ignores)
+	assert.Equal(t, []string{"config/settings_data.json"}, initial.IgnoredFiles)
+
+	env, _ = newEnv("development", Env{}, Env{IgnoredFiles: []string{"*.png"}})
+	assert.Equal(t, []string{"*.png"}, env.IgnoredFiles)
+	assert.Nil(t, env.Ignores)
+}
+
+func TestEnv_IgnoreRules(t *testing.T) {
+	e := &Env{Directory: filepath.Join("_testdata", "ignoredir")}
+	rules, err := e.IgnoreRules()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"assets/*.map", "!assets/keep.map", "node/", `\#hash.liquid`}, rules)
+
+	e = &Env{Directory: filepath.Join("_testdata", "projectdir")}
+	rules, err = e.IgnoreRules()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, rules)
+}
+
+func TestExpandVars(t *testing.T) {
+	testcases := []struct {
+		value, expected, err string
+	}{
+		{value: "plain", expected: "plain"},
+		{value: "${THEMEKIT_TEST_PASSWORD}", expected: "secret"},
+		{value: "pre-${THEMEKIT_TEST_PASSWORD}-${THEMEKIT_TEST_EMPTY}post", expected: "pre-secret-post"},
+		{value: "pa$$word", expected: "pa$word"},
+		{value: "$${THEMEKIT_TEST_PASSWORD}", expected: "${THEMEKIT_TEST_PASSWORD}"},
+		{value: "pa$sword$", expected: "pa$sword$"},
+		{value: "${THEMEKIT_TEST_MISSING}", err: "environment variable THEMEKIT_TEST_MISSING is not set"},
+		{value: "${THEMEKIT_TEST_PASSWORD", err: "unterminated variable reference"},
+		{value: "${}", err: "empty variable reference"},
+	}
+
+	os.Unsetenv("THEMEKIT_TEST_MISSING")
+	overWriteEnvVar("THEMEKIT_TEST_EMPTY", "", func() {
+		overWriteEnvVar("THEMEKIT_TEST_PASSWORD", "secret", func() {
+			for _, testcase := range testcases {
+				expanded, err := expandVars(testcase.value)
+				if testcase.err == "" && assert.Nil(t, err, testcase.value) {
+					assert.Equal(t, testcase.expected, expanded)
+				} else if testcase.err != "" && assert.NotNil(t, err, testcase.value) {
+					assert.Contains(t, err.Error(), testcase.err)
+				}
+			}
+		})
+	})
+}
+
 func TestEnv_Validate(t *testing.T) {
 	testCases := []struct {
 		env        Env
@@ -49,6 +111,18 @@ func TestEnv_Validate(t *testing.T) {
 		{env: Env{Password: "test", ThemeID: "123"}, err: "missing store domain"},
 		{env: Env{Password: "test", Domain: "test.myshopify.com"}},
 		{env: Env{Password: "file", ThemeID: "abc", Domain: "test.myshopify.com"}, err: "invalid theme_id"},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", APIVersion: "2024-01"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", APIVersion: "unstable"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", APIVersion: "v1"}, err: "invalid api_version"},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", CaseInsensitive: "auto"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", AuthMode: "token"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", AuthMode: "basic", APIKey: "key"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", AuthMode: "basic"}, err: "missing api_key"},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", AuthMode: "oauth"}, err: "invalid auth_mode"},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", CaseInsensitive: "false"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", CaseInsensitive: "yes"}, err: "invalid case_insensitive"},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", SettingsData: "merge"}},
+		{env: Env{Password: "file", Domain: "test.myshopify.com", SettingsData: "always"}, err: "invalid settings_data"},
 		{notwindows: true, env: Env{Password: "abc123", Domain: "test.myshopify.com", Directory: filepath.Join("_testdata", "symlink_projectdir")}},
 		{notwindows: true, env: Env{Password: "abc123", Domain: "test.myshopify.com", Directory: filepath.Join("_testdata", "bad_symlink")}, err: "invalid project symlink"},
 		{notwindows: true, env: Env{Password: "abc123", Domain: "test.myshopify.com", Directory: filepath.Join("_testdata", "symlink_file")}, err: "is not a directory"},
@@ -3,9 +3,12 @@ package file
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 
+	"github.com/Shopify/themekit/src/env"
 	"github.com/ryanuber/go-glob"
 )
 
@@ -23,15 +26,25 @@ var defaultRegexes = []*regexp.Regexp{
 	regexp.MustCompile(`desktop\.ini`),
 	regexp.MustCompile(`config.yml`),
 	regexp.MustCompile(`node_modules`),
+	regexp.MustCompile(`\.themekitignore`),
 }
 
 var defaultGlobs = []string{}
 
 // Filter matches filepaths to a list of patterns
 type Filter struct {
-	rootDir string
-	regexps []*regexp.Regexp
-	globs   []string
+	rootDir  string
+	regexps  []*regexp.Regexp
+	globs    []string
+	includes []string
+	rules    []ignoreRule
+	foldCase bool
+}
+
+// ignoreRule is a single line of a gitignore-style ignore file
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
 }
 
 // NewFilter will create a new file path filter
@@ -54,6 +67,93 @@ func NewFilter(rootDir string, patterns []string, files []string) (Filter, error
 	}, nil
 }
 
+// NewEnvFilter will create a file path filter from the ignore settings of the
+// environment, including the rules in the project's .themekitignore file.
+func NewEnvFilter(e *env.Env) (Filter, error) {
+	filter, err := NewFilter(e.Directory, e.IgnoredFiles, e.Ignores)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	rules, err := e.IgnoreRules()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	foldCase := e.CaseInsensitive == "true" || (e.CaseInsensitive == "auto" && IsCaseInsensitive(e.Directory))
+	return filter.WithIgnoreRules(rules).WithFoldCase(foldCase), nil
+}
+
+// WithFoldCase will return a copy of the filter that ignores case when matching
+// paths if fold is true, so that patterns work the same way as the paths they
+// refer to on a case-insensitive filesystem.
+func (f Filter) WithFoldCase(fold bool) Filter {
+	f.foldCase = fold
+	if !fold {
+		return f
+	}
+
+	regexps := make([]*regexp.Regexp, len(f.regexps))
+	for i, pattern := range f.regexps {
+		regexps[i] = foldRegexp(pattern)
+	}
+	f.regexps = regexps
+	f.rules = foldRules(f.rules)
+	return f
+}
+
+// IsCaseInsensitive will check if the filesystem that dir is on ignores case by
+// creating a temporary file and looking it up by a different case. If the check is
+// not possible it is assumed that macOS and windows are case-insensitive.
+func IsCaseInsensitive(dir string) bool {
+	tmp, err := ioutil.TempFile(dir, ".themekit-case-")
+	if err != nil {
+		return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(tmp.Name()), strings.ToUpper(filepath.Base(tmp.Name()))))
+	return err == nil
+}
+
+func foldRegexp(pattern *regexp.Regexp) *regexp.Regexp {
+	if strings.HasPrefix(pattern.String(), "(?i)") {
+		return pattern
+	}
+	return regexp.MustCompile("(?i)" + pattern.String())
+}
+
+func foldRules(rules []ignoreRule) []ignoreRule {
+	if rules == nil {
+		return nil
+	}
+	folded := make([]ignoreRule, len(rules))
+	for i, rule := range rules {
+		folded[i] = ignoreRule{pattern: foldRegexp(rule.pattern), negate: rule.negate}
+	}
+	return folded
+}
+
+// WithIgnoreRules will return a copy of the filter that also matches paths using
+// gitignore-style rules. Rules are applied in order so a later rule overrides an
+// earlier one, a rule starting with ! will re-include paths and a rule ending in /
+// only matches directories. Rules can only re-include paths that were excluded by
+// other rules, the ignore patterns from the config always apply.
+func (f Filter) WithIgnoreRules(lines []string) Filter {
+	var rules []ignoreRule
+	for _, line := range lines {
+		if rule, ok := parseIgnoreRule(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	f.rules = rules
+	if f.foldCase {
+		f.rules = foldRules(rules)
+	}
+	return f
+}
+
 // Match will return true if the file path has matched a pattern in this filter
 func (f Filter) Match(path string) bool {
 	if len(path) == 0 || !pathInProject(f.rootDir, path) {
@@ -67,7 +167,134 @@ func (f Filter) Match(path string) bool {
 	}
 
 	for _, pattern := range f.globs {
-		if glob.Glob(pattern, path) {
+		if f.glob(pattern, path) {
+			return true
+		}
+	}
+
+	return f.matchRules(path)
+}
+
+func (f Filter) glob(pattern, path string) bool {
+	if f.foldCase {
+		return glob.Glob(strings.ToLower(pattern), strings.ToLower(path))
+	}
+	return glob.Glob(pattern, path)
+}
+
+func (f Filter) matchRules(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), filepath.ToSlash(f.rootDir))
+	matched := false
+	for _, rule := range f.rules {
+		if rule.pattern.MatchString(path) {
+			matched = !rule.negate
+		}
+	}
+	return matched
+}
+
+// parseIgnoreRule will convert a gitignore-style line into a regexp that matches
+// a file path relative to the project root.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	dirOnly := strings.HasSuffix(line, "/")
+	line = strings.TrimSuffix(line, "/")
+	// a pattern with a slash in it is relative to the project root, otherwise it
+	// can match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	expr := "(^|/)"
+	if anchored {
+		expr = "^"
+	}
+	expr += ignoreRuleToRegexp(line)
+	if dirOnly {
+		// only files are matched so a directory matches if it is a parent
+		expr += "/"
+	} else {
+		expr += "(/|$)"
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+func ignoreRuleToRegexp(pattern string) string {
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(pattern[i:], ']'); end > 0 {
+				class := pattern[i+1 : i+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				expr.WriteString("[" + class + "]")
+				i += end
+			} else {
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
+
+// WithIncludes will return a copy of the filter that only includes file paths matching
+// one of the glob patterns. Ignored paths are still matched even if they are included.
+func (f Filter) WithIncludes(patterns []string) Filter {
+	f.includes = []string{}
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			f.includes = append(f.includes, toGlob(pattern))
+		}
+	}
+	return f
+}
+
+// Included will return true if the file path matches one of the include patterns
+// or if there are no include patterns at all.
+func (f Filter) Included(path string) bool {
+	if len(f.includes) == 0 {
+		return true
+	}
+
+	for _, pattern := range f.includes {
+		if f.glob(pattern, path) {
 			return true
 		}
 	}
@@ -114,19 +341,23 @@ func patternsToRegexpsAndGlobs(patterns []string) ([]*regexp.Regexp, []string) {
 			continue
 		}
 
-		// if specifying a directory match everything below it
-		if strings.HasSuffix(pattern, "/") {
-			pattern += "*"
-		}
+		globs = append(globs, toGlob(pattern))
+	}
 
-		// The pattern will be scoped to root directory so it should match anything
-		// within that space
-		if !strings.HasPrefix(pattern, "*") {
-			pattern = "*" + pattern
-		}
+	return regexps, globs
+}
 
-		globs = append(globs, pattern)
+func toGlob(pattern string) string {
+	// if specifying a directory match everything below it
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "*"
 	}
 
-	return regexps, globs
+	// The pattern will be scoped to root directory so it should match anything
+	// within that space
+	if !strings.HasPrefix(pattern, "*") {
+		pattern = "*" + pattern
+	}
+
+	return pattern
 }
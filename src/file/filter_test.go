@@ -1,9 +1,15 @@
 package file
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/Shopify/themekit/src/env"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -57,6 +63,135 @@ func TestFilter_Match(t *testing.T) {
 	}
 }
 
+func TestFilter_Included(t *testing.T) {
+	testcases := []struct {
+		includes []string
+		input    string
+		included bool
+	}{
+		{input: "templates/index.json", included: true},
+		{includes: []string{"templates/*.json"}, input: "templates/index.json", included: true},
+		{includes: []string{"templates/*.json"}, input: "templates/index.liquid", included: false},
+		{includes: []string{"templates/*.json", "sections/"}, input: "sections/header.liquid", included: true},
+		{includes: []string{"templates/*.json"}, input: "/tmp/templates/index.json", included: true},
+		{includes: []string{" ", ""}, input: "assets/app.js", included: true},
+	}
+
+	for _, testcase := range testcases {
+		filter := Filter{rootDir: "/tmp"}.WithIncludes(testcase.includes)
+		assert.Equal(t, testcase.included, filter.Included(testcase.input), testcase.input)
+	}
+}
+
+func TestFilter_IgnoreRules(t *testing.T) {
+	testcases := []struct {
+		rules   []string
+		input   string
+		matches bool
+	}{
+		{rules: []string{"*.map"}, input: "assets/app.js.map", matches: true},
+		{rules: []string{"*.map"}, input: "/tmp/assets/app.js.map", matches: true},
+		{rules: []string{"*.map"}, input: "assets/app.js", matches: false},
+		{rules: []string{"*.map", "!app.js.map"}, input: "assets/app.js.map", matches: false},
+		{rules: []string{"*.map", "!app.js.map"}, input: "assets/vendor.js.map", matches: true},
+		{rules: []string{"!app.js.map", "*.map"}, input: "assets/app.js.map", matches: true},
+		{rules: []string{"drafts/"}, input: "templates/drafts/index.liquid", matches: true},
+		{rules: []string{"drafts/"}, input: "templates/drafts", matches: false},
+		{rules: []string{"drafts"}, input: "templates/drafts", matches: true},
+		{rules: []string{"/snippets/old/"}, input: "snippets/old/a.liquid", matches: true},
+		{rules: []string{"snippets/old/"}, input: "sections/snippets/old/a.liquid", matches: false},
+		{rules: []string{"assets/**/*.scss"}, input: "assets/css/base/app.scss", matches: true},
+		{rules: []string{"assets/**/*.scss"}, input: "assets/app.scss", matches: true},
+		{rules: []string{"assets/*.scss"}, input: "assets/css/app.scss", matches: false},
+		{rules: []string{"file?.liquid"}, input: "snippets/file1.liquid", matches: true},
+		{rules: []string{"file[!0-9].liquid"}, input: "snippets/file1.liquid", matches: false},
+		{rules: []string{`\#hash.liquid`}, input: "snippets/#hash.liquid", matches: true},
+		{rules: []string{`\!bang.liquid`}, input: "snippets/!bang.liquid", matches: true},
+		{rules: []string{"# comment", "", "!"}, input: "snippets/comment", matches: false},
+	}
+
+	for _, testcase := range testcases {
+		filter := Filter{rootDir: "/tmp/"}.WithIgnoreRules(testcase.rules)
+		assert.Equal(t, testcase.matches, filter.Match(testcase.input), fmt.Sprintf("%v %s", testcase.rules, testcase.input))
+	}
+
+	// config patterns can not be re-included by a rule
+	filter, _ := NewFilter("/tmp", []string{"*.map"}, []string{})
+	assert.True(t, filter.WithIgnoreRules([]string{"!app.js.map"}).Match("assets/app.js.map"))
+}
+
+func TestFilter_FoldCase(t *testing.T) {
+	testcases := []struct {
+		patterns, rules, includes []string
+		input                     string
+		sensitive, insensitive    bool
+	}{
+		{patterns: []string{"templates/foo.liquid"}, input: "templates/Foo.liquid", sensitive: false, insensitive: true},
+		{patterns: []string{"*.PNG"}, input: "assets/logo.png", sensitive: false, insensitive: true},
+		{patterns: []string{`/\.gif$/`}, input: "assets/logo.GIF", sensitive: false, insensitive: true},
+		{rules: []string{"Drafts/"}, input: "templates/drafts/index.liquid", sensitive: false, insensitive: true},
+		{rules: []string{"*.map", "!App.js.map"}, input: "assets/app.js.map", sensitive: true, insensitive: false},
+		{patterns: []string{"templates/foo.liquid"}, input: "templates/foo.liquid", sensitive: true, insensitive: true},
+		{patterns: []string{"templates/foo.liquid"}, input: "templates/bar.liquid", sensitive: false, insensitive: false},
+	}
+
+	for _, testcase := range testcases {
+		filter, err := NewFilter("/tmp", testcase.patterns, []string{})
+		assert.Nil(t, err)
+		filter = filter.WithIgnoreRules(testcase.rules)
+		assert.Equal(t, testcase.sensitive, filter.WithFoldCase(false).Match(testcase.input), testcase.input)
+		assert.Equal(t, testcase.insensitive, filter.WithFoldCase(true).Match(testcase.input), testcase.input)
+	}
+
+	// rules added after folding are folded as well
+	filter := Filter{rootDir: "/tmp/"}.WithFoldCase(true).WithIgnoreRules([]string{"FOO.liquid"})
+	assert.True(t, filter.Match("snippets/foo.liquid"))
+
+	filter = Filter{rootDir: "/tmp/"}.WithIncludes([]string{"Templates/*.json"})
+	assert.False(t, filter.Included("templates/index.json"))
+	assert.True(t, filter.WithFoldCase(true).Included("templates/index.json"))
+
+	// folding does not change the default patterns used by other filters
+	Filter{rootDir: "/tmp/", regexps: defaultRegexes}.WithFoldCase(true)
+	assert.False(t, defaultRegexes[0].MatchString(".GIT"))
+}
+
+func TestIsCaseInsensitive(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "themekit")
+	defer os.RemoveAll(dir)
+
+	_, err := os.Stat(strings.ToUpper(dir))
+	assert.Equal(t, err == nil, IsCaseInsensitive(dir))
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 0, len(files))
+}
+
+func TestNewEnvFilter(t *testing.T) {
+	filter, err := NewEnvFilter(&env.Env{Directory: filepath.Join("..", "env", "_testdata", "ignoredir")})
+	assert.Nil(t, err)
+	assert.True(t, filter.Match("assets/app.js.map"))
+	assert.False(t, filter.Match("assets/keep.map"))
+	assert.True(t, filter.Match("assets/node/index.js"))
+	assert.True(t, filter.Match(".themekitignore"))
+	assert.False(t, filter.Match("assets/app.js"))
+
+	_, err = NewEnvFilter(&env.Env{Directory: "/tmp", Ignores: []string{"does not exist"}})
+	assert.NotNil(t, err)
+
+	filter, err = NewEnvFilter(&env.Env{Directory: "/tmp", IgnoredFiles: []string{"templates/foo.liquid"}, CaseInsensitive: "true"})
+	assert.Nil(t, err)
+	assert.True(t, filter.Match("templates/FOO.liquid"))
+
+	filter, err = NewEnvFilter(&env.Env{Directory: "/tmp", IgnoredFiles: []string{"templates/foo.liquid"}, CaseInsensitive: "false"})
+	assert.Nil(t, err)
+	assert.False(t, filter.Match("templates/FOO.liquid"))
+
+	filter, err = NewEnvFilter(&env.Env{Directory: "/tmp", IgnoredFiles: []string{"templates/foo.liquid"}, CaseInsensitive: "auto"})
+	assert.Nil(t, err)
+	assert.Equal(t, IsCaseInsensitive("/tmp"), filter.Match("templates/FOO.liquid"))
+}
+
 func TestFilesToPatterns(t *testing.T) {
 	patterns, err := filesToPatterns([]string{"_testdata/ignores_file"})
 	assert.Nil(t, err)
@@ -0,0 +1,142 @@
+package file
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshot records the state of every watched file in the project keyed by path
+type snapshot map[string]fileState
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	sum     string
+}
+
+// Poll will start the watcher scanning the project directory for changes every
+// interval and sending events to the Events channel. It is used instead of Watch
+// where filesystem events are not available, like on some network drives and in
+// containers. Files are compared by the hash of their contents.
+func (w *Watcher) Poll(interval time.Duration) (chan Event, error) {
+	current, err := w.scan(snapshot{})
+	if err != nil {
+		return nil, err
+	}
+
+	w.events = make(chan Event)
+	w.done = make(chan struct{})
+	go w.poll(interval, current, w.done)
+	return w.events, nil
+}
+
+func (w *Watcher) poll(interval time.Duration, current snapshot, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(w.events)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			next, err := w.scan(current)
+			if err != nil {
+				// a file may be removed while scanning, it will be picked up next time
+				continue
+			}
+
+			changes := diffSnapshots(current, next)
+			for _, change := range changes {
+				select {
+				case w.events <- w.toEvent(change.Path, change.Op):
+				case <-done:
+					return
+				}
+			}
+			if len(changes) > 0 {
+				w.onIdle()
+			}
+			current = next
+		}
+	}
+}
+
+// scan will record the state of every file that is not ignored. The hash of a file
+// is only computed again if its size or modification time has changed since the
+// previous snapshot.
+func (w *Watcher) scan(previous snapshot) (snapshot, error) {
+	next := snapshot{}
+	record := func(path string, info os.FileInfo) error {
+		if prev, ok := previous[path]; ok && prev.size == info.Size() && prev.modTime.Equal(info.ModTime()) {
+			next[path] = prev
+			return nil
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		next[path] = fileState{modTime: info.ModTime(), size: info.Size(), sum: sum}
+		return nil
+	}
+
+	if w.configPath != "" {
+		if info, err := os.Stat(w.configPath); err == nil {
+			if err := record(w.configPath, info); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := filepath.Walk(w.directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if info.IsDir() {
+			if path != w.directory && w.filter.Match(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if path == w.configPath || w.filter.Match(path) {
+			return nil
+		}
+		return record(path, info)
+	})
+	return next, err
+}
+
+// diffSnapshots will return an event for every file that was added, changed or
+// removed between two snapshots, sorted by path.
+func diffSnapshots(before, after snapshot) []Event {
+	changes := []Event{}
+	for path, state := range after {
+		if prev, ok := before[path]; !ok || prev.sum != state.sum {
+			changes = append(changes, Event{Op: Update, Path: path})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, Event{Op: Remove, Path: path})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
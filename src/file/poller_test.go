@@ -0,0 +1,94 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/env"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := snapshot{
+		"assets/changed.js":   {sum: "abc"},
+		"assets/same.js":      {sum: "def"},
+		"assets/removed.js":   {sum: "ghi"},
+		"assets/touched.scss": {sum: "jkl", size: 1},
+	}
+	after := snapshot{
+		"assets/changed.js":   {sum: "xyz"},
+		"assets/same.js":      {sum: "def"},
+		"assets/added.js":     {sum: "mno"},
+		"assets/touched.scss": {sum: "jkl", size: 2},
+	}
+
+	assert.Equal(t, []Event{
+		{Op: Update, Path: "assets/added.js"},
+		{Op: Update, Path: "assets/changed.js"},
+		{Op: Remove, Path: "assets/removed.js"},
+	}, diffSnapshots(before, after))
+	assert.Equal(t, []Event{}, diffSnapshots(after, after))
+}
+
+func TestFileWatcher_Scan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themekit-scan")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"templates/customers/account.liquid", "config/settings.json", "node_modules/lib.js"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(name), 0644))
+	}
+
+	watcher, err := NewWatcher(&env.Env{Directory: dir, IgnoredFiles: []string{"config/settings.json"}}, "")
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, "templates", "customers", "account.liquid")
+	current, err := watcher.scan(snapshot{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(current))
+	assert.Equal(t, "4728102e01d126412f383f5ebfa31560", current[path].sum)
+
+	state := current[path]
+	state.sum = "kept"
+	next, err := watcher.scan(snapshot{path: state})
+	assert.Nil(t, err)
+	assert.Equal(t, "kept", next[path].sum)
+}
+
+func TestFileWatcher_Poll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themekit-poll")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "assets"), 0755))
+	assetPath := filepath.Join(dir, "assets", "app.js")
+
+	watcher, err := NewWatcher(&env.Env{Directory: dir}, "")
+	assert.Nil(t, err)
+	events, err := watcher.Poll(10 * time.Millisecond)
+	assert.Nil(t, err)
+
+	expectEvent := func(expected Event) {
+		select {
+		case event := <-events:
+			assert.Equal(t, expected, event)
+		case <-time.After(time.Second):
+			t.Errorf("did not receive %v", expected)
+		}
+	}
+
+	assert.Nil(t, ioutil.WriteFile(assetPath, []byte("one"), 0644))
+	expectEvent(Event{Op: Update, Path: "assets/app.js"})
+	assert.Nil(t, ioutil.WriteFile(assetPath, []byte("two!"), 0644))
+	expectEvent(Event{Op: Update, Path: "assets/app.js"})
+	assert.Nil(t, os.Remove(assetPath))
+	expectEvent(Event{Op: Remove, Path: "assets/app.js"})
+
+	watcher.Stop()
+	for range events {
+	}
+}
@@ -37,6 +37,7 @@ type Watcher struct {
 	directory       string
 	configPath      string
 	events          chan Event
+	done            chan struct{}
 	debounceTimeout time.Duration
 	idleTimeout     time.Duration
 }
@@ -44,7 +45,7 @@ type Watcher struct {
 // NewWatcher will create a new file change watching for a a given directory defined
 // in an environment
 func NewWatcher(e *env.Env, configPath string) (*Watcher, error) {
-	filter, err := NewFilter(e.Directory, e.IgnoredFiles, e.Ignores)
+	filter, err := NewEnvFilter(e)
 	if err != nil {
 		return nil, err
 	}
@@ -99,15 +100,11 @@ func (w *Watcher) watchFsEvents(events chan fsnotify.Event, debounce debouncer)
 	for {
 		select {
 		case event := <-complete:
-			projectPath := pathToProject(w.directory, event.Name)
-			if projectPath == "" {
-				projectPath = event.Name
-			}
-			e := Event{Op: Update, Path: projectPath}
+			op := Update
 			if event.Op&fsnotify.Remove == fsnotify.Remove || event.Op&fsnotify.Rename == fsnotify.Rename {
-				e.Op = Remove
+				op = Remove
 			}
-			w.events <- e
+			w.events <- w.toEvent(event.Name, op)
 			delete(fileEvents, event.Name)
 			if len(fileEvents) == 0 {
 				idleTimer.Reset(w.idleTimeout)
@@ -134,9 +131,23 @@ func (w *Watcher) watchFsEvents(events chan fsnotify.Event, debounce debouncer)
 	}
 }
 
+// toEvent will build an event with the path relative to the project, paths outside
+// of the project like the config file are kept as they are.
+func (w *Watcher) toEvent(path string, op Op) Event {
+	projectPath := pathToProject(w.directory, path)
+	if projectPath == "" {
+		projectPath = path
+	}
+	return Event{Op: op, Path: projectPath}
+}
+
 // Stop will stop the Watcher from watching it's directories and clean
 // up any go routines doing work.
 func (w *Watcher) Stop() {
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
 	if w.fsWatcher == nil {
 		return
 	}
@@ -2,16 +2,23 @@ package httpify
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Shopify/themekit/src/ratelimiter"
@@ -21,25 +28,168 @@ import (
 var (
 	errClientTimeout   = errors.New(`request timed out. if you are receive this error consistently, try increasing the timeout in your config`)
 	errConnectionIssue = errors.New("DNS problem while connecting to Shopify, this indicates a problem with your internet connection")
+
+	// customAppTokenPrefix is the prefix of the admin api access tokens of custom apps
+	customAppTokenPrefix = "shpat_"
+
+	// sleep is stubbed out in tests so that retries do not slow them down
+	sleep = time.After
+
+	// throttleThreshold is the bucket utilization after which requests are slowed
+	// down so that the bucket can drain before the limit is reached
+	throttleThreshold = 0.75
+
+	// defaultMaxIdleConnsPerHost is how many connections to shopify are kept open
+	// for reuse. Go only keeps 2 by default, which means that concurrent uploads
+	// keep opening new connections and doing new TLS handshakes.
+	defaultMaxIdleConnsPerHost = 16
+	// defaultIdleConnTimeout is how long an unused connection is kept open
+	defaultIdleConnTimeout = 90 * time.Second
+	// defaultMaxRateLimitRetries is how many times a rate limited request is sent
+	// again. Shopify says how long to wait so these retries are cheap and they do
+	// not count towards MaxRetries.
+	defaultMaxRateLimitRetries = 10
+)
+
+// RetryError is returned when a request has failed after being retried. It
+// records the amount of attempts that were made and the id shopify assigned to
+// the last request, if there was a response, for debugging.
+type RetryError struct {
+	Attempts  int
+	RequestID string
+	Err       error
+}
+
+func (e RetryError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%s (failed after %d attempts, request id: %s)", e.Err, e.Attempts, e.RequestID)
+	}
+	return fmt.Sprintf("%s (failed after %d attempts)", e.Err, e.Attempts)
+}
+
+// Unwrap will return the error of the last attempt
+func (e RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryEvent describes a request that had to be retried. An event is sent before
+// every retry and a final one once the request succeeded or was given up on.
+type RetryEvent struct {
+	Method string
+	Path   string
+	// Attempt is the attempt that failed, or the amount of attempts that were
+	// made if the event is final.
+	Attempt int
+	// Wait is how long until the next attempt is made.
+	Wait time.Duration
+	// Err is the reason the attempt failed. On the final event it is nil if the
+	// request eventually succeeded.
+	Err   error
+	Final bool
+}
+
+// RequestEvent describes a single attempt at a request so that the traffic to
+// shopify can be inspected while debugging or measured. The amount of times a
+// request was retried is one less than the attempt.
+type RequestEvent struct {
+	Method  string
+	Path    string
+	Attempt int
+	// Status is the status of the response, it is empty if there was no response.
+	Status string
+	// StatusCode is the code of the response, it is 0 if there was no response.
+	StatusCode int
+	RequestID  string
+	// CallLimit is the call limit header, like 32/40, if shopify sent one.
+	CallLimit string
+	// BytesSent is the size of the request body.
+	BytesSent int64
+	// BytesReceived is the size of the response body that shopify announced, it
+	// is -1 if the size is not known up front.
+	BytesReceived int64
+	// Duration is how long it took to receive the response headers.
+	Duration time.Duration
+	Err      error
+}
+
+const (
+	// AuthToken sends the password as an admin api access token, which works for
+	// custom apps and private apps.
+	AuthToken = "token"
+	// AuthBasic sends the api key and password of a private app with basic auth.
+	AuthBasic = "basic"
 )
 
 // Params allows for a better structured input into NewClient
 type Params struct {
-	Domain   string
-	Password string
-	Proxy    string
-	Timeout  time.Duration
-	APILimit time.Duration
+	Domain     string
+	Password   string
+	Proxy      string
+	Timeout    time.Duration
+	APILimit   time.Duration
+	MaxRetries int
+	RetryDelay time.Duration
+	// APIKey is the api key of a private app, which is only needed for basic auth.
+	APIKey string
+	// AuthMode is either AuthToken or AuthBasic. When it is empty basic auth is
+	// used if there is an api key and the password is not a custom app token.
+	AuthMode string
+	// ConnectTimeout limits how long establishing a connection may take.
+	ConnectTimeout time.Duration
+	// ReadTimeout limits how long to wait for the response headers after the
+	// request was sent.
+	ReadTimeout time.Duration
+	// WriteTimeout replaces Timeout for requests that send a body, like asset
+	// uploads, since they can take a lot longer than other requests.
+	WriteTimeout time.Duration
+	// UserAgentSuffix is appended to the user agent so that requests from a
+	// specific source, like a CI pipeline, can be identified.
+	UserAgentSuffix string
+	// Limiter replaces the limiter of the domain so that several clients can share
+	// one rate and concurrency budget, see ratelimiter.NewShared.
+	Limiter *ratelimiter.Limiter
+	// MaxIdleConnsPerHost is how many idle connections are kept for reuse, the
+	// default is 16.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept, the default is 90s.
+	IdleConnTimeout time.Duration
+	// DisableHTTP2 will only use HTTP/1.1, which can help with proxies that do not
+	// handle HTTP/2 well.
+	DisableHTTP2 bool
+	// CAFile is the path to a PEM bundle of certificates that are trusted as well
+	// as the system ones, like the one of a proxy that intercepts TLS.
+	CAFile string
+	// InsecureSkipVerify turns off checking the certificates of shopify and should
+	// only be used as a last resort.
+	InsecureSkipVerify bool
+	// MaxRateLimitRetries is how many times a request that was rate limited is
+	// retried, separately from MaxRetries. The default is 10.
+	MaxRateLimitRetries int
 }
 
 // HTTPClient encapsulates an authenticate http client to issue theme requests
 // to Shopify
 type HTTPClient struct {
-	domain   string
-	password string
-	baseURL  *url.URL
-	client   *http.Client
-	limit    *ratelimiter.Limiter
+	domain     string
+	password   string
+	apiKey     string
+	authMode   string
+	userAgent  string
+	baseURL    *url.URL
+	client     *http.Client
+	upload     *http.Client
+	limit      *ratelimiter.Limiter
+	maxRetries int
+	retryDelay time.Duration
+	apiLimit   time.Duration
+	onRetry    func(RetryEvent)
+	onRequest  func(RequestEvent)
+
+	maxRateLimitRetries int
+
+	mu           sync.Mutex
+	callsUsed    int
+	callCapacity int
 }
 
 // NewClient will create a new authenticated http client that will communicate
@@ -50,50 +200,250 @@ func NewClient(params Params) (*HTTPClient, error) {
 		return nil, err
 	}
 
-	adapter, err := generateHTTPAdapter(params.Timeout, params.Proxy)
+	adapter, err := generateHTTPAdapter(params)
 	if err != nil {
 		return nil, err
 	}
 
+	upload := adapter
+	if params.WriteTimeout > 0 {
+		upload = &http.Client{Timeout: params.WriteTimeout, Transport: adapter.Transport}
+	}
+
+	limit := params.Limiter
+	if limit == nil {
+		limit = ratelimiter.New(params.Domain, params.APILimit)
+	}
+
+	maxRateLimitRetries := params.MaxRateLimitRetries
+	if maxRateLimitRetries <= 0 {
+		maxRateLimitRetries = defaultMaxRateLimitRetries
+	}
+
 	return &HTTPClient{
-		domain:   params.Domain,
-		password: params.Password,
-		baseURL:  baseURL,
-		client:   adapter,
-		limit:    ratelimiter.New(params.Domain, params.APILimit),
+		domain:     params.Domain,
+		password:   params.Password,
+		apiKey:     params.APIKey,
+		authMode:   authMode(params),
+		userAgent:  userAgent(params.UserAgentSuffix),
+		baseURL:    baseURL,
+		client:     adapter,
+		upload:     upload,
+		limit:      limit,
+		maxRetries: params.MaxRetries,
+		retryDelay: params.RetryDelay,
+		apiLimit:   params.APILimit,
+
+		maxRateLimitRetries: maxRateLimitRetries,
 	}, nil
 }
 
+// Utilization will return how full the shop's api call bucket was according to the
+// last response, from 0 to 1. It is 0 if shopify has not reported the call limit yet.
+func (client *HTTPClient) Utilization() float64 {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.callCapacity == 0 {
+		return 0
+	}
+	return float64(client.callsUsed) / float64(client.callCapacity)
+}
+
+// SetRetryObserver will set a function that is called every time a request is
+// retried and once a retried request has finished. It should be set before the
+// client is used.
+func (client *HTTPClient) SetRetryObserver(observer func(RetryEvent)) {
+	client.onRetry = observer
+}
+
+func (client *HTTPClient) reportRetry(event RetryEvent) {
+	if client.onRetry != nil {
+		client.onRetry(event)
+	}
+}
+
+// SetRequestObserver will set a function that is called after every attempt at a
+// request with the metadata of the response. It should be set before the client
+// is used.
+func (client *HTTPClient) SetRequestObserver(observer func(RequestEvent)) {
+	client.onRequest = observer
+}
+
+// SetLimiter will replace the limiter of the client so that it shares its rate and
+// concurrency budget with other clients. It should be set before the client is used.
+func (client *HTTPClient) SetLimiter(limiter *ratelimiter.Limiter) {
+	client.limit = limiter
+}
+
 // Get will send a get request to the path provided
-func (client *HTTPClient) Get(path string) (*http.Response, error) {
-	return client.do("GET", path, nil)
+func (client *HTTPClient) Get(ctx context.Context, path string) (*http.Response, error) {
+	return client.do(ctx, "GET", path, nil)
 }
 
 // Post will send a Post request to the path provided and set the post body as the
 // object passed
-func (client *HTTPClient) Post(path string, body interface{}) (*http.Response, error) {
-	return client.do("POST", path, body)
+func (client *HTTPClient) Post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return client.do(ctx, "POST", path, body)
 }
 
 // Put will send a Put request to the path provided and set the post body as the
 // object passed
-func (client *HTTPClient) Put(path string, body interface{}) (*http.Response, error) {
-	return client.do("PUT", path, body)
+func (client *HTTPClient) Put(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return client.do(ctx, "PUT", path, body)
 }
 
 // Delete will send a delete request to the path provided
-func (client *HTTPClient) Delete(path string) (*http.Response, error) {
-	return client.do("DELETE", path, nil)
+func (client *HTTPClient) Delete(ctx context.Context, path string) (*http.Response, error) {
+	return client.do(ctx, "DELETE", path, nil)
 }
 
-// DoJSON will issue an authenticated json request to shopify.
-func (client *HTTPClient) do(method, path string, body interface{}) (*http.Response, error) {
-	var jsonData io.Reader
-	if body != nil {
-		data, err := json.Marshal(body)
-		if err != nil {
+// do will issue an authenticated json request to shopify. Requests that fail
+// because of connection problems are retried with an exponential backoff. GET
+// and DELETE requests are idempotent so they will also be retried if they time
+// out or shopify responds with a server error, other requests are only retried
+// if they never reached shopify. Rate limited requests are retried after the
+// wait shopify asks for, up to their own limit. If the context is cancelled no
+// more attempts will be made.
+func (client *HTTPClient) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var data []byte
+	if raw, ok := body.(json.RawMessage); ok {
+		// already encoded, marshalling it again would only copy it
+		data = raw
+	} else if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
 			return nil, err
 		}
+	}
+
+	var rateLimitWait time.Duration
+	rateLimited := 0
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := client.attempt(ctx, method, path, data)
+		client.reportRequest(method, path, attempt, len(data), time.Since(start), resp, err)
+		if resp != nil {
+			client.throttle(resp.Header.Get("X-Shopify-Shop-Api-Call-Limit"))
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctxErr
+		}
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			if rateLimited++; rateLimited > client.maxRateLimitRetries {
+				retryErr := RetryError{
+					Attempts:  attempt,
+					RequestID: resp.Header.Get("X-Request-Id"),
+					Err:       fmt.Errorf("rate limit exceeded, gave up after waiting %s", rateLimitWait),
+				}
+				client.reportRetry(RetryEvent{Method: method, Path: path, Attempt: attempt, Err: retryErr, Final: true})
+				return nil, retryErr
+			}
+			wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+			if !ok {
+				wait = client.backoff(rateLimited)
+			}
+			client.reportRetry(RetryEvent{Method: method, Path: path, Attempt: attempt, Wait: wait, Err: errors.New(resp.Status)})
+			rateLimitWait += wait
+			// pausing the limiter holds back every request to this domain, so
+			// the next attempt waits in limit.Wait()
+			client.limit.Pause(wait)
+			continue
+		}
+		if attempt-rateLimited > client.maxRetries || !shouldRetry(method, resp, err) {
+			if attempt > 1 {
+				event := RetryEvent{Method: method, Path: path, Attempt: attempt, Final: true}
+				if err != nil {
+					event.Err = RetryError{Attempts: attempt, Err: err}
+				} else if shouldRetry(method, resp, nil) {
+					event.Err = errors.New(resp.Status)
+				}
+				client.reportRetry(event)
+				if err != nil {
+					return nil, event.Err
+				}
+			}
+			return resp, err
+		}
+
+		wait := client.backoff(attempt - rateLimited)
+		if err != nil {
+			client.reportRetry(RetryEvent{Method: method, Path: path, Attempt: attempt, Wait: wait, Err: err})
+		} else {
+			resp.Body.Close()
+			client.reportRetry(RetryEvent{Method: method, Path: path, Attempt: attempt, Wait: wait, Err: errors.New(resp.Status)})
+		}
+		select {
+		case <-sleep(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (client *HTTPClient) reportRequest(method, path string, attempt, sent int, duration time.Duration, resp *http.Response, err error) {
+	if client.onRequest == nil {
+		return
+	}
+	event := RequestEvent{Method: method, Path: path, Attempt: attempt, BytesSent: int64(sent), BytesReceived: -1, Duration: duration, Err: err}
+	if resp != nil {
+		event.Status = resp.Status
+		event.StatusCode = resp.StatusCode
+		event.BytesReceived = resp.ContentLength
+		event.RequestID = resp.Header.Get("X-Request-Id")
+		event.CallLimit = resp.Header.Get("X-Shopify-Shop-Api-Call-Limit")
+	}
+	client.onRequest(event)
+}
+
+// throttle will record the call limit reported by shopify and slow down the
+// following requests if the bucket is getting full.
+func (client *HTTPClient) throttle(header string) {
+	used, capacity, ok := parseCallLimit(header)
+	if !ok {
+		return
+	}
+
+	client.mu.Lock()
+	client.callsUsed, client.callCapacity = used, capacity
+	client.mu.Unlock()
+
+	if delay := throttleDelay(used, capacity, client.apiLimit); delay > 0 {
+		client.limit.Pause(delay)
+	}
+}
+
+// parseCallLimit will parse a call limit header like 32/40 into the amount of calls
+// used and the size of the bucket.
+func parseCallLimit(header string) (used, capacity int, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "/")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	used, usedErr := strconv.Atoi(parts[0])
+	capacity, capErr := strconv.Atoi(parts[1])
+	if usedErr != nil || capErr != nil || capacity <= 0 || used < 0 {
+		return 0, 0, false
+	}
+	return used, capacity, true
+}
+
+// throttleDelay is the time needed for the bucket to drain back to the threshold
+// when it leaks one call every apiLimit.
+func throttleDelay(used, capacity int, apiLimit time.Duration) time.Duration {
+	threshold := int(float64(capacity) * throttleThreshold)
+	if used <= threshold {
+		return 0
+	}
+	return time.Duration(used-threshold) * apiLimit
+}
+
+func (client *HTTPClient) attempt(ctx context.Context, method, path string, data []byte) (*http.Response, error) {
+	var jsonData io.Reader
+	if data != nil {
 		jsonData = bytes.NewBuffer(data)
 	}
 
@@ -101,14 +451,28 @@ func (client *HTTPClient) do(method, path string, body interface{}) (*http.Respo
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
-	req.Header.Add("X-Shopify-Access-Token", client.password)
+	if client.authMode == AuthBasic {
+		req.SetBasicAuth(client.apiKey, client.password)
+	} else {
+		req.Header.Add("X-Shopify-Access-Token", client.password)
+	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
-	req.Header.Add("User-Agent", fmt.Sprintf("go/themekit (%s; %s; %s)", runtime.GOOS, runtime.GOARCH, release.ThemeKitVersion.String()))
+	req.Header.Add("User-Agent", client.userAgent)
 
+	adapter := client.client
+	if data != nil {
+		adapter = client.upload
+	}
+
+	if err := client.limit.Acquire(ctx); err != nil {
+		return nil, err
+	}
 	client.limit.Wait()
-	resp, err := client.client.Do(req)
+	resp, err := adapter.Do(req)
+	client.limit.Release()
 	if err, ok := err.(net.Error); ok && err.Timeout() {
 		return nil, errClientTimeout
 	} else if err != nil && strings.Contains(err.Error(), "no such host") {
@@ -117,14 +481,146 @@ func (client *HTTPClient) do(method, path string, body interface{}) (*http.Respo
 	return resp, err
 }
 
-func generateHTTPAdapter(timeout time.Duration, proxyURL string) (*http.Client, error) {
-	adapter := &http.Client{Timeout: timeout}
-	if transport, err := generateClientTransport(proxyURL); err != nil {
+// userAgent will identify themekit, its version and the platform it runs on,
+// followed by the suffix if one was configured.
+func userAgent(suffix string) string {
+	agent := fmt.Sprintf("themekit/%s (%s; %s)", release.Version, runtime.GOOS, runtime.GOARCH)
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		agent += " " + suffix
+	}
+	return agent
+}
+
+// authMode will decide how the client authenticates. Custom app tokens can be told
+// apart by their prefix, any other password is sent with basic auth if there is an
+// api key to send it with.
+func authMode(params Params) string {
+	if params.AuthMode != "" {
+		return params.AuthMode
+	} else if params.APIKey != "" && !strings.HasPrefix(params.Password, customAppTokenPrefix) {
+		return AuthBasic
+	}
+	return AuthToken
+}
+
+// backoff will return the time to wait before making the next attempt. The delay
+// doubles with every attempt and a random jitter is added so that many failing
+// requests do not all retry at the same moment.
+func (client *HTTPClient) backoff(attempt int) time.Duration {
+	delay := client.retryDelay << uint(attempt-1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)))
+}
+
+// parseRetryAfter will parse the value of a Retry-After header which can either
+// be a number of seconds or an http date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func shouldRetry(method string, resp *http.Response, err error) bool {
+	idempotent := method == "GET" || method == "DELETE"
+	if err != nil {
+		return err != errConnectionIssue && (idempotent || notSent(err))
+	}
+	return idempotent && resp.StatusCode >= 500
+}
+
+// notSent will check if a request failed before it reached shopify, so sending
+// it again cannot apply it twice.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func generateHTTPAdapter(params Params) (*http.Client, error) {
+	transport, err := generateClientTransport(params.Proxy)
+	if err != nil {
 		return nil, err
-	} else if transport != nil {
-		adapter.Transport = transport
+	} else if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	if params.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: params.ConnectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = params.ConnectTimeout
+	}
+	transport.ResponseHeaderTimeout = params.ReadTimeout
+
+	transport.MaxIdleConnsPerHost = params.MaxIdleConnsPerHost
+	if transport.MaxIdleConnsPerHost <= 0 {
+		transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = params.IdleConnTimeout
+	if transport.IdleConnTimeout <= 0 {
+		transport.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	if params.CAFile != "" || params.InsecureSkipVerify {
+		tlsConfig, err := generateTLSConfig(params.CAFile, params.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if params.DisableHTTP2 {
+		// a non-nil empty map is how the transport is told not to upgrade to HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else {
+		// a custom tls config, like the one used with proxies, would otherwise
+		// disable HTTP/2
+		transport.ForceAttemptHTTP2 = true
 	}
-	return adapter, nil
+
+	return &http.Client{Timeout: params.Timeout, Transport: transport}, nil
+}
+
+// generateTLSConfig will build a tls config that trusts the certificates in the
+// ca file on top of the system certificates. Since the certificates are configured
+// on purpose they are checked even when a proxy is used.
+func generateTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ca file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in ca file %s", caFile)
+	}
+	config.RootCAs = pool
+	return config, nil
 }
 
 func generateClientTransport(proxyURL string) (*http.Transport, error) {
@@ -137,6 +633,17 @@ func generateClientTransport(proxyURL string) (*http.Transport, error) {
 		return nil, fmt.Errorf("invalid proxy URI")
 	}
 
+	// credentials in the url are sent as Proxy-Authorization by the transport
+	switch parsedURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", parsedURL.Scheme)
+	}
+
+	if parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URI")
+	}
+
 	return &http.Transport{
 		Proxy:           http.ProxyURL(parsedURL),
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -1,6 +1,10 @@
 package ratelimiter
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 var domainLimitMap = make(map[string]*Limiter)
 
@@ -8,6 +12,11 @@ var domainLimitMap = make(map[string]*Limiter)
 type Limiter struct {
 	nextChan chan bool
 	apiLimit time.Duration
+	mu       sync.Mutex
+	resumeAt time.Time
+	// slots holds a value for every request in flight, it is nil if the amount
+	// of requests is not limited
+	slots chan struct{}
 }
 
 // New creates a new call rate limiter for a single domain
@@ -22,6 +31,23 @@ func New(domain string, apiLimit time.Duration) *Limiter {
 	return domainLimitMap[domain]
 }
 
+// NewShared creates a limiter that is not tied to a domain so that it can be shared
+// by clients of different domains, or given to clients that should share one
+// budget. Requests are limited to one every apiLimit and at most concurrency of
+// them can be in flight at the same time. A concurrency that is not positive does
+// not limit the amount of requests in flight.
+func NewShared(apiLimit time.Duration, concurrency int) *Limiter {
+	limiter := &Limiter{
+		nextChan: make(chan bool),
+		apiLimit: apiLimit,
+	}
+	if concurrency > 0 {
+		limiter.slots = make(chan struct{}, concurrency)
+	}
+	limiter.next()
+	return limiter
+}
+
 func (limiter *Limiter) next() {
 	go func(l *Limiter) {
 		ticker := time.NewTimer(l.apiLimit)
@@ -34,5 +60,43 @@ func (limiter *Limiter) next() {
 // Wait will block until enough time has passed and the limit will not be passed
 func (limiter *Limiter) Wait() {
 	<-limiter.nextChan
+	limiter.mu.Lock()
+	pause := time.Until(limiter.resumeAt)
+	limiter.mu.Unlock()
+	if pause > 0 {
+		time.Sleep(pause)
+	}
 	limiter.next()
 }
+
+// Pause will hold back all callers of Wait until the duration has passed. This
+// is used when the server has told us that the limit has been reached.
+func (limiter *Limiter) Pause(d time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if resumeAt := time.Now().Add(d); resumeAt.After(limiter.resumeAt) {
+		limiter.resumeAt = resumeAt
+	}
+}
+
+// Acquire will block until another request can be in flight. Every successful
+// call has to be followed by a call to Release once the request is done. An error
+// is returned if the context is done before a request could be made.
+func (limiter *Limiter) Acquire(ctx context.Context) error {
+	if limiter.slots == nil {
+		return nil
+	}
+	select {
+	case limiter.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release will let another request be in flight
+func (limiter *Limiter) Release() {
+	if limiter.slots != nil {
+		<-limiter.slots
+	}
+}
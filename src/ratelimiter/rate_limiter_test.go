@@ -1,6 +1,8 @@
 package ratelimiter
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +35,65 @@ func TestWait(t *testing.T) {
 	limiter.Wait()
 }
 
+func TestPause(t *testing.T) {
+	domainLimitMap = make(map[string]*Limiter)
+	limiter := New("domain.com", time.Nanosecond)
+	limiter.Pause(100 * time.Millisecond)
+	limiter.Pause(time.Millisecond)
+	start := time.Now()
+	limiter.Wait()
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	start = time.Now()
+	limiter.Wait()
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+}
+
+func TestNewShared(t *testing.T) {
+	domainLimitMap = make(map[string]*Limiter)
+	limiter := NewShared(time.Nanosecond, 2)
+	assert.NotEqual(t, limiter, New("domain.com", time.Nanosecond))
+	assert.Equal(t, 1, len(domainLimitMap))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	inFlight, maxInFlight := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, limiter.Acquire(context.Background()))
+			defer limiter.Release()
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, maxInFlight)
+
+	limiter.Acquire(context.Background())
+	limiter.Acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, limiter.Acquire(ctx))
+	limiter.Release()
+	assert.Nil(t, limiter.Acquire(context.Background()))
+
+	unlimited := NewShared(time.Nanosecond, 0)
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, unlimited.Acquire(context.Background()))
+	}
+	unlimited.Release()
+}
+
 func checkTime(domain string, dur time.Duration) (received, timeout bool) {
 	domainLimitMap = make(map[string]*Limiter)
 	limiter := New(domain, dur)
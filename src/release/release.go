@@ -29,8 +29,11 @@ var (
 		"windows-386":   "theme.exe",
 		"windows-amd64": "theme.exe",
 	}
+	// Version is the version of the build. Release builds set it with
+	// -ldflags "-X github.com/Shopify/themekit/src/release.Version=x.y.z"
+	Version = "0.8.1"
 	// ThemeKitVersion is the version build of the library
-	ThemeKitVersion, _ = version.NewVersion("0.8.1")
+	ThemeKitVersion, _ = version.NewVersion(Version)
 )
 
 const (
@@ -138,7 +138,7 @@ func TestInstallVersion(t *testing.T) {
 func TestApplyUpdate(t *testing.T) {
 	updateFile := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
 	sum := md5.Sum(updateFile)
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, string(updateFile)) }))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(updateFile) }))
 	p := platform{URL: ts.URL, Digest: hex.EncodeToString(sum[:])}
 	installtoDir := filepath.Join("_testdata", "installto")
 	installto := filepath.Join(installtoDir, "updateme")
@@ -176,7 +176,7 @@ func TestUpdate(t *testing.T) {
 	for _, testcase := range testcases {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if testcase.req {
-				fmt.Fprintf(w, `[{"version":"`+ThemeKitVersion.String()+`", "platforms": [{"name": "plat-name"}]}]`)
+				fmt.Fprint(w, `[{"version":"`+ThemeKitVersion.String()+`", "platforms": [{"name": "plat-name"}]}]`)
 			} else {
 				fmt.Fprintf(w, `[{"version":"0.4.7", "platforms": [{"name": "plat-name"}]}]`)
 			}
@@ -1,6 +1,7 @@
 // Code generated by mockery v1.0.0. DO NOT EDIT.
 package mocks
 
+import context "context"
 import http "net/http"
 import mock "github.com/stretchr/testify/mock"
 
@@ -9,13 +10,13 @@ type HttpAdapter struct {
 	mock.Mock
 }
 
-// Delete provides a mock function with given fields: _a0
-func (_m *HttpAdapter) Delete(_a0 string) (*http.Response, error) {
-	ret := _m.Called(_a0)
+// Delete provides a mock function with given fields: _a0, _a1
+func (_m *HttpAdapter) Delete(_a0 context.Context, _a1 string) (*http.Response, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 *http.Response
-	if rf, ok := ret.Get(0).(func(string) *http.Response); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *http.Response); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*http.Response)
@@ -23,8 +24,8 @@ func (_m *HttpAdapter) Delete(_a0 string) (*http.Response, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -32,13 +33,13 @@ func (_m *HttpAdapter) Delete(_a0 string) (*http.Response, error) {
 	return r0, r1
 }
 
-// Get provides a mock function with given fields: _a0
-func (_m *HttpAdapter) Get(_a0 string) (*http.Response, error) {
-	ret := _m.Called(_a0)
+// Get provides a mock function with given fields: _a0, _a1
+func (_m *HttpAdapter) Get(_a0 context.Context, _a1 string) (*http.Response, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 *http.Response
-	if rf, ok := ret.Get(0).(func(string) *http.Response); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *http.Response); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*http.Response)
@@ -46,8 +47,8 @@ func (_m *HttpAdapter) Get(_a0 string) (*http.Response, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -55,13 +56,13 @@ func (_m *HttpAdapter) Get(_a0 string) (*http.Response, error) {
 	return r0, r1
 }
 
-// Post provides a mock function with given fields: _a0, _a1
-func (_m *HttpAdapter) Post(_a0 string, _a1 interface{}) (*http.Response, error) {
-	ret := _m.Called(_a0, _a1)
+// Post provides a mock function with given fields: _a0, _a1, _a2
+func (_m *HttpAdapter) Post(_a0 context.Context, _a1 string, _a2 interface{}) (*http.Response, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *http.Response
-	if rf, ok := ret.Get(0).(func(string, interface{}) *http.Response); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) *http.Response); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*http.Response)
@@ -69,8 +70,8 @@ func (_m *HttpAdapter) Post(_a0 string, _a1 interface{}) (*http.Response, error)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, interface{}) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, string, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -78,13 +79,13 @@ func (_m *HttpAdapter) Post(_a0 string, _a1 interface{}) (*http.Response, error)
 	return r0, r1
 }
 
-// Put provides a mock function with given fields: _a0, _a1
-func (_m *HttpAdapter) Put(_a0 string, _a1 interface{}) (*http.Response, error) {
-	ret := _m.Called(_a0, _a1)
+// Put provides a mock function with given fields: _a0, _a1, _a2
+func (_m *HttpAdapter) Put(_a0 context.Context, _a1 string, _a2 interface{}) (*http.Response, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *http.Response
-	if rf, ok := ret.Get(0).(func(string, interface{}) *http.Response); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) *http.Response); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*http.Response)
@@ -92,8 +93,8 @@ func (_m *HttpAdapter) Put(_a0 string, _a1 interface{}) (*http.Response, error)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, interface{}) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, string, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1,16 +1,18 @@
 package shopify
 
 import (
-	"bytes"
+	"crypto/md5"
 	"encoding/base64"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/Shopify/themekit/src/env"
 	"github.com/Shopify/themekit/src/file"
@@ -24,13 +26,94 @@ type Asset struct {
 	ContentType string `json:"content_type,omitempty"`
 	ThemeID     int64  `json:"theme_id,omitempty"`
 	UpdatedAt   string `json:"updated_at,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
+	Size        *int64 `json:"size,omitempty"`
+	// PublicURL is the CDN url of the asset, it is only set by shopify for files
+	// under assets/
+	PublicURL string `json:"public_url,omitempty"`
+	// Src is a url that shopify downloads the contents of the asset from when it
+	// is updated, see UpdateAssetFromURL.
+	Src string `json:"src,omitempty"`
 }
 
 var (
 	// ErrAssetIsDir is the error returned if you try and load a directory with ReadAsset
 	ErrAssetIsDir = errors.New("requested asset is a directory")
+
+	binaryExtensions = map[string]bool{
+		".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true, ".webp": true,
+		".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+		".pdf": true, ".zip": true, ".mp3": true, ".mp4": true, ".webm": true,
+	}
 )
 
+// NewAsset will build an asset from its key and contents. Binary contents are stored
+// as a base64 encoded attachment and text contents as the value of the asset.
+func NewAsset(key string, data []byte) Asset {
+	if isBinary(key, data) {
+		return Asset{Key: key, Attachment: base64.StdEncoding.EncodeToString(data)}
+	}
+	return Asset{Key: key, Value: string(data)}
+}
+
+// Bytes will return the contents of the asset, decoding the attachment if the asset is
+// binary.
+func (asset Asset) Bytes() ([]byte, error) {
+	if asset.Value == "" && asset.Attachment != "" {
+		data, err := base64.StdEncoding.DecodeString(asset.Attachment)
+		if err != nil {
+			return nil, fmt.Errorf("Could not decode %s. error: %s", asset.Key, err)
+		}
+		return data, nil
+	}
+	return []byte(asset.Value), nil
+}
+
+// IsBinary will report if the asset holds binary data. The content type shopify
+// reported is used when it is known, otherwise the contents are sniffed.
+func (asset Asset) IsBinary() bool {
+	if asset.ContentType == "" {
+		data, err := asset.Bytes()
+		return err != nil || isBinary(asset.Key, data)
+	}
+	return !isTextContentType(asset.ContentType)
+}
+
+func isTextContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, text := range []string{"json", "javascript", "xml", "liquid"} {
+		if strings.Contains(mediaType, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContentType will guess the content type of an asset from its extension,
+// falling back to sniffing its contents.
+func detectContentType(asset Asset) string {
+	if contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(asset.Key))); contentType != "" {
+		return contentType
+	}
+	data, _ := asset.Bytes()
+	return http.DetectContentType(data)
+}
+
+// isBinary decides if the contents have to be sent as an attachment. Known binary
+// file extensions are always binary, otherwise the content is sniffed.
+func isBinary(key string, data []byte) bool {
+	if binaryExtensions[strings.ToLower(filepath.Ext(key))] {
+		return true
+	}
+	return !utf8.Valid(data) || !strings.Contains(http.DetectContentType(data), "text")
+}
+
 // ReadAsset will read a single asset from disk
 func ReadAsset(e *env.Env, filename string) (Asset, error) {
 	return readAsset(e.Directory, filename)
@@ -40,7 +123,7 @@ func ReadAsset(e *env.Env, filename string) (Asset, error) {
 // read directories recursively. If no paths are passed in then the whole project
 // directory will be read
 func FindAssets(e *env.Env, paths ...string) (assets []string, err error) {
-	filter, err := file.NewFilter(e.Directory, e.IgnoredFiles, e.Ignores)
+	filter, err := file.NewEnvFilter(e)
 	if err != nil {
 		return []string{}, err
 	}
@@ -96,23 +179,28 @@ func (asset Asset) Write(directory string) error {
 	return err
 }
 
+// ComputeChecksum will return the md5 checksum of the asset's content in the same
+// format that shopify reports checksums so that they can be compared.
+func (asset Asset) ComputeChecksum() (string, error) {
+	data, err := asset.Bytes()
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// contents will return the exact bytes shopify sent for the asset. Binary assets are
+// decoded from their attachment and text assets are never reformatted.
 func (asset Asset) contents() ([]byte, error) {
-	var data []byte
-	var err error
-	switch {
-	case len(asset.Value) > 0:
-		data = []byte(asset.Value)
-		if filepath.Ext(asset.Key) == ".json" {
-			var out bytes.Buffer
-			json.Indent(&out, data, "", "  ")
-			data = out.Bytes()
-		}
-	case len(asset.Attachment) > 0:
-		if data, err = base64.StdEncoding.DecodeString(asset.Attachment); err != nil {
+	if asset.Attachment != "" && (asset.IsBinary() || asset.Value == "") {
+		data, err := base64.StdEncoding.DecodeString(asset.Attachment)
+		if err != nil {
 			return data, fmt.Errorf("Could not decode %s. error: %s", asset.Key, err)
 		}
+		return data, nil
 	}
-	return data, nil
+	return []byte(asset.Value), nil
 }
 
 func assetsToFilenames(assets []Asset) []string {
@@ -173,11 +261,5 @@ func readAsset(root, filename string) (asset Asset, err error) {
 		return Asset{}, fmt.Errorf("readAsset: %s", err)
 	}
 
-	contentType := http.DetectContentType(buffer)
-	if strings.Contains(contentType, "text") {
-		asset.Value = string(buffer)
-	} else {
-		asset.Attachment = base64.StdEncoding.EncodeToString(buffer)
-	}
-	return asset, nil
+	return NewAsset(asset.Key, buffer), nil
 }
@@ -2,14 +2,18 @@ package shopify
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/shopify/_mocks"
 )
 
 func TestFindAssets(t *testing.T) {
@@ -75,7 +79,9 @@ func TestAsset_Contents(t *testing.T) {
 		{asset: Asset{Value: "this is content"}, length: 15},
 		{asset: Asset{Attachment: "this is bad content"}, err: "Could not decode"},
 		{asset: Asset{Attachment: base64.StdEncoding.EncodeToString([]byte("this is good content"))}, length: 20},
-		{asset: Asset{Key: "test.json", Value: "{\"test\":\"one\"}"}, length: 19},
+		{asset: Asset{Key: "test.json", Value: "{\"test\":\"one\"}"}, length: 14},
+		{asset: Asset{Key: "icon.svg", Value: "<svg/>", Attachment: base64.StdEncoding.EncodeToString([]byte("<svg></svg>")), ContentType: "image/svg+xml"}, length: 6},
+		{asset: Asset{Key: "logo.png", Value: "ignored", Attachment: base64.StdEncoding.EncodeToString([]byte("png")), ContentType: "image/png"}, length: 3},
 	}
 
 	for _, testcase := range testcases {
@@ -89,6 +95,154 @@ func TestAsset_Contents(t *testing.T) {
 	}
 }
 
+func TestAsset_ComputeChecksum(t *testing.T) {
+	testcases := []struct {
+		asset         Asset
+		checksum, err string
+	}{
+		{asset: Asset{Value: "this is content"}, checksum: "b7fcef7fe745f2a95560ff5f550e3b8f"},
+		{asset: Asset{Attachment: base64.StdEncoding.EncodeToString([]byte("this is content"))}, checksum: "b7fcef7fe745f2a95560ff5f550e3b8f"},
+		{asset: Asset{}, checksum: "d41d8cd98f00b204e9800998ecf8427e"},
+		{asset: Asset{Attachment: "this is bad content"}, err: "Could not decode"},
+	}
+
+	for _, testcase := range testcases {
+		checksum, err := testcase.asset.ComputeChecksum()
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.checksum, checksum)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+	}
+}
+
+func TestNewAsset(t *testing.T) {
+	png, _ := ioutil.ReadFile(filepath.Join("_testdata", "project", "assets", "image.png"))
+
+	testcases := []struct {
+		key    string
+		data   []byte
+		binary bool
+	}{
+		{key: "assets/app.js", data: []byte("console.log('hi')")},
+		{key: "templates/index.json", data: []byte(`{"sections":{}}`)},
+		{key: "assets/empty.css", data: []byte{}},
+		{key: "assets/image.png", data: png, binary: true},
+		{key: "assets/image.txt", data: png, binary: true},
+		{key: "assets/font.woff2", data: []byte("wOF2"), binary: true},
+		{key: "assets/LOGO.PNG", data: []byte("not really a png"), binary: true},
+		{key: "assets/data.bin", data: []byte{0xff, 0xfe, 0x00, 0x01}, binary: true},
+	}
+
+	for _, testcase := range testcases {
+		asset := NewAsset(testcase.key, testcase.data)
+		assert.Equal(t, testcase.key, asset.Key)
+		if testcase.binary {
+			assert.Equal(t, "", asset.Value, testcase.key)
+			assert.NotEqual(t, "", asset.Attachment, testcase.key)
+		} else {
+			assert.Equal(t, string(testcase.data), asset.Value, testcase.key)
+			assert.Equal(t, "", asset.Attachment, testcase.key)
+		}
+		data, err := asset.Bytes()
+		assert.Nil(t, err)
+		assert.Equal(t, len(testcase.data), len(data))
+	}
+
+	_, err := Asset{Key: "assets/image.png", Attachment: "this is bad content"}.Bytes()
+	assert.NotNil(t, err)
+}
+
+func TestAsset_BinaryRoundTrip(t *testing.T) {
+	png, err := ioutil.ReadFile(filepath.Join("_testdata", "project", "assets", "image.png"))
+	assert.Nil(t, err)
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+
+	var uploaded Asset
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything).Run(func(args mock.Arguments) {
+		uploaded = args.Get(2).(map[string]Asset)["asset"]
+	}).Return(jsonResponse(`{"asset":{"key":"assets/image.png"}}`, 200), nil)
+
+	asset, err := ReadAsset(&env.Env{Directory: filepath.Join("_testdata", "project")}, filepath.Join("assets", "image.png"))
+	assert.Nil(t, err)
+	assert.Nil(t, client.UpdateAsset(asset))
+	assert.Equal(t, "", uploaded.Value)
+
+	body, _ := json.Marshal(map[string]Asset{"asset": uploaded})
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fimage.png").Return(jsonResponse(string(body), 200), nil)
+
+	downloaded, err := client.GetAsset("assets/image.png")
+	assert.Nil(t, err)
+	data, err := downloaded.Bytes()
+	assert.Nil(t, err)
+	assert.Equal(t, png, data)
+}
+
+func TestAsset_IsBinary(t *testing.T) {
+	testcases := []struct {
+		asset  Asset
+		binary bool
+	}{
+		{asset: Asset{Key: "assets/app.js", ContentType: "application/javascript"}},
+		{asset: Asset{Key: "templates/index.json", ContentType: "application/json"}},
+		{asset: Asset{Key: "layout/theme.liquid", ContentType: "text/x-liquid; charset=utf-8"}},
+		{asset: Asset{Key: "assets/icon.svg", ContentType: "image/svg+xml"}},
+		{asset: Asset{Key: "assets/logo.png", ContentType: "image/png"}, binary: true},
+		{asset: Asset{Key: "assets/font.woff2", ContentType: "font/woff2"}, binary: true},
+		{asset: Asset{Key: "assets/app.css", Value: "body {}"}},
+		{asset: Asset{Key: "assets/data.bin", Attachment: base64.StdEncoding.EncodeToString([]byte{0xff, 0x00})}, binary: true},
+	}
+
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.binary, testcase.asset.IsBinary(), testcase.asset.Key)
+	}
+}
+
+func TestAsset_DownloadMixed(t *testing.T) {
+	png, err := ioutil.ReadFile(filepath.Join("_testdata", "project", "assets", "image.png"))
+	assert.Nil(t, err)
+	text := "{\n  \"current\": \"Default\"\r\n}"
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+
+	for key, asset := range map[string]Asset{
+		"assets/image.png":          {Key: "assets/image.png", Attachment: base64.StdEncoding.EncodeToString(png)},
+		"config/settings_data.json": {Key: "config/settings_data.json", Value: text, ContentType: "application/json"},
+	} {
+		body, _ := json.Marshal(map[string]Asset{"asset": asset})
+		m.On("Get", mock.Anything, client.assetPath(map[string]string{"asset[key]": key})).Return(jsonResponse(string(body), 200), nil)
+	}
+
+	testDir := filepath.Join("_testdata", "mixed")
+	os.Mkdir(testDir, 0755)
+	defer os.RemoveAll(testDir)
+
+	image, err := client.GetAsset("assets/image.png")
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", image.ContentType)
+	assert.True(t, image.IsBinary())
+	assert.Nil(t, image.Write(testDir))
+
+	settings, err := client.GetAsset("config/settings_data.json")
+	assert.Nil(t, err)
+	assert.False(t, settings.IsBinary())
+	assert.Nil(t, settings.Write(testDir))
+
+	written, err := ioutil.ReadFile(filepath.Join(testDir, "assets", "image.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, png, written)
+
+	written, err = ioutil.ReadFile(filepath.Join(testDir, "config", "settings_data.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, text, string(written))
+}
+
 func TestLoadAssetsFromDirectory(t *testing.T) {
 	root := filepath.Join("_testdata", "project")
 	ignoreNone := func(path string) bool { return strings.Contains(path, ".gitkeep") }
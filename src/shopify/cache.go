@@ -0,0 +1,92 @@
+package shopify
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// assetCache keeps the contents of downloaded assets on disk so that an asset whose
+// checksum has not changed since it was last downloaded does not have to be fetched
+// again. Every version is stored as dir/key/checksum and only the latest version of
+// an asset is kept. The directory only ever holds copies so it is safe to delete.
+type assetCache struct {
+	dir string
+}
+
+// newAssetCache will build a cache for the theme in the cache directory, or return
+// nil if the cache directory is not set.
+func newAssetCache(cacheDir, domain, themeID string) *assetCache {
+	if cacheDir == "" {
+		return nil
+	}
+	if themeID == "" {
+		themeID = "live"
+	}
+	return &assetCache{dir: filepath.Join(cacheDir, domain, themeID)}
+}
+
+// forTheme will return the cache of another theme of the same shop
+func (cache *assetCache) forTheme(themeID string) *assetCache {
+	if cache == nil {
+		return nil
+	}
+	return &assetCache{dir: filepath.Join(filepath.Dir(cache.dir), themeID)}
+}
+
+func (cache *assetCache) path(key, checksum string) string {
+	return filepath.Join(cache.dir, filepath.FromSlash(key), checksum)
+}
+
+// get will return the cached asset if it is cached with the checksum. Entries that
+// no longer match their checksum are treated as missing.
+func (cache *assetCache) get(key, checksum string) (Asset, bool) {
+	data, err := ioutil.ReadFile(cache.path(key, checksum))
+	if err != nil {
+		return Asset{}, false
+	}
+	asset := NewAsset(key, data)
+	if computed, err := asset.ComputeChecksum(); err != nil || computed != checksum {
+		return Asset{}, false
+	}
+	asset.Checksum = checksum
+	asset.ContentType = detectContentType(asset)
+	return asset, true
+}
+
+// put will store the asset under its checksum, replacing any other version of it.
+// Failing to cache an asset does not fail the download so errors are ignored.
+func (cache *assetCache) put(asset Asset) {
+	if asset.Checksum == "" {
+		return
+	}
+	data, err := asset.contents()
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(cache.path(asset.Key, asset.Checksum))
+	os.RemoveAll(dir)
+	if os.MkdirAll(dir, 0755) == nil {
+		ioutil.WriteFile(cache.path(asset.Key, asset.Checksum), data, 0644)
+	}
+}
+
+// GetCachedAsset will return the asset from the cache in cache_dir if it was
+// downloaded before with the same checksum, and otherwise fetch it like GetAsset and
+// cache it. The checksum is the one shopify reports for the asset, for example from
+// GetAssetChecksums, and an empty checksum or an unset cache_dir always fetches it.
+func (c Client) GetCachedAsset(key, checksum string) (Asset, error) {
+	return c.GetCachedAssetContext(context.Background(), key, checksum)
+}
+
+// GetCachedAssetContext is the same as GetCachedAsset but the request is bound to
+// the context so it can be cancelled.
+func (c Client) GetCachedAssetContext(ctx context.Context, key, checksum string) (Asset, error) {
+	if c.cache != nil && checksum != "" {
+		if asset, found := c.cache.get(key, checksum); found {
+			return asset, nil
+		}
+	}
+	return c.GetAssetContext(ctx, key)
+}
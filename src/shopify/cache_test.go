@@ -0,0 +1,92 @@
+package shopify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/shopify/_mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestThemeClient_GetCachedAsset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themekit-cache")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.js"
+	// md5 of "hello" and "world"
+	hello, world := "5d41402abc4b2a76b9719d911017c592", "7d793037a0760186574b0282f2f435e7"
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", Domain: "shop.myshopify.com", CacheDir: dir})
+	client.http = m
+	m.On("Get", mock.Anything, path).Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"hello","checksum":"`+hello+`"}}`, 200), nil).Once()
+
+	asset, err := client.GetCachedAsset("assets/app.js", hello)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", asset.Value)
+	assert.FileExists(t, filepath.Join(dir, "shop.myshopify.com", "123", "assets", "app.js", hello))
+
+	asset, err = client.GetCachedAsset("assets/app.js", hello)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", asset.Value)
+	assert.Equal(t, hello, asset.Checksum)
+	assert.Contains(t, asset.ContentType, "javascript")
+	m.AssertNumberOfCalls(t, "Get", 1)
+
+	m.On("Get", mock.Anything, path).Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"world","checksum":"`+world+`"}}`, 200), nil).Once()
+	asset, err = client.GetCachedAsset("assets/app.js", world)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", asset.Value)
+	m.AssertNumberOfCalls(t, "Get", 2)
+	_, err = os.Stat(filepath.Join(dir, "shop.myshopify.com", "123", "assets", "app.js", hello))
+	assert.True(t, os.IsNotExist(err))
+
+	ioutil.WriteFile(filepath.Join(dir, "shop.myshopify.com", "123", "assets", "app.js", world), []byte("corrupt"), 0644)
+	m.On("Get", mock.Anything, path).Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"world","checksum":"`+world+`"}}`, 200), nil).Once()
+	asset, err = client.GetCachedAsset("assets/app.js", world)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", asset.Value)
+	m.AssertNumberOfCalls(t, "Get", 3)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{ThemeID: "123", Domain: "shop.myshopify.com"})
+	client.http = m
+	m.On("Get", mock.Anything, path).Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"world","checksum":"`+world+`"}}`, 200), nil).Once()
+	_, err = client.GetCachedAsset("assets/app.js", world)
+	assert.Nil(t, err)
+	m.AssertNumberOfCalls(t, "Get", 1)
+}
+
+func TestThemeClient_forThemeCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themekit-cache")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// md5 of "hello" and "world"
+	hello, world := "5d41402abc4b2a76b9719d911017c592", "7d793037a0760186574b0282f2f435e7"
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", Domain: "shop.myshopify.com", CacheDir: dir})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.js").Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"hello","checksum":"`+hello+`"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=assets%2Fapp.js").Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"world","checksum":"`+world+`"}}`, 200), nil)
+
+	_, err = client.GetAsset("assets/app.js")
+	assert.Nil(t, err)
+	_, err = client.forTheme(456).GetAsset("assets/app.js")
+	assert.Nil(t, err)
+	assert.FileExists(t, filepath.Join(dir, "shop.myshopify.com", "123", "assets", "app.js", hello))
+	assert.FileExists(t, filepath.Join(dir, "shop.myshopify.com", "456", "assets", "app.js", world))
+
+	client, _ = NewClient(&env.Env{ThemeID: "123", Domain: "shop.myshopify.com"})
+	assert.Nil(t, client.forTheme(456).cache)
+}
@@ -0,0 +1,99 @@
+package shopify
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sort"
+)
+
+// AssetDiff is the difference between the local project and the remote theme. Every
+// list holds asset keys and is sorted.
+type AssetDiff struct {
+	// Added assets only exist locally
+	Added []string
+	// Changed assets exist on both sides but their contents differ
+	Changed []string
+	// Removed assets only exist remotely
+	Removed []string
+	// Unchanged assets have the same contents on both sides
+	Unchanged []string
+}
+
+// Diff will compare the local contents of the project with the remote assets by
+// comparing their md5 checksums. Remote assets without a checksum are hashed from
+// their contents. Assets that shopify generates from a .liquid file, like
+// assets/app.css for assets/app.css.liquid, are left out unless the generated key
+// also exists locally.
+func Diff(local map[string][]byte, remote []Asset) AssetDiff {
+	diff := AssetDiff{
+		Added:     []string{},
+		Changed:   []string{},
+		Removed:   []string{},
+		Unchanged: []string{},
+	}
+
+	remoteChecksums := map[string]string{}
+	for _, asset := range remote {
+		checksum := asset.Checksum
+		if checksum == "" {
+			// an undecodable asset gets no checksum so that it is always seen as changed
+			checksum, _ = asset.ComputeChecksum()
+		}
+		remoteChecksums[asset.Key] = checksum
+	}
+
+	for key, data := range local {
+		remoteChecksum, exists := remoteChecksums[key]
+		sum := md5.Sum(data)
+		if !exists {
+			diff.Added = append(diff.Added, key)
+		} else if remoteChecksum == hex.EncodeToString(sum[:]) {
+			diff.Unchanged = append(diff.Unchanged, key)
+		} else {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range remoteChecksums {
+		if _, exists := local[key]; exists {
+			continue
+		} else if _, generated := remoteChecksums[key+".liquid"]; generated {
+			continue
+		}
+		diff.Removed = append(diff.Removed, key)
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Unchanged)
+	return diff
+}
+
+// ThemeDiff is the difference between the assets of two remote themes, A and B.
+// Every list holds asset keys and is sorted.
+type ThemeDiff struct {
+	// OnlyInA assets only exist in theme A
+	OnlyInA []string
+	// OnlyInB assets only exist in theme B
+	OnlyInB []string
+	// Differing assets exist in both themes but their contents differ
+	Differing []string
+	// Identical assets have the same contents in both themes
+	Identical []string
+}
+
+// withoutGenerated will remove the assets that shopify generates from a .liquid
+// file that is also in the list, since they change along with their source.
+func withoutGenerated(assets []Asset) map[string]Asset {
+	byKey := map[string]Asset{}
+	for _, asset := range assets {
+		byKey[asset.Key] = asset
+	}
+	for key := range byKey {
+		if _, generated := byKey[key+".liquid"]; generated {
+			delete(byKey, key)
+		}
+	}
+	return byKey
+}
@@ -0,0 +1,78 @@
+package shopify
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	testcases := []struct {
+		name     string
+		local    map[string][]byte
+		remote   []Asset
+		expected AssetDiff
+	}{
+		{
+			name:     "empty",
+			expected: AssetDiff{Added: []string{}, Changed: []string{}, Removed: []string{}, Unchanged: []string{}},
+		},
+		{
+			name:     "added",
+			local:    map[string][]byte{"assets/b.js": []byte("b"), "assets/a.js": []byte("a")},
+			expected: AssetDiff{Added: []string{"assets/a.js", "assets/b.js"}, Changed: []string{}, Removed: []string{}, Unchanged: []string{}},
+		},
+		{
+			name:     "removed",
+			remote:   []Asset{{Key: "assets/a.js", Value: "a"}},
+			expected: AssetDiff{Added: []string{}, Changed: []string{}, Removed: []string{"assets/a.js"}, Unchanged: []string{}},
+		},
+		{
+			name:     "changed and unchanged by value",
+			local:    map[string][]byte{"assets/a.js": []byte("a"), "assets/b.js": []byte("new b")},
+			remote:   []Asset{{Key: "assets/a.js", Value: "a"}, {Key: "assets/b.js", Value: "b"}},
+			expected: AssetDiff{Added: []string{}, Changed: []string{"assets/b.js"}, Removed: []string{}, Unchanged: []string{"assets/a.js"}},
+		},
+		{
+			name:  "compared by checksum",
+			local: map[string][]byte{"assets/a.js": {}, "assets/b.js": []byte("b")},
+			remote: []Asset{
+				{Key: "assets/a.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"},
+				{Key: "assets/b.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"},
+			},
+			expected: AssetDiff{Added: []string{}, Changed: []string{"assets/b.js"}, Removed: []string{}, Unchanged: []string{"assets/a.js"}},
+		},
+		{
+			name:  "attachments",
+			local: map[string][]byte{"assets/a.png": {0x89, 0x50}, "assets/b.png": {0x89, 0x50}},
+			remote: []Asset{
+				{Key: "assets/a.png", Attachment: base64.StdEncoding.EncodeToString([]byte{0x89, 0x50})},
+				{Key: "assets/b.png", Attachment: "not base64"},
+			},
+			expected: AssetDiff{Added: []string{}, Changed: []string{"assets/b.png"}, Removed: []string{}, Unchanged: []string{"assets/a.png"}},
+		},
+		{
+			name:     "generated assets are not removed",
+			local:    map[string][]byte{"assets/app.css.liquid": []byte("{{ 'red' }}")},
+			remote:   []Asset{{Key: "assets/app.css.liquid", Value: "{{ 'red' }}"}, {Key: "assets/app.css", Value: "red"}},
+			expected: AssetDiff{Added: []string{}, Changed: []string{}, Removed: []string{}, Unchanged: []string{"assets/app.css.liquid"}},
+		},
+		{
+			name:     "generated asset replaced by a plain file",
+			local:    map[string][]byte{"assets/app.css": []byte("blue")},
+			remote:   []Asset{{Key: "assets/app.css.liquid", Value: "{{ 'red' }}"}, {Key: "assets/app.css", Value: "red"}},
+			expected: AssetDiff{Added: []string{}, Changed: []string{"assets/app.css"}, Removed: []string{"assets/app.css.liquid"}, Unchanged: []string{}},
+		},
+		{
+			name:     "liquid counterpart removed",
+			local:    map[string][]byte{},
+			remote:   []Asset{{Key: "templates/index.json.liquid", Value: "{}"}, {Key: "templates/index.json", Value: "{}"}},
+			expected: AssetDiff{Added: []string{}, Changed: []string{}, Removed: []string{"templates/index.json.liquid"}, Unchanged: []string{}},
+		},
+	}
+
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.expected, Diff(testcase.local, testcase.remote), testcase.name)
+	}
+}
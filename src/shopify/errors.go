@@ -0,0 +1,198 @@
+package shopify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// snippetLength is how much of a response body is included in a ParseError
+const snippetLength = 200
+
+// APIError is returned when shopify responds to a request with an error. If the
+// response matched a known failure, like a missing theme, Err will be set to one
+// of the package's error values so that it can be checked with errors.Is.
+type APIError struct {
+	StatusCode     int
+	ShopifyMessage string
+	RequestID      string
+	Err            error
+}
+
+// Error will format the error message and include the request id, if shopify
+// provided one, so that it can be included in support requests.
+func (e *APIError) Error() string {
+	message := e.ShopifyMessage
+	if e.Err != nil {
+		message = e.Err.Error()
+	}
+	if e.RequestID != "" {
+		return fmt.Sprintf("%s (request id: %s)", message, e.RequestID)
+	}
+	return message
+}
+
+// Unwrap will return the known error that this error represents, if any.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is the reason a response from shopify could not be parsed. It is set
+// as the Err of an APIError and it wraps ErrMalformedResponse so it can still be
+// checked with errors.Is.
+type ParseError struct {
+	// Type is the name of the type the response was parsed into.
+	Type string
+	// Field and Expected are only set if a value had the wrong type, Field is the
+	// path to the value and Expected is the type it should have had.
+	Field    string
+	Expected string
+	// Snippet is the part of the response body around where parsing failed.
+	Snippet string
+	Err     error
+}
+
+// Error will describe what could not be parsed and include the part of the body
+// that caused it.
+func (e *ParseError) Error() string {
+	reason := e.Err.Error()
+	if e.Field != "" {
+		reason = fmt.Sprintf("%s should be %s", e.Field, e.Expected)
+	}
+	return fmt.Sprintf("%s: could not parse %s, %s in %s", ErrMalformedResponse, e.Type, reason, e.Snippet)
+}
+
+// Unwrap will return ErrMalformedResponse
+func (e *ParseError) Unwrap() error {
+	return ErrMalformedResponse
+}
+
+// newParseError will describe a json error that happened while parsing body into data
+func newParseError(body []byte, data interface{}, err error) *ParseError {
+	parseErr := &ParseError{Type: strings.TrimPrefix(fmt.Sprintf("%T", data), "*"), Err: err}
+	offset := int64(0)
+	switch jsonErr := err.(type) {
+	case *json.SyntaxError:
+		offset = jsonErr.Offset
+	case *json.UnmarshalTypeError:
+		offset = jsonErr.Offset
+		parseErr.Field = jsonErr.Field
+		parseErr.Expected = fmt.Sprintf("%s but was %s", jsonErr.Type, jsonErr.Value)
+	}
+	parseErr.Snippet = snippet(body, int(offset))
+	return parseErr
+}
+
+// snippet will return the part of body leading up to offset, quoted so that empty
+// and whitespace bodies are visible.
+func snippet(body []byte, offset int) string {
+	if offset <= 0 || offset > len(body) {
+		offset = len(body)
+	}
+	start := offset - snippetLength
+	if start < 0 {
+		start = 0
+	}
+	quoted := fmt.Sprintf("%q", body[start:offset])
+	if start > 0 {
+		quoted = "..." + quoted
+	}
+	if offset < len(body) {
+		quoted += "..."
+	}
+	return quoted
+}
+
+// AssetResult is the outcome of changing a single asset as part of a bulk request.
+type AssetResult struct {
+	Key     string
+	Success bool
+	Error   error
+}
+
+// BulkError is returned when some of the assets in a bulk request could not be
+// changed. Results holds the outcome of every asset in the request, including
+// those that succeeded, so that all of the failures can be reported at once.
+type BulkError struct {
+	Results []AssetResult
+	// Action is what was done to the assets, like delete. It is update if empty.
+	Action string
+}
+
+// Error will list every asset that failed along with the reason it failed.
+func (e *BulkError) Error() string {
+	failures := []string{}
+	for _, result := range e.Failed() {
+		failures = append(failures, fmt.Sprintf("%s (%s)", result.Key, result.Error))
+	}
+	action := e.Action
+	if action == "" {
+		action = "update"
+	}
+	return "could not " + action + " " + toSentence(failures)
+}
+
+// Unwrap will return the error of the first asset that failed.
+func (e *BulkError) Unwrap() error {
+	if failed := e.Failed(); len(failed) > 0 {
+		return failed[0].Error
+	}
+	return nil
+}
+
+// Failed will return the results of the assets that could not be changed.
+func (e *BulkError) Failed() []AssetResult {
+	failed := []AssetResult{}
+	for _, result := range e.Results {
+		if !result.Success {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// MessagesError holds every validation message shopify responded with, like
+// "name can't be blank", so that they can be reported individually.
+type MessagesError []string
+
+// Error will join the messages into a single sentence.
+func (e MessagesError) Error() string {
+	return toSentence(e)
+}
+
+// newAPIError will create an error for a response that matched a known failure
+func newAPIError(resp *http.Response, err error) error {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  requestID(resp),
+		Err:        err,
+	}
+}
+
+// newAPIMessageError will create an error using the message shopify responded with
+func newAPIMessageError(resp *http.Response, message string) error {
+	return &APIError{
+		StatusCode:     resp.StatusCode,
+		ShopifyMessage: message,
+		RequestID:      requestID(resp),
+	}
+}
+
+// newAPIMessagesError will create an error from the validation errors shopify
+// responded with. The messages are available by unwrapping it to a MessagesError.
+func newAPIMessagesError(resp *http.Response, errs map[string][]string) error {
+	messages := MessagesError(toMessages(errs))
+	return &APIError{
+		StatusCode:     resp.StatusCode,
+		ShopifyMessage: messages.Error(),
+		RequestID:      requestID(resp),
+		Err:            messages,
+	}
+}
+
+// requestID will return the id that shopify assigned to the request that produced
+// the response.
+func requestID(resp *http.Response) string {
+	return resp.Header.Get("X-Request-Id")
+}
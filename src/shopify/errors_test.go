@@ -0,0 +1,61 @@
+package shopify
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	resp := jsonResponse("{}", 404)
+	resp.Header = http.Header{"X-Request-Id": {"abc-123"}}
+
+	err := newAPIError(resp, ErrThemeNotFound)
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+	assert.False(t, errors.Is(err, ErrNotPartOfTheme))
+	assert.Equal(t, "requested theme was not found (request id: abc-123)", err.Error())
+
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 404, apiErr.StatusCode)
+		assert.Equal(t, "abc-123", apiErr.RequestID)
+	}
+
+	err = newAPIMessageError(jsonResponse("{}", 422), "name can't be blank")
+	assert.Equal(t, "name can't be blank", err.Error())
+	assert.Nil(t, errors.Unwrap(err))
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 422, apiErr.StatusCode)
+		assert.Equal(t, "name can't be blank", apiErr.ShopifyMessage)
+	}
+}
+
+func TestAPIMessagesError(t *testing.T) {
+	err := newAPIMessagesError(jsonResponse("{}", 422), map[string][]string{"src": {"is empty"}, "name": {"can't be blank"}})
+	assert.Equal(t, "name can't be blank and src is empty", err.Error())
+
+	var messages MessagesError
+	if assert.True(t, errors.As(err, &messages)) {
+		assert.Equal(t, MessagesError{"name can't be blank", "src is empty"}, messages)
+	}
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 422, apiErr.StatusCode)
+		assert.Equal(t, "name can't be blank and src is empty", apiErr.ShopifyMessage)
+	}
+}
+
+func TestBulkError(t *testing.T) {
+	err := &BulkError{Results: []AssetResult{
+		{Key: "assets/a.js", Success: true},
+		{Key: "assets/b.js", Error: newAPIError(jsonResponse("{}", 404), ErrThemeNotFound)},
+		{Key: "assets/c.js", Error: errors.New("timeout")},
+	}}
+
+	assert.Equal(t, "could not update assets/b.js (requested theme was not found) and assets/c.js (timeout)", err.Error())
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+	assert.Equal(t, []AssetResult{err.Results[1], err.Results[2]}, err.Failed())
+	assert.Nil(t, (&BulkError{}).Unwrap())
+}
@@ -0,0 +1,141 @@
+package shopify
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidLiquid is returned if a liquid file did not pass the validation done
+// before uploading it when validate_liquid is set.
+var ErrInvalidLiquid = errors.New("invalid liquid")
+
+var (
+	// liquidBlocks are the tags that have to be closed with a matching end tag
+	liquidBlocks = map[string]bool{
+		"if": true, "unless": true, "case": true, "for": true, "tablerow": true,
+		"capture": true, "comment": true, "raw": true, "form": true, "paginate": true,
+		"schema": true, "javascript": true, "stylesheet": true, "style": true,
+	}
+	// liquidBranches are the tags that split a block and the blocks they can be used in
+	liquidBranches = map[string][]string{
+		"else":  {"if", "unless", "case", "for"},
+		"elsif": {"if", "unless"},
+		"when":  {"case"},
+	}
+	// liquidVerbatim are the blocks whose contents are not parsed as liquid
+	liquidVerbatim = map[string]*regexp.Regexp{
+		"raw":     regexp.MustCompile(`\{%-?\s*endraw\s*-?%\}`),
+		"comment": regexp.MustCompile(`\{%-?\s*endcomment\s*-?%\}`),
+	}
+)
+
+// LiquidError describes a problem found in a liquid file before it was uploaded.
+// It wraps ErrInvalidLiquid so it can be checked with errors.Is.
+type LiquidError struct {
+	Key     string
+	Line    int
+	Message string
+}
+
+// Error will include the file and line of the problem
+func (e *LiquidError) Error() string {
+	return fmt.Sprintf("%s: %s:%d: %s", ErrInvalidLiquid, e.Key, e.Line, e.Message)
+}
+
+// Unwrap will return ErrInvalidLiquid
+func (e *LiquidError) Unwrap() error {
+	return ErrInvalidLiquid
+}
+
+type liquidBlock struct {
+	name string
+	line int
+}
+
+// validateLiquid will do a best effort check of the liquid in an asset, looking for
+// tags and outputs that are not closed and blocks that are not ended. It does not
+// know about filters or the contents of tags so a file that passes may still be
+// rejected by shopify. Assets that are not liquid files are not checked.
+func validateLiquid(asset Asset) error {
+	if !strings.HasSuffix(asset.Key, ".liquid") || asset.Value == "" {
+		return nil
+	}
+
+	src := asset.Value
+	fail := func(offset int, format string, args ...interface{}) error {
+		line := strings.Count(src[:offset], "\n") + 1
+		return &LiquidError{Key: asset.Key, Line: line, Message: fmt.Sprintf(format, args...)}
+	}
+
+	stack := []liquidBlock{}
+	for pos := 0; pos < len(src); {
+		start := strings.Index(src[pos:], "{")
+		if start == -1 || pos+start+1 >= len(src) {
+			break
+		}
+		start += pos
+		pos = start + 1
+
+		switch src[start+1] {
+		case '{':
+			end := strings.Index(src[start+2:], "}}")
+			if end == -1 {
+				return fail(start, "output {{ is not closed with }}")
+			}
+			pos = start + 2 + end + 2
+		case '%':
+			end := strings.Index(src[start+2:], "%}")
+			if end == -1 {
+				return fail(start, "tag {%% is not closed with %%}")
+			}
+			pos = start + 2 + end + 2
+
+			markup := strings.TrimSpace(strings.Trim(src[start+2:start+2+end], "-"))
+			fields := strings.Fields(markup)
+			if len(fields) == 0 {
+				return fail(start, "tag {%% %%} is empty")
+			}
+			name := fields[0]
+
+			if strings.HasPrefix(name, "#") {
+				continue
+			} else if closing, ok := liquidVerbatim[name]; ok {
+				loc := closing.FindStringIndex(src[pos:])
+				if loc == nil {
+					return fail(start, "%s is not closed, expected end%s", name, name)
+				}
+				pos += loc[1]
+			} else if liquidBlocks[name] {
+				stack = append(stack, liquidBlock{name: name, line: strings.Count(src[:start], "\n") + 1})
+			} else if allowed, ok := liquidBranches[name]; ok {
+				if len(stack) == 0 || !contains(allowed, stack[len(stack)-1].name) {
+					return fail(start, "%s can only be used inside %s", name, SentenceStyle{Conjunction: "or", Separator: ", "}.Join(allowed))
+				}
+			} else if strings.HasPrefix(name, "end") && liquidBlocks[name[3:]] {
+				if len(stack) == 0 {
+					return fail(start, "%s does not have a matching %s", name, name[3:])
+				} else if open := stack[len(stack)-1]; open.name != name[3:] {
+					return fail(start, "expected end%s to close the %s on line %d but found %s", open.name, open.name, open.line, name)
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		open := stack[len(stack)-1]
+		return &LiquidError{Key: asset.Key, Line: open.line, Message: fmt.Sprintf("%s is not closed, expected end%s", open.name, open.name)}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package shopify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLiquid(t *testing.T) {
+	testcases := []struct {
+		key, value, err string
+	}{
+		{key: "templates/index.liquid", value: "{% if product %}\n  {{ product.title | escape }}\n{% elsif collection %}\n{% else %}\n{% endif %}"},
+		{key: "templates/index.liquid", value: "{%- for item in cart.items -%}\n{{- item.title -}}\n{%- else -%}\nempty\n{%- endfor -%}"},
+		{key: "sections/header.liquid", value: "{% schema %}\n{\"name\": \"Header\"}\n{% endschema %}"},
+		{key: "snippets/raw.liquid", value: "{% raw %}{{ not closed {% if %}{% endraw %}"},
+		{key: "snippets/comment.liquid", value: "{% comment %}\n{% if unclosed %}\n{% endcomment %}\n{% # inline comment %}"},
+		{key: "snippets/liquid.liquid", value: "{% liquid\n  if product\n    echo product.title\n  endif\n%}"},
+		{key: "snippets/case.liquid", value: "{% case x %}{% when 1 %}one{% else %}other{% endcase %}"},
+		{key: "assets/app.js", value: "{% if unclosed"},
+		{key: "assets/app.css.liquid", value: "body { color: {{ settings.color }}; }"},
+		{key: "templates/index.liquid", value: "line one\n{{ product.title \nline three", err: "templates/index.liquid:2: output {{ is not closed with }}"},
+		{key: "templates/index.liquid", value: "\n\n{% if product", err: "templates/index.liquid:3: tag {% is not closed with %}"},
+		{key: "templates/index.liquid", value: "{% if a %}\n{% for b in c %}\n{% endif %}", err: "templates/index.liquid:3: expected endfor to close the for on line 2 but found endif"},
+		{key: "templates/index.liquid", value: "{% if a %}\n\n{% unless b %}{% endunless %}", err: "templates/index.liquid:1: if is not closed, expected endif"},
+		{key: "templates/index.liquid", value: "text\n{% endif %}", err: "templates/index.liquid:2: endif does not have a matching if"},
+		{key: "templates/index.liquid", value: "{% for a in b %}{% elsif c %}{% endfor %}", err: "templates/index.liquid:1: elsif can only be used inside if or unless"},
+		{key: "templates/index.liquid", value: "{% when 1 %}", err: "templates/index.liquid:1: when can only be used inside case"},
+		{key: "templates/index.liquid", value: "{% raw %}\n{{ a }}", err: "templates/index.liquid:1: raw is not closed, expected endraw"},
+		{key: "templates/index.liquid", value: "{%  %}", err: "templates/index.liquid:1: tag {% %} is empty"},
+	}
+
+	for _, testcase := range testcases {
+		err := validateLiquid(Asset{Key: testcase.key, Value: testcase.value})
+		if testcase.err == "" {
+			assert.Nil(t, err, testcase.value)
+		} else if assert.NotNil(t, err, testcase.value) {
+			assert.Equal(t, "invalid liquid: "+testcase.err, err.Error())
+			assert.True(t, errors.Is(err, ErrInvalidLiquid))
+		}
+	}
+}
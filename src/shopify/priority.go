@@ -0,0 +1,36 @@
+package shopify
+
+import (
+	"sort"
+	"strings"
+)
+
+// uploadPriorities are the directories of a theme in the order they should be
+// uploaded so that the files a file depends on already exist when it is validated
+// by shopify. Snippets are rendered by sections, sections are referenced by
+// templates and layouts, and the settings data refers to all of them.
+var uploadPriorities = []string{"config/", "locales/", "assets/", "snippets/", "sections/", "templates/", "layout/"}
+
+// UploadPriority will return the priority of an asset when uploading a theme,
+// assets with a lower priority should be uploaded first. The settings schema is
+// uploaded before anything else and the settings data after everything else.
+// Files outside of the theme directories are uploaded with the assets.
+func UploadPriority(key string) int {
+	if key == SettingsDataKey {
+		return len(uploadPriorities)
+	}
+	for priority, dir := range uploadPriorities {
+		if strings.HasPrefix(key, dir) {
+			return priority
+		}
+	}
+	return 2
+}
+
+// SortForUpload will order the assets by their upload priority. Assets with the
+// same priority keep their order.
+func SortForUpload(assets []Asset) {
+	sort.SliceStable(assets, func(i, j int) bool {
+		return UploadPriority(assets[i].Key) < UploadPriority(assets[j].Key)
+	})
+}
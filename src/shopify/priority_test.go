@@ -0,0 +1,58 @@
+package shopify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadPriority(t *testing.T) {
+	testcases := []struct {
+		key      string
+		priority int
+	}{
+		{key: "config/settings_schema.json", priority: 0},
+		{key: "locales/en.default.json", priority: 1},
+		{key: "assets/theme.js", priority: 2},
+		{key: "snippets/icon.liquid", priority: 3},
+		{key: "sections/header.liquid", priority: 4},
+		{key: "templates/index.liquid", priority: 5},
+		{key: "templates/customers/login.liquid", priority: 5},
+		{key: "layout/theme.liquid", priority: 6},
+		{key: "config/settings_data.json", priority: 7},
+		{key: "unknown/file.txt", priority: 2},
+	}
+
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.priority, UploadPriority(testcase.key), testcase.key)
+	}
+}
+
+func TestSortForUpload(t *testing.T) {
+	assets := []Asset{
+		{Key: "layout/theme.liquid"},
+		{Key: "config/settings_data.json"},
+		{Key: "templates/product.liquid"},
+		{Key: "sections/footer.liquid"},
+		{Key: "templates/index.liquid"},
+		{Key: "snippets/icon.liquid"},
+		{Key: "assets/theme.js"},
+		{Key: "sections/header.liquid"},
+		{Key: "locales/en.default.json"},
+		{Key: "config/settings_schema.json"},
+	}
+
+	SortForUpload(assets)
+	assert.Equal(t, []string{
+		"config/settings_schema.json",
+		"locales/en.default.json",
+		"assets/theme.js",
+		"snippets/icon.liquid",
+		"sections/footer.liquid",
+		"sections/header.liquid",
+		"templates/product.liquid",
+		"templates/index.liquid",
+		"layout/theme.liquid",
+		"config/settings_data.json",
+	}, assetsToFilenames(assets))
+}
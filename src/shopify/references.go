@@ -0,0 +1,197 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// snippetTagRegexp matches render and include tags, including the ones on their
+	// own line in a liquid tag, and captures the name of the snippet if it is a
+	// string or otherwise the expression that is rendered
+	snippetTagRegexp = regexp.MustCompile(`(?m)(?:\{%-?|^)\s*(?:render|include)\s+(?:['"]([^'"]+)['"]|(\S+))`)
+	// sectionTagRegexp matches section and sections tags and captures whether it
+	// renders a section group and the name of the section or group
+	sectionTagRegexp = regexp.MustCompile(`(?m)(?:\{%-?|^)\s*section(s?)\s+['"]([^'"]+)['"]`)
+	// assetFilterRegexp matches every use of the asset url filters
+	assetFilterRegexp = regexp.MustCompile(`\|\s*asset_(?:img_)?url\b`)
+	// literalAssetRegexp matches the asset url filters used directly on a string
+	// that is output or assigned
+	literalAssetRegexp = regexp.MustCompile(`(?:\{\{-?|=)\s*['"][^'"]+['"]\s*\|\s*asset_(?:img_)?url\b`)
+)
+
+// UnusedAssets will return the keys of the snippets and assets that no other file
+// in the theme refers to, see FindUnusedAssets. Only the text files are downloaded
+// since binary files cannot refer to other files. Ignored files are never reported.
+func (c Client) UnusedAssets() ([]string, error) {
+	return c.UnusedAssetsContext(context.Background())
+}
+
+// UnusedAssetsContext is the same as UnusedAssets but the requests are bound to the
+// context so they can be cancelled.
+func (c Client) UnusedAssetsContext(ctx context.Context) ([]string, error) {
+	listed, err := c.listAssets(ctx, "key")
+	if err != nil {
+		return nil, err
+	}
+
+	assets := withoutGenerated(listed)
+	text := []string{}
+	for key := range assets {
+		if !binaryExtensions[strings.ToLower(filepath.Ext(key))] {
+			text = append(text, key)
+		}
+	}
+	sort.Strings(text)
+
+	downloaded, err := c.DownloadAssetsContext(ctx, text, 0)
+	if err != nil {
+		return nil, err
+	}
+	for key, asset := range downloaded {
+		assets[key] = asset
+	}
+
+	unused := []string{}
+	for _, key := range FindUnusedAssets(assets) {
+		if !c.filter.Match(key) {
+			unused = append(unused, key)
+		}
+	}
+	return unused, nil
+}
+
+// FindUnusedAssets will return the sorted keys of the snippets and assets that are
+// not referred to by any other asset. A snippet is used if it is rendered or
+// included by name and an asset is used if its file name appears in another file,
+// which covers the asset_url filter, stylesheets and settings. Other files, like
+// sections and templates, are never reported since shopify uses them without a
+// reference. References that are built while rendering, like
+// {% render block.type %}, cannot be followed so if there are any no snippets are
+// reported, and the same goes for assets.
+func FindUnusedAssets(assets map[string]Asset) []string {
+	snippets, files := map[string]string{}, map[string]string{}
+	for key := range assets {
+		name := strings.TrimSuffix(path.Base(key), ".liquid")
+		if strings.HasPrefix(key, "snippets/") {
+			snippets[name] = key
+		} else if strings.HasPrefix(key, "assets/") {
+			files[key] = name
+		}
+	}
+
+	renderedSnippets, dynamicSnippets := map[string]bool{}, false
+	dynamicAssets := false
+	for key, asset := range assets {
+		if !strings.HasSuffix(key, ".liquid") {
+			continue
+		}
+		for _, match := range snippetTagRegexp.FindAllStringSubmatch(asset.Value, -1) {
+			if match[1] != "" {
+				renderedSnippets[match[1]] = true
+			} else {
+				dynamicSnippets = true
+			}
+		}
+		if len(assetFilterRegexp.FindAllString(asset.Value, -1)) > len(literalAssetRegexp.FindAllString(asset.Value, -1)) {
+			dynamicAssets = true
+		}
+	}
+
+	unused := []string{}
+	if !dynamicSnippets {
+		for name, key := range snippets {
+			if !renderedSnippets[name] {
+				unused = append(unused, key)
+			}
+		}
+	}
+	if !dynamicAssets {
+		for key, name := range files {
+			if !referencedByOthers(assets, key, name) {
+				unused = append(unused, key)
+			}
+		}
+	}
+
+	sort.Strings(unused)
+	return unused
+}
+
+// referencedByOthers will check if the name is used in any file other than the
+// asset itself
+func referencedByOthers(assets map[string]Asset, key, name string) bool {
+	for other, asset := range assets {
+		if other != key && strings.Contains(asset.Value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingReference is a snippet or section that a liquid file refers to but that
+// does not exist in the theme.
+type MissingReference struct {
+	// Key is the file with the reference and Line the line it is on, starting at 1
+	Key  string
+	Line int
+	// Missing is the key of the file that does not exist, like snippets/a.liquid
+	Missing string
+}
+
+func (ref MissingReference) String() string {
+	return fmt.Sprintf("%s:%d refers to %s which does not exist", ref.Key, ref.Line, ref.Missing)
+}
+
+// FindMissingReferences will check that every snippet rendered or included by name
+// and every section or section group that the liquid assets refer to is one of the
+// assets or one of the existing keys, which can be the files that are already on
+// shopify. References that are built while rendering cannot be checked and are
+// skipped. The missing references are sorted by file and line.
+func FindMissingReferences(assets map[string]Asset, existing ...string) []MissingReference {
+	exists := map[string]bool{}
+	for _, key := range existing {
+		exists[key] = true
+	}
+	for key := range assets {
+		exists[key] = true
+	}
+
+	missing := []MissingReference{}
+	for key, asset := range assets {
+		if !strings.HasSuffix(key, ".liquid") {
+			continue
+		}
+		check := func(ref string, offset int) {
+			if !exists[ref] {
+				line := strings.Count(asset.Value[:offset], "\n") + 1
+				missing = append(missing, MissingReference{Key: key, Line: line, Missing: ref})
+			}
+		}
+		for _, match := range snippetTagRegexp.FindAllStringSubmatchIndex(asset.Value, -1) {
+			if match[2] >= 0 {
+				check("snippets/"+asset.Value[match[2]:match[3]]+".liquid", match[2])
+			}
+		}
+		for _, match := range sectionTagRegexp.FindAllStringSubmatchIndex(asset.Value, -1) {
+			ext := ".liquid"
+			if match[3] > match[2] {
+				ext = ".json"
+			}
+			check("sections/"+asset.Value[match[4]:match[5]]+ext, match[4])
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Key != missing[j].Key {
+			return missing[i].Key < missing[j].Key
+		}
+		return missing[i].Line < missing[j].Line
+	})
+	return missing
+}
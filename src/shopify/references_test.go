@@ -0,0 +1,105 @@
+package shopify
+
+import (
+	"testing"
+
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/shopify/_mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFindUnusedAssets(t *testing.T) {
+	theme := func(extra ...Asset) map[string]Asset {
+		assets := map[string]Asset{
+			"layout/theme.liquid": {Key: "layout/theme.liquid", Value: `{{ 'theme.css' | asset_url | stylesheet_tag }}
+{%- render 'header', title: page_title -%}
+{%- assign icons = 'icons.svg' | asset_url -%}
+{{ content_for_layout }}`},
+			"sections/footer.liquid": {Key: "sections/footer.liquid", Value: `{% liquid
+  include "social"
+%}`},
+			"templates/index.json":    {Key: "templates/index.json", Value: `{"sections":{"hero":{"type":"hero","settings":{"image":"hero.jpg"}}}}`},
+			"sections/hero.liquid":    {Key: "sections/hero.liquid", Value: `<h1>{{ section.settings.title }}</h1>`},
+			"snippets/header.liquid":  {Key: "snippets/header.liquid", Value: `<img src="{{ 'logo.png' | asset_img_url: '100x' }}">`},
+			"snippets/social.liquid":  {Key: "snippets/social.liquid"},
+			"snippets/old.liquid":     {Key: "snippets/old.liquid", Value: `{{ 'unused.js' | asset_url }}`},
+			"assets/theme.css.liquid": {Key: "assets/theme.css.liquid", Value: `body { background: url(bg.png); }`},
+			"assets/bg.png":           {Key: "assets/bg.png"},
+			"assets/logo.png":         {Key: "assets/logo.png"},
+			"assets/hero.jpg":         {Key: "assets/hero.jpg"},
+			"assets/unused.js":        {Key: "assets/unused.js"},
+			"assets/orphan.svg":       {Key: "assets/orphan.svg"},
+			"assets/icons.svg":        {Key: "assets/icons.svg"},
+		}
+		for _, asset := range extra {
+			assets[asset.Key] = asset
+		}
+		return assets
+	}
+
+	assert.Equal(t, []string{"assets/orphan.svg", "snippets/old.liquid"}, FindUnusedAssets(theme()))
+
+	dynamicSnippet := Asset{Key: "sections/blocks.liquid", Value: `{% for block in section.blocks %}{% render block.type %}{% endfor %}`}
+	assert.Equal(t, []string{"assets/orphan.svg"}, FindUnusedAssets(theme(dynamicSnippet)))
+
+	dynamicAsset := Asset{Key: "sections/icons.liquid", Value: `{{ icon | append: '.svg' | asset_url }}`}
+	assert.Equal(t, []string{"snippets/old.liquid"}, FindUnusedAssets(theme(dynamicAsset)))
+
+	assert.Equal(t, []string{}, FindUnusedAssets(map[string]Asset{}))
+}
+
+func TestFindMissingReferences(t *testing.T) {
+	assets := map[string]Asset{
+		"layout/theme.liquid": {Key: "layout/theme.liquid", Value: `<html>
+{% sections 'header-group' %}
+{%- render 'meta-tags' -%}
+{{ content_for_layout }}
+{% section 'footer' %}
+</html>`},
+		"templates/page.liquid": {Key: "templates/page.liquid", Value: `{% liquid
+  render 'title'
+  include "missing-include"
+  for block in section.blocks
+    render block.type
+  endfor
+%}
+{% render 'icon', name: 'cart' %}`},
+		"snippets/meta-tags.liquid": {Key: "snippets/meta-tags.liquid"},
+		"snippets/title.liquid":     {Key: "snippets/title.liquid", Value: `{% render 'title' %}`},
+		"sections/footer.liquid":    {Key: "sections/footer.liquid", Value: `{% schema %}{"name":"footer"}{% endschema %}`},
+		"templates/index.json":      {Key: "templates/index.json", Value: `{% render 'not-liquid' %}`},
+	}
+
+	assert.Equal(t, []MissingReference{
+		{Key: "layout/theme.liquid", Line: 2, Missing: "sections/header-group.json"},
+		{Key: "templates/page.liquid", Line: 3, Missing: "snippets/missing-include.liquid"},
+		{Key: "templates/page.liquid", Line: 8, Missing: "snippets/icon.liquid"},
+	}, FindMissingReferences(assets))
+
+	assert.Equal(t, []MissingReference{
+		{Key: "templates/page.liquid", Line: 3, Missing: "snippets/missing-include.liquid"},
+	}, FindMissingReferences(assets, "sections/header-group.json", "snippets/icon.liquid"))
+
+	assert.Equal(t, "templates/page.liquid:3 refers to snippets/missing-include.liquid which does not exist", MissingReference{Key: "templates/page.liquid", Line: 3, Missing: "snippets/missing-include.liquid"}.String())
+	assert.Equal(t, []MissingReference{}, FindMissingReferences(map[string]Asset{}))
+}
+
+func TestThemeClient_UnusedAssets(t *testing.T) {
+	listed := `{"assets":[{"key":"layout/theme.liquid"},{"key":"snippets/old.liquid"},{"key":"snippets/ignored.liquid"},{"key":"assets/logo.png"},{"key":"assets/app.js"}]}`
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: []string{"snippets/ignored.liquid"}})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(listed, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(jsonResponse(`{"asset":{"key":"layout/theme.liquid","value":"{{ 'app.js' | asset_url }}"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=snippets%2Fold.liquid").Return(jsonResponse(`{"asset":{"key":"snippets/old.liquid","value":"old"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=snippets%2Fignored.liquid").Return(jsonResponse(`{"asset":{"key":"snippets/ignored.liquid","value":""}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.js").Return(jsonResponse(`{"asset":{"key":"assets/app.js","value":"init()"}}`, 200), nil)
+
+	unused, err := client.UnusedAssets()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"assets/logo.png", "snippets/old.liquid"}, unused)
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Flogo.png")
+}
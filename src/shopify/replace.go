@@ -0,0 +1,152 @@
+package shopify
+
+import (
+	"context"
+	"sort"
+	"strconv"
+)
+
+// ReplaceOptions change how Replace swaps the contents of a theme.
+type ReplaceOptions struct {
+	// ThemeName is the name of a new unpublished theme to replace the contents of
+	// instead of the theme of the client, so that the current theme is never seen
+	// half replaced.
+	ThemeName string
+	// Publish will publish the theme once all of its contents were replaced.
+	Publish bool
+}
+
+// ReplaceState is how far a Replace got. If a replace fails, passing the same state
+// to Replace again resumes it instead of starting over. It can be saved as json to
+// resume a replace in another process.
+type ReplaceState struct {
+	// ThemeID is the theme being replaced, it is set once a new theme was created.
+	ThemeID int64 `json:"theme_id,omitempty"`
+	// Uploaded maps the keys that were uploaded to the checksum of their contents
+	// so that a file that changed since is uploaded again.
+	Uploaded map[string]string `json:"uploaded,omitempty"`
+}
+
+// Replace will replace the contents of the theme with the local assets in one
+// coordinated pass. Every local asset is uploaded first, in their upload priority
+// so that the files other files depend on exist first, then the remote assets
+// that are not part of the local set are deleted in the opposite order so that
+// no file is deleted while a remaining file still refers to it. Finally the theme
+// is published if requested. Ignored files are neither uploaded nor deleted and the
+// assets a theme requires are never deleted. The state records every step that
+// is done so that a replace that failed part way can be resumed with it, see
+// ReplaceState.
+func (c Client) Replace(local map[string]Asset, opts ReplaceOptions, state *ReplaceState) error {
+	return c.ReplaceContext(context.Background(), local, opts, state)
+}
+
+// ReplaceContext is the same as Replace but the requests are bound to the context so
+// they can be cancelled.
+func (c Client) ReplaceContext(ctx context.Context, local map[string]Asset, opts ReplaceOptions, state *ReplaceState) error {
+	if state.Uploaded == nil {
+		state.Uploaded = map[string]string{}
+	}
+
+	target := c
+	if state.ThemeID == 0 && opts.ThemeName != "" {
+		if c.dryRun {
+			c.log.Printf("[dry-run] would create theme %s and upload %d assets to it", opts.ThemeName, len(local))
+			return nil
+		}
+		theme, err := c.createTheme(ctx, Theme{Name: opts.ThemeName, Role: "unpublished"})
+		if err != nil {
+			return err
+		}
+		state.ThemeID = theme.ID
+	}
+	if state.ThemeID != 0 {
+		target = target.forTheme(state.ThemeID)
+		if opts.ThemeName != "" {
+			// the new theme is never the published one
+			target.guard = nil
+		}
+	}
+
+	if err := target.replaceUploads(ctx, local, state); err != nil {
+		return err
+	} else if err := target.replaceDeletes(ctx, local); err != nil {
+		return err
+	}
+
+	if !opts.Publish {
+		return nil
+	} else if c.dryRun {
+		c.log.Printf("[dry-run] would publish theme %s", target.themeID)
+		return nil
+	} else if target.themeID == "" {
+		// the live theme is already published
+		return nil
+	}
+	id, err := strconv.ParseInt(target.themeID, 10, 64)
+	if err != nil {
+		return err
+	}
+	return target.PublishContext(ctx, id)
+}
+
+// replaceUploads will upload the local assets that were not uploaded with the same
+// contents yet and record the ones that succeeded in the state.
+func (c Client) replaceUploads(ctx context.Context, local map[string]Asset, state *ReplaceState) error {
+	uploads, checksums := []Asset{}, map[string]string{}
+	for key, asset := range local {
+		if !c.filter.Included(key) || c.filter.Match(key) {
+			continue
+		}
+		checksum, err := asset.ComputeChecksum()
+		if err != nil {
+			return err
+		} else if uploaded, ok := state.Uploaded[key]; ok && uploaded == checksum {
+			continue
+		}
+		checksums[key] = checksum
+		uploads = append(uploads, asset)
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Key < uploads[j].Key })
+
+	results, err := c.BulkUpdateAssetsResultsContext(ctx, uploads)
+	for _, result := range results {
+		if result.Success && !c.dryRun {
+			state.Uploaded[result.Key] = checksums[result.Key]
+		}
+	}
+	return err
+}
+
+// replaceDeletes will delete the remote assets that are not part of the local set,
+// the ones with the highest upload priority first. The assets a theme requires are
+// kept. Since deleted assets are no longer listed a resumed replace only deletes
+// the ones that are left.
+func (c Client) replaceDeletes(ctx context.Context, local map[string]Asset) error {
+	remote, err := c.GetAllAssetsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	groups := map[int][]string{}
+	for _, key := range remote {
+		if _, ok := local[key]; ok || requiredAssets[key] {
+			continue
+		} else if _, ok := local[key+".liquid"]; ok {
+			continue
+		}
+		priority := UploadPriority(key)
+		groups[priority] = append(groups[priority], key)
+	}
+
+	priorities := []int{}
+	for priority := range groups {
+		priorities = append(priorities, priority)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+	for _, priority := range priorities {
+		if err := c.BulkDeleteAssetsContext(ctx, groups[priority]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
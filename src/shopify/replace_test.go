@@ -0,0 +1,104 @@
+package shopify
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/shopify/_mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestThemeClient_Replace(t *testing.T) {
+	local := map[string]Asset{
+		"layout/theme.liquid":     {Key: "layout/theme.liquid", Value: "{{ content_for_layout }}"},
+		"assets/app.js":           {Key: "assets/app.js", Value: "app()"},
+		"assets/style.css.liquid": {Key: "assets/style.css.liquid", Value: "body {}"},
+	}
+	listed := `{"assets":[{"key":"layout/theme.liquid"},{"key":"assets/app.js"},{"key":"assets/style.css"},{"key":"assets/old.css"},{"key":"snippets/old.liquid"},{"key":"templates/old.liquid"},{"key":"config/settings_schema.json"}]}`
+	uploaded := []Asset{local["assets/app.js"], local["assets/style.css.liquid"], local["layout/theme.liquid"]}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Post", mock.Anything, "/admin/themes.json", map[string]interface{}{"theme": Theme{Name: "fresh", Role: "unpublished"}}).
+		Return(jsonResponse(`{"theme":{"id":456,"name":"fresh","role":"unpublished"}}`, 201), nil)
+	m.On("Put", mock.Anything, "/admin/themes/456/assets/bulk.json", map[string][]Asset{"assets": uploaded}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/app.js"}}},{"code":200,"body":{"asset":{"key":"assets/style.css.liquid"}}},{"code":200,"body":{"asset":{"key":"layout/theme.liquid"}}}]}`, 207), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?fields=key").Return(jsonResponse(listed, 200), nil)
+	var lock sync.Mutex
+	deleted := []string{}
+	for _, path := range []string{"templates%2Fold.liquid", "snippets%2Fold.liquid", "assets%2Fold.css"} {
+		m.On("Delete", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D="+path).
+			Run(func(args mock.Arguments) {
+				lock.Lock()
+				defer lock.Unlock()
+				deleted = append(deleted, args.String(1))
+			}).
+			Return(jsonResponse(`{}`, 200), nil)
+	}
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(jsonResponse(`{"theme":{"id":456,"role":"unpublished"}}`, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/456.json", map[string]map[string]string{"theme": {"role": "main"}}).
+		Return(jsonResponse(`{"theme":{"id":456,"role":"main"}}`, 200), nil)
+
+	state := &ReplaceState{}
+	err := client.Replace(local, ReplaceOptions{ThemeName: "fresh", Publish: true}, state)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(456), state.ThemeID)
+	assert.Equal(t, 3, len(state.Uploaded))
+	assert.Equal(t, "123", client.themeID)
+	assert.Equal(t, []string{
+		"/admin/themes/456/assets.json?asset%5Bkey%5D=templates%2Fold.liquid",
+		"/admin/themes/456/assets.json?asset%5Bkey%5D=snippets%2Fold.liquid",
+		"/admin/themes/456/assets.json?asset%5Bkey%5D=assets%2Fold.css",
+	}, deleted)
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Delete", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=assets%2Fstyle.css")
+	m.AssertNotCalled(t, "Delete", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=config%2Fsettings_schema.json")
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/456/assets/bulk.json", map[string][]Asset{"assets": uploaded}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/app.js"}}},{"code":200,"body":{"asset":{"key":"assets/style.css.liquid"}}},{"code":422,"body":{"errors":{"asset":["Liquid syntax error"]}}}]}`, 207), nil)
+	state = &ReplaceState{ThemeID: 456}
+	err = client.Replace(local, ReplaceOptions{ThemeName: "fresh", Publish: true}, state)
+	assert.EqualError(t, err, "could not update layout/theme.liquid (Liquid syntax error)")
+	assert.Equal(t, 2, len(state.Uploaded))
+	m.AssertNotCalled(t, "Post", mock.Anything, mock.Anything, mock.Anything)
+	m.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/456/assets/bulk.json", map[string][]Asset{"assets": uploaded[2:]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"layout/theme.liquid"}}}]}`, 207), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"layout/theme.liquid"},{"key":"assets/app.js"}]}`, 200), nil)
+	err = client.Replace(local, ReplaceOptions{ThemeName: "fresh"}, state)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(state.Uploaded))
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Post", mock.Anything, "/admin/themes.json", mock.Anything).Return(nil, errors.New("connection reset"))
+	state = &ReplaceState{}
+	err = client.Replace(local, ReplaceOptions{ThemeName: "fresh"}, state)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), state.ThemeID)
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestThemeClient_ReplaceDryRun(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", DryRun: true})
+	client.http = m
+
+	state := &ReplaceState{}
+	err := client.Replace(map[string]Asset{"assets/app.js": {Key: "assets/app.js", Value: "app()"}}, ReplaceOptions{ThemeName: "fresh", Publish: true}, state)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), state.ThemeID)
+	assert.Equal(t, 0, len(state.Uploaded))
+	m.AssertNotCalled(t, "Post", mock.Anything, mock.Anything, mock.Anything)
+}
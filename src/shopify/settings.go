@@ -0,0 +1,127 @@
+package shopify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// SettingsDataKey is the key of the asset that holds the theme settings that
+	// merchants configure in the theme editor.
+	SettingsDataKey = "config/settings_data.json"
+	// SettingsSchemaKey is the key of the asset that describes the theme settings.
+	SettingsSchemaKey = "config/settings_schema.json"
+)
+
+// ErrMalformedSchema is returned if the settings schema of a theme is not a list
+// of setting groups.
+var ErrMalformedSchema = errors.New("malformed settings schema")
+
+// SchemaError is the reason a settings schema is malformed. It wraps
+// ErrMalformedSchema so it can be checked with errors.Is.
+type SchemaError struct {
+	// Path points to the part of the schema that is malformed, like [2].settings[0],
+	// it is empty if the schema as a whole is malformed.
+	Path   string
+	Reason string
+}
+
+// Error will describe what part of the schema is malformed.
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", ErrMalformedSchema, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s %s", ErrMalformedSchema, e.Path, e.Reason)
+}
+
+// Unwrap will return ErrMalformedSchema
+func (e *SchemaError) Unwrap() error {
+	return ErrMalformedSchema
+}
+
+// MergeSettingsData will return the local settings data with the current settings
+// taken from the remote settings data. This keeps the settings that were configured
+// on the shop while deploying any other changes like new presets. If the remote
+// settings data has no current settings the local asset is returned as is.
+func MergeSettingsData(local, remote Asset) (Asset, error) {
+	localSettings, err := parseSettingsData(local)
+	if err != nil {
+		return Asset{}, err
+	}
+
+	remoteSettings, err := parseSettingsData(remote)
+	if err != nil {
+		return Asset{}, err
+	}
+
+	current, found := remoteSettings["current"]
+	if !found {
+		return local, nil
+	}
+	localSettings["current"] = current
+
+	data, err := json.MarshalIndent(localSettings, "", "  ")
+	if err != nil {
+		return Asset{}, err
+	}
+	return Asset{Key: local.Key, Value: string(data)}, nil
+}
+
+func parseSettingsData(asset Asset) (map[string]json.RawMessage, error) {
+	settings := map[string]json.RawMessage{}
+	data, err := asset.Bytes()
+	if err != nil {
+		return settings, err
+	} else if len(data) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("could not read the settings in %s: %v", asset.Key, err)
+	}
+	return settings, nil
+}
+
+// validateSettingsSchema will check that the schema is a list of setting groups,
+// each with a name and a list of settings that all have a type.
+func validateSettingsSchema(data []byte) error {
+	var groups []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &groups); err != nil || groups == nil {
+		return &SchemaError{Reason: "it should be a list of setting groups"}
+	}
+
+	for i, group := range groups {
+		path := fmt.Sprintf("[%d]", i)
+		if name, found := group["name"]; !found || !isJSONKind(name, '"', '{') {
+			return &SchemaError{Path: path, Reason: "should have a name"}
+		}
+
+		raw, found := group["settings"]
+		if !found {
+			continue
+		}
+		var settings []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &settings); err != nil || settings == nil {
+			return &SchemaError{Path: path + ".settings", Reason: "should be a list of settings"}
+		}
+		for j, setting := range settings {
+			var settingType string
+			if err := json.Unmarshal(setting["type"], &settingType); err != nil || settingType == "" {
+				return &SchemaError{Path: fmt.Sprintf("%s.settings[%d]", path, j), Reason: "should have a type"}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isJSONKind will check if the raw json value starts with one of the delimiters,
+// like '"' for a string or '{' for an object.
+func isJSONKind(raw json.RawMessage, delims ...byte) bool {
+	for _, delim := range delims {
+		if len(raw) > 0 && raw[0] == delim {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package shopify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSettingsData(t *testing.T) {
+	local := Asset{Key: SettingsDataKey, Value: `{"current":"Default","presets":{"Default":{"color":"blue"},"Dark":{"color":"black"}}}`}
+	remote := Asset{Key: SettingsDataKey, Value: `{"current":{"color":"red"},"presets":{"Default":{"color":"blue"}}}`}
+
+	merged, err := MergeSettingsData(local, remote)
+	assert.Nil(t, err)
+	assert.Equal(t, SettingsDataKey, merged.Key)
+	assert.JSONEq(t, `{"current":{"color":"red"},"presets":{"Default":{"color":"blue"},"Dark":{"color":"black"}}}`, merged.Value)
+
+	merged, err = MergeSettingsData(local, Asset{Key: SettingsDataKey, Value: `{"presets":{}}`})
+	assert.Nil(t, err)
+	assert.Equal(t, local, merged)
+
+	merged, err = MergeSettingsData(Asset{Key: SettingsDataKey}, remote)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"current":{"color":"red"}}`, merged.Value)
+
+	_, err = MergeSettingsData(local, Asset{Key: SettingsDataKey, Value: `{"current":`})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not read the settings in config/settings_data.json")
+	}
+
+	_, err = MergeSettingsData(Asset{Key: SettingsDataKey, Value: `[]`}, remote)
+	assert.NotNil(t, err)
+}
+
+func TestValidateSettingsSchema(t *testing.T) {
+	testcases := []struct {
+		schema, err string
+	}{
+		{schema: `[{"name":"theme_info","theme_name":"Debut"},{"name":"Colors","settings":[{"type":"header","content":"Text"},{"type":"color","id":"color_text","label":"Text","default":"#000"}]}]`},
+		{schema: `[{"name":{"en":"Colors","fr":"Couleurs"},"settings":[]}]`},
+		{schema: `[]`},
+		{schema: `{"name":"Colors"}`, err: "malformed settings schema: it should be a list of setting groups"},
+		{schema: `null`, err: "malformed settings schema: it should be a list of setting groups"},
+		{schema: `[{"name":`, err: "malformed settings schema: it should be a list of setting groups"},
+		{schema: `[{"settings":[]}]`, err: "malformed settings schema: [0] should have a name"},
+		{schema: `[{"name":"Colors"},{"name":2}]`, err: "malformed settings schema: [1] should have a name"},
+		{schema: `[{"name":"Colors","settings":{"type":"color"}}]`, err: "malformed settings schema: [0].settings should be a list of settings"},
+		{schema: `[{"name":"Colors","settings":[{"type":"color"},{"id":"color_text"}]}]`, err: "malformed settings schema: [0].settings[1] should have a type"},
+	}
+
+	for _, testcase := range testcases {
+		err := validateSettingsSchema([]byte(testcase.schema))
+		if testcase.err == "" {
+			assert.Nil(t, err, testcase.schema)
+		} else if assert.NotNil(t, err, testcase.schema) {
+			assert.EqualError(t, err, testcase.err)
+			assert.True(t, errors.Is(err, ErrMalformedSchema))
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package shopify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// sniffLength is how much of a reader is looked at to detect its content type
+	sniffLength = 512
+	hexDigits   = "0123456789abcdef"
+)
+
+// shopifyMessage is returned by streamAsset when shopify responded with an error
+// message instead of an asset.
+type shopifyMessage string
+
+func (msg shopifyMessage) Error() string { return string(msg) }
+
+// streamAsset will read an asset response and write the contents of the asset to
+// w. The value or attachment string is unescaped as it is read so that the asset
+// is never fully held in memory. Attachments are base64 decoded on the fly.
+func streamAsset(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return ErrMalformedResponse
+		}
+
+		switch key {
+		case "errors":
+			var errs json.RawMessage
+			if err := dec.Decode(&errs); err != nil {
+				return ErrMalformedResponse
+			}
+			var message string
+			if json.Unmarshal(errs, &message) != nil {
+				message = string(errs)
+			}
+			return shopifyMessage(message)
+		case "asset":
+			return streamAssetFields(dec, r, w)
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return ErrMalformedResponse
+			}
+		}
+	}
+
+	return ErrMalformedResponse
+}
+
+func streamAssetFields(dec *json.Decoder, r io.Reader, w io.Writer) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return ErrMalformedResponse
+		}
+
+		if key == "value" || key == "attachment" {
+			// the decoder has read ahead, so the rest of the body starts with
+			// whatever it buffered
+			body := bufio.NewReader(io.MultiReader(dec.Buffered(), r))
+			out := bufio.NewWriter(w)
+			if key == "value" {
+				if err := unescapeString(body, out); err != nil {
+					return err
+				}
+				return out.Flush()
+			}
+
+			decoder := &base64Writer{w: out}
+			if err := unescapeString(body, decoder); err != nil {
+				return err
+			} else if err := decoder.Close(); err != nil {
+				return err
+			}
+			return out.Flush()
+		}
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return ErrMalformedResponse
+		}
+	}
+
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil || token != delim {
+		return ErrMalformedResponse
+	}
+	return nil
+}
+
+// unescapeString will read a json string that follows an object key, including the
+// colon before it, and write the unescaped contents to w.
+func unescapeString(r *bufio.Reader, w io.Writer) error {
+	if err := skipTo(r, ':'); err != nil {
+		return err
+	} else if err := skipTo(r, '"'); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 4096)
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return ErrMalformedResponse
+		}
+
+		switch c {
+		case '"':
+			_, err := w.Write(buf)
+			return err
+		case '\\':
+			if buf, err = appendEscape(r, buf); err != nil {
+				return err
+			}
+		default:
+			buf = append(buf, c)
+		}
+
+		if len(buf) >= 4000 {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+}
+
+func skipTo(r *bufio.Reader, expected byte) error {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return ErrMalformedResponse
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case expected:
+			return nil
+		}
+		return ErrMalformedResponse
+	}
+}
+
+func appendEscape(r *bufio.Reader, buf []byte) ([]byte, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return buf, ErrMalformedResponse
+	}
+
+	switch c {
+	case '"', '\\', '/':
+		return append(buf, c), nil
+	case 'b':
+		return append(buf, '\b'), nil
+	case 'f':
+		return append(buf, '\f'), nil
+	case 'n':
+		return append(buf, '\n'), nil
+	case 'r':
+		return append(buf, '\r'), nil
+	case 't':
+		return append(buf, '\t'), nil
+	case 'u':
+		char, err := readHex(r)
+		if err != nil {
+			return buf, err
+		}
+		if utf16.IsSurrogate(char) {
+			if next, err := r.Peek(2); err == nil && string(next) == `\u` {
+				r.Discard(2)
+				low, err := readHex(r)
+				if err != nil {
+					return buf, err
+				}
+				char = utf16.DecodeRune(char, low)
+			} else {
+				char = utf8.RuneError
+			}
+		}
+		return utf8.AppendRune(buf, char), nil
+	}
+
+	return buf, ErrMalformedResponse
+}
+
+func readHex(r *bufio.Reader) (rune, error) {
+	hex := make([]byte, 4)
+	if _, err := io.ReadFull(r, hex); err != nil {
+		return 0, ErrMalformedResponse
+	}
+	char, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil {
+		return 0, ErrMalformedResponse
+	}
+	return rune(char), nil
+}
+
+// base64Writer decodes base64 data written to it in complete blocks of four
+// characters and writes the result to w.
+type base64Writer struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (b *base64Writer) Write(p []byte) (int, error) {
+	b.pending = append(b.pending, p...)
+	n := len(b.pending) / 4 * 4
+	if n == 0 {
+		return len(p), nil
+	}
+
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(n))
+	size, err := base64.StdEncoding.Decode(decoded, b.pending[:n])
+	if err != nil {
+		return 0, err
+	}
+	b.pending = append(b.pending[:0], b.pending[n:]...)
+	if _, err := b.w.Write(decoded[:size]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close will return an error if the data written was not complete.
+func (b *base64Writer) Close() error {
+	if len(b.pending) > 0 {
+		return base64.CorruptInputError(0)
+	}
+	return nil
+}
+
+// encodeAsset will build the json body of an asset update from the contents of r.
+// Whether the contents are sent as a value or an attachment is decided by the
+// content type detected from the key and the start of the contents.
+func encodeAsset(key string, r io.Reader) (json.RawMessage, error) {
+	reader := bufio.NewReaderSize(r, sniffLength)
+	sample, err := reader.Peek(sniffLength)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return encodeAssetAs(key, reader, isBinaryStream(key, sample))
+}
+
+// encodeAssetAs will build the json body of an asset update from the contents of r,
+// sending them as an attachment if binary is set. The contents are escaped or base64
+// encoded as they are read so only the encoded body is held in memory.
+func encodeAssetAs(key string, reader io.Reader, binary bool) (json.RawMessage, error) {
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	body.WriteString(`{"asset":{"key":`)
+	body.Write(encodedKey)
+	if binary {
+		body.WriteString(`,"attachment":"`)
+		encoder := base64.NewEncoder(base64.StdEncoding, &body)
+		if _, err := io.Copy(encoder, reader); err != nil {
+			return nil, err
+		}
+		encoder.Close()
+	} else {
+		body.WriteString(`,"value":"`)
+		if _, err := io.Copy(jsonStringWriter{w: &body}, reader); err != nil {
+			return nil, err
+		}
+	}
+	body.WriteString(`"}}`)
+	return json.RawMessage(body.Bytes()), nil
+}
+
+// isBinaryStream decides if contents that start with sample have to be sent as an
+// attachment.
+func isBinaryStream(key string, sample []byte) bool {
+	ext := strings.ToLower(filepath.Ext(key))
+	if binaryExtensions[ext] {
+		return true
+	}
+
+	// the sample may end part way through a character
+	for i := 0; i < utf8.UTFMax-1 && len(sample) > 0 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+	if !utf8.Valid(sample) {
+		return true
+	}
+
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = http.DetectContentType(sample)
+	}
+	return !isTextContentType(contentType)
+}
+
+// jsonStringWriter escapes everything written to it so that it can be placed in a
+// json string.
+type jsonStringWriter struct {
+	w io.Writer
+}
+
+func (j jsonStringWriter) Write(p []byte) (int, error) {
+	escaped := make([]byte, 0, len(p))
+	for _, c := range p {
+		switch {
+		case c == '"' || c == '\\':
+			escaped = append(escaped, '\\', c)
+		case c == '\n':
+			escaped = append(escaped, '\\', 'n')
+		case c == '\r':
+			escaped = append(escaped, '\\', 'r')
+		case c == '\t':
+			escaped = append(escaped, '\\', 't')
+		case c < 0x20:
+			escaped = append(escaped, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			escaped = append(escaped, c)
+		}
+	}
+	if _, err := j.w.Write(escaped); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
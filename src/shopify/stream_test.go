@@ -0,0 +1,79 @@
+package shopify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamAsset(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0x10, 0x22}
+	testcases := []struct {
+		body, expected, err string
+	}{
+		{body: `{"asset":{"key":"a.txt","value":"hello world"}}`, expected: "hello world"},
+		{body: `{"asset": {"key": "a.txt", "value" : "line\nquote\" slash\/ tab\t"}}`, expected: "line\nquote\" slash/ tab\t"},
+		{body: `{"asset":{"key":"a.txt","value":"café 😀 ü"}}`, expected: "café 😀 ü"},
+		{body: `{"asset":{"key":"a.png","attachment":"` + base64.StdEncoding.EncodeToString(binary) + `"}}`, expected: string(binary)},
+		{body: `{"asset":{"key":"a.txt"}}`, expected: ""},
+		{body: `{"errors":"Not Found"}`, err: "Not Found"},
+		{body: `{"asset":{"key":"a.txt","value":"unterminated`, err: ErrMalformedResponse.Error()},
+		{body: `{"asset":{"key":"a.png","attachment":"abc"}}`, err: "illegal base64"},
+		{body: `not json`, err: ErrMalformedResponse.Error()},
+	}
+
+	for _, testcase := range testcases {
+		var buf bytes.Buffer
+		err := streamAsset(strings.NewReader(testcase.body), &buf)
+		if testcase.err == "" && assert.Nil(t, err, testcase.body) {
+			assert.Equal(t, testcase.expected, buf.String(), testcase.body)
+		} else if testcase.err != "" && assert.NotNil(t, err, testcase.body) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+	}
+}
+
+func TestStreamAsset_Large(t *testing.T) {
+	content := strings.Repeat("{% comment %}\"large\"{% endcomment %}\n", 10000)
+	body, _ := json.Marshal(map[string]Asset{"asset": {Key: "templates/large.liquid", Value: content}})
+
+	var buf bytes.Buffer
+	assert.Nil(t, streamAsset(bytes.NewReader(body), &buf))
+	assert.Equal(t, content, buf.String())
+
+	data := bytes.Repeat([]byte{0x00, 0x01, 0xfe, 0xff, 0x7f}, 10000)
+	body, _ = json.Marshal(map[string]Asset{"asset": {Key: "assets/large.png", Attachment: base64.StdEncoding.EncodeToString(data)}})
+
+	buf.Reset()
+	assert.Nil(t, streamAsset(bytes.NewReader(body), &buf))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestEncodeAsset(t *testing.T) {
+	binary := append([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, bytes.Repeat([]byte{0x10, 0x22}, 600)...)
+	testcases := []struct {
+		key, contents string
+		expected      Asset
+	}{
+		{key: "templates/index.liquid", contents: "{{ \"quote\" }}\n\ttab \\ slash", expected: Asset{Key: "templates/index.liquid", Value: "{{ \"quote\" }}\n\ttab \\ slash"}},
+		{key: "assets/app.js", contents: "café 😀 \x01\r", expected: Asset{Key: "assets/app.js", Value: "café 😀 \x01\r"}},
+		{key: "assets/long.txt", contents: "a" + strings.Repeat("é", 1000), expected: Asset{Key: "assets/long.txt", Value: "a" + strings.Repeat("é", 1000)}},
+		{key: "assets/empty.css", contents: "", expected: Asset{Key: "assets/empty.css"}},
+		{key: "assets/logo.png", contents: "not really a png", expected: Asset{Key: "assets/logo.png", Attachment: base64.StdEncoding.EncodeToString([]byte("not really a png"))}},
+		{key: "assets/data.bin", contents: string(binary), expected: Asset{Key: "assets/data.bin", Attachment: base64.StdEncoding.EncodeToString(binary)}},
+		{key: "assets/noext", contents: string(binary), expected: Asset{Key: "assets/noext", Attachment: base64.StdEncoding.EncodeToString(binary)}},
+	}
+
+	for _, testcase := range testcases {
+		body, err := encodeAsset(testcase.key, strings.NewReader(testcase.contents))
+		if assert.Nil(t, err, testcase.key) {
+			var r map[string]Asset
+			assert.Nil(t, json.Unmarshal(body, &r), testcase.key)
+			assert.Equal(t, testcase.expected, r["asset"], testcase.key)
+		}
+	}
+}
@@ -1,20 +1,27 @@
 package shopify
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Shopify/themekit/src/colors"
 	"github.com/Shopify/themekit/src/env"
 	"github.com/Shopify/themekit/src/file"
 	"github.com/Shopify/themekit/src/httpify"
+	"github.com/Shopify/themekit/src/ratelimiter"
 )
 
 var (
@@ -34,8 +41,60 @@ var (
 	ErrShopDomainNotFound = errors.New("provided myshopify domain does not exist")
 	// ErrMissingAssetName is returned from delete when an invalid key was provided
 	ErrMissingAssetName = errors.New("asset has no name so could not be processes")
+	// ErrPublishFailed is returned if shopify did not make the theme the main theme when publishing
+	ErrPublishFailed = errors.New("theme was not published")
+	// ErrPublishDevelopment is returned when trying to publish a development theme, which shopify never publishes
+	ErrPublishDevelopment = errors.New("development themes cannot be published, duplicate the theme or deploy it to an unpublished theme instead")
+	// ErrDeleteMainTheme is returned when trying to delete the theme that is currently published
+	ErrDeleteMainTheme = errors.New("cannot delete the main theme, publish another theme first")
+	// ErrAssetConflict is returned if the remote asset was changed after the version the update is based on
+	ErrAssetConflict = errors.New("the file was changed on shopify since it was last downloaded")
+	// ErrThemePublished is returned before changing a published theme when require_unpublished is set
+	ErrThemePublished = errors.New("refusing to change the published theme because require_unpublished is set")
+	// ErrResponseTooLarge is returned if a response body is larger than max_response_bytes
+	ErrResponseTooLarge = errors.New("response too large")
+	// ErrThemeProcessing is returned if a theme is still processing once the wait for it timed out
+	ErrThemeProcessing = errors.New("theme is still processing")
+	// ErrThemeLocked is returned if the theme could not be changed because shopify was still processing it
+	ErrThemeLocked = errors.New("the theme is locked while shopify is processing it, try again once it is ready")
+	// ErrInvalidSrc is returned if the url to update an asset from is not an http or https url
+	ErrInvalidSrc = errors.New("the url to copy the asset from should be an http or https url")
+	// ErrUnauthorized is returned if shopify rejected the password, for example because it was revoked
+	ErrUnauthorized = errors.New("shopify rejected the password, it may have expired or been revoked, please run `theme configure` with a new password")
 
 	shopifyAPILimit = time.Second / 2 // 2 calls per second
+	// defaultBatchSize is the amount of assets sent in a single bulk request
+	defaultBatchSize = 5
+	// maxGeneratedAssetRetries is how many times an update is retried after removing
+	// the .liquid files of generated assets that shopify would not overwrite
+	maxGeneratedAssetRetries = 2
+	generatedAssetRegexp     = regexp.MustCompile(`Cannot overwrite generated asset ?(\S*)`)
+	// maxValidationRetries is how many times an update of a retry_keys asset is
+	// retried after shopify rejected it with a validation error
+	maxValidationRetries = 3
+	// validationRetryDelay is how long to wait before retrying a rejected retry_keys
+	// asset so that the assets it refers to have time to be processed
+	validationRetryDelay = 2 * time.Second
+	// requiredAssets are the assets that a theme does not work without, they are
+	// only removed in bulk when forced
+	requiredAssets = map[string]bool{"layout/theme.liquid": true, "config/settings_schema.json": true, SettingsDataKey: true}
+	// defaultRetryKeys are the assets retried on validation errors if retry_keys
+	// is not set. The settings data refers to sections that may still be uploading.
+	defaultRetryKeys = []string{SettingsDataKey}
+	// lockedRetryDelay is how long to wait before sending a change again after
+	// shopify responded with 423 Locked because the theme is still processing
+	lockedRetryDelay = 2 * time.Second
+	// lockedTimeout is how long changes are retried while the theme is locked
+	lockedTimeout = 2 * time.Minute
+	// processingPollDelay is how long to wait before checking a processing theme
+	// again, it doubles after every check up to maxProcessingPollDelay
+	processingPollDelay    = 500 * time.Millisecond
+	maxProcessingPollDelay = 10 * time.Second
+	// defaultConcurrency is the amount of assets downloaded at the same time
+	defaultConcurrency = 4
+	// defaultMaxResponseBytes is the largest response body that will be read. It
+	// leaves room for the largest assets shopify accepts once they are base64 encoded.
+	defaultMaxResponseBytes = 32 << 20
 )
 
 // Theme represents a shopify theme.
@@ -45,16 +104,87 @@ type Theme struct {
 	Source      string `json:"src,omitempty"`
 	Role        string `json:"role,omitempty"`
 	Previewable bool   `json:"previewable,omitempty"`
-	Processing  bool   `json:"processing,omitempty"`
+	// Processing is true while shopify is still extracting the theme zip or copying
+	// the theme, the assets are not complete until it is false.
+	Processing bool `json:"processing,omitempty"`
+	// ThemeStoreID is the id of the theme in the theme store, it is 0 for themes
+	// that were not installed from the theme store.
+	ThemeStoreID      int64  `json:"theme_store_id,omitempty"`
+	AdminGraphQLAPIID string `json:"admin_graphql_api_id,omitempty"`
+	// CreatedAt and UpdatedAt are only read from shopify and never sent back
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// ThemeFilter selects themes by their role and name, an empty field matches every
+// theme.
+type ThemeFilter struct {
+	// Role is the role of the themes: main, unpublished, demo or development.
+	Role string
+	// Name is a part of the name of the themes, it is matched regardless of case.
+	Name string
+}
+
+// Match will return true if the theme matches every field of the filter.
+func (f ThemeFilter) Match(theme Theme) bool {
+	if f.Role != "" && theme.Role != f.Role {
+		return false
+	}
+	return strings.Contains(strings.ToLower(theme.Name), strings.ToLower(f.Name))
+}
+
+// UnmarshalJSON will parse a theme from shopify including its timestamps. Timestamps
+// that are missing or malformed are left as the zero time.
+func (t *Theme) UnmarshalJSON(data []byte) error {
+	type theme Theme
+	aux := struct {
+		*theme
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}{theme: (*theme)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	t.CreatedAt = parseTimestamp(aux.CreatedAt)
+	t.UpdatedAt = parseTimestamp(aux.UpdatedAt)
+	return nil
+}
+
+func parseTimestamp(value string) time.Time {
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return timestamp
+}
+
+// AssetStats is the amount of assets in a theme and their combined size.
+type AssetStats struct {
+	Count      int
+	TotalBytes int64
+	// SizeAvailable is false if shopify did not report the size of every asset, in
+	// which case TotalBytes only adds up the assets that had a size.
+	SizeAvailable bool
 }
 
 // Shop information for the domain your are currently working on
 type Shop struct {
-	ID      int64  `json:"id"`
-	Name    string `json:"name"`
-	City    string `json:"city"`
-	Country string `json:"country"`
-	Desc    string `json:"description"`
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	City            string `json:"city"`
+	Country         string `json:"country"`
+	Desc            string `json:"description"`
+	Domain          string `json:"domain"`
+	MyshopifyDomain string `json:"myshopify_domain"`
+	PlanName        string `json:"plan_name"`
+	PlanDisplayName string `json:"plan_display_name"`
+	Currency        string `json:"currency"`
+	Timezone        string `json:"iana_timezone"`
+	PrimaryLocale   string `json:"primary_locale"`
+}
+
+type shopResponse struct {
+	Shop Shop `json:"shop"`
 }
 
 type themeResponse struct {
@@ -75,296 +205,1871 @@ type assetsResponse struct {
 	Assets []Asset `json:"assets"`
 }
 
+type bulkAssetsResponse struct {
+	Results []struct {
+		Code int           `json:"code"`
+		Body assetResponse `json:"body"`
+	} `json:"results"`
+}
+
 type reqErr struct {
 	Errors string `json:"errors"`
 }
 
 type httpAdapter interface {
-	Get(string) (*http.Response, error)
-	Post(string, interface{}) (*http.Response, error)
-	Put(string, interface{}) (*http.Response, error)
-	Delete(string) (*http.Response, error)
+	Get(context.Context, string) (*http.Response, error)
+	Post(context.Context, string, interface{}) (*http.Response, error)
+	Put(context.Context, string, interface{}) (*http.Response, error)
+	Delete(context.Context, string) (*http.Response, error)
+}
+
+// utilizationReporter is implemented by http adapters that track the api call limit
+type utilizationReporter interface {
+	Utilization() float64
+}
+
+// retryReporter is implemented by http adapters that retry failed requests
+type retryReporter interface {
+	SetRetryObserver(func(httpify.RetryEvent))
+}
+
+// requestReporter is implemented by http adapters that report every request
+type requestReporter interface {
+	SetRequestObserver(func(httpify.RequestEvent))
+}
+
+// limiterSetter is implemented by http adapters whose limiter can be shared
+type limiterSetter interface {
+	SetLimiter(*ratelimiter.Limiter)
 }
 
 // Client is the interactor with the shopify server. All actions are processed
 // with the client.
 type Client struct {
-	themeID string
-	filter  file.Filter
-	http    httpAdapter
+	themeID     string
+	domain      string
+	filter      file.Filter
+	http        httpAdapter
+	batchSize   int
+	concurrency int
+	maxBody     int64
+	generated   bool
+	validate    bool
+	retryKeys   map[string]bool
+	retryDelay  time.Duration
+	lockedDelay time.Duration
+	lockedWait  time.Duration
+	pollDelay   time.Duration
+	cache       *assetCache
+	dryRun      bool
+	log         *log.Logger
+	progress    ProgressReporter
+	apiVersion  string
+	guard       *writeGuard
+	shop        *shopCache
+}
+
+// shopCache remembers the domain of the shop so that urls can be built without
+// looking up the shop every time.
+type shopCache struct {
+	mu     sync.Mutex
+	domain string
+}
+
+// writeGuard remembers if the theme was confirmed to be unpublished so that the
+// theme is only looked up before the first write.
+type writeGuard struct {
+	mu      sync.Mutex
+	checked bool
+	err     error
+}
+
+// ProgressReporter is notified every time an asset has been listed or downloaded
+// so that the progress of long running operations can be displayed. The index
+// starts at 1 and total is the amount of assets being processed.
+type ProgressReporter interface {
+	OnAsset(key string, index, total int)
 }
 
 // NewClient will build a new theme client from a configuration and a theme event
 // channel. The channel is used for logging all events. The configuration specifies how
 // the client will behave.
 func NewClient(e *env.Env) (Client, error) {
-	filter, err := file.NewFilter(e.Directory, e.IgnoredFiles, e.Ignores)
+	filter, err := file.NewEnvFilter(e)
 	if err != nil {
 		return Client{}, err
 	}
+	filter = filter.WithIncludes(e.Includes)
+
+	maxRetries := e.MaxRetries
+	if maxRetries < 0 {
+		// env.NoRetries
+		maxRetries = 0
+	}
 
 	http, err := httpify.NewClient(httpify.Params{
-		Domain:   e.Domain,
-		Password: e.Password,
-		Proxy:    e.Proxy,
-		Timeout:  e.Timeout,
-		APILimit: shopifyAPILimit,
+		Domain:     e.Domain,
+		Password:   e.Password,
+		APIKey:     e.APIKey,
+		AuthMode:   e.AuthMode,
+		Proxy:      e.Proxy,
+		Timeout:    e.Timeout,
+		APILimit:   shopifyAPILimit,
+		MaxRetries: maxRetries,
+		RetryDelay: e.RetryDelay,
+
+		ConnectTimeout: e.ConnectTimeout,
+		ReadTimeout:    e.ReadTimeout,
+		WriteTimeout:   e.WriteTimeout,
+
+		UserAgentSuffix: e.UserAgentSuffix,
+
+		MaxIdleConnsPerHost: e.MaxIdleConns,
+		IdleConnTimeout:     e.IdleConnTimeout,
+		DisableHTTP2:        e.DisableHTTP2,
+
+		CAFile:             e.CAFile,
+		InsecureSkipVerify: e.InsecureSkipVerify,
 	})
 	if err != nil {
 		return Client{}, err
 	}
 
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	maxBody := int64(e.MaxResponseBytes)
+	if maxBody <= 0 {
+		maxBody = int64(defaultMaxResponseBytes)
+	}
+
+	retryKeys := map[string]bool{}
+	keys := e.RetryKeys
+	if len(keys) == 0 {
+		keys = defaultRetryKeys
+	}
+	for _, key := range keys {
+		retryKeys[key] = true
+	}
+
+	var guard *writeGuard
+	if e.RequireUnpublished {
+		guard = &writeGuard{}
+	}
+
 	return Client{
-		themeID: e.ThemeID,
-		http:    http,
-		filter:  filter,
+		themeID:     e.ThemeID,
+		domain:      adminDomain(e.Domain),
+		http:        http,
+		filter:      filter,
+		batchSize:   batchSize,
+		concurrency: concurrency,
+		maxBody:     maxBody,
+		generated:   e.ListGenerated,
+		validate:    e.ValidateLiquid,
+		retryKeys:   retryKeys,
+		retryDelay:  validationRetryDelay,
+		lockedDelay: lockedRetryDelay,
+		lockedWait:  lockedTimeout,
+		pollDelay:   processingPollDelay,
+		cache:       newAssetCache(e.CacheDir, e.Domain, e.ThemeID),
+		dryRun:      e.DryRun,
+		apiVersion:  e.APIVersion,
+		log:         colors.ColorStdOut,
+		guard:       guard,
+		shop:        &shopCache{},
 	}, nil
 }
 
-// GetShop will return information for the shop you are working on
+// SetProgressReporter will set the reporter that is notified of the progress
+// of listing and downloading assets. A nil reporter disables reporting.
+func (c *Client) SetProgressReporter(reporter ProgressReporter) {
+	c.progress = reporter
+}
+
+// SetLogger will set the logger used for messages like the changes that would be
+// made in a dry run.
+func (c *Client) SetLogger(logger *log.Logger) {
+	c.log = logger
+}
+
+// APIUtilization will return how much of the shop's api call limit was in use after
+// the last request, from 0 to 1.
+func (c Client) APIUtilization() float64 {
+	if reporter, ok := c.http.(utilizationReporter); ok {
+		return reporter.Utilization()
+	}
+	return 0
+}
+
+// SetRetryObserver will set a function that is notified every time a request is
+// retried and once a retried request has finished, so that unreliable connections
+// can be reported.
+func (c *Client) SetRetryObserver(observer func(httpify.RetryEvent)) {
+	if reporter, ok := c.http.(retryReporter); ok {
+		reporter.SetRetryObserver(observer)
+	}
+}
+
+// SetRequestObserver will set a function that is notified after every request with
+// the metadata of the response, which is useful for debugging and for measuring
+// how long requests take and how much data they transfer. Nothing is measured if
+// the observer is nil.
+func (c *Client) SetRequestObserver(observer func(httpify.RequestEvent)) {
+	if reporter, ok := c.http.(requestReporter); ok {
+		reporter.SetRequestObserver(observer)
+	}
+}
+
+// SetLimiter will make the client share the limiter with other clients so that all
+// of them together stay within one rate and concurrency budget, for example when
+// watching and uploading at the same time in one process. It should be set before
+// the client is used.
+func (c *Client) SetLimiter(limiter *ratelimiter.Limiter) {
+	if setter, ok := c.http.(limiterSetter); ok {
+		setter.SetLimiter(limiter)
+	}
+}
+
+func (c Client) reportProgress(key string, index, total int) {
+	if c.progress != nil {
+		c.progress.OnAsset(key, index, total)
+	}
+}
+
+// GetShop will return information for the shop you are working on like its domains,
+// plan, currency and timezone.
 func (c Client) GetShop() (Shop, error) {
-	resp, err := c.http.Get("/meta.json")
+	return c.GetShopContext(context.Background())
+}
+
+// GetShopContext is the same as GetShop but the request is bound to the context so
+// it can be cancelled.
+func (c Client) GetShopContext(ctx context.Context) (Shop, error) {
+	resp, err := c.http.Get(ctx, c.adminPath("/shop.json"))
 	if err != nil {
 		return Shop{}, err
 	} else if resp.StatusCode == 404 {
-		return Shop{}, ErrShopDomainNotFound
+		return Shop{}, newAPIError(resp, ErrShopDomainNotFound)
 	}
 
-	var shop Shop
-	if err := unmarshalResponse(resp.Body, &shop); err != nil {
+	var r shopResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
 		return Shop{}, err
 	}
 
-	return shop, nil
+	return r.Shop, nil
+}
+
+// PreviewURL will return the url to preview the theme on the shop's primary
+// domain. The shop is only looked up the first time a url is built.
+func (c Client) PreviewURL(themeID int64) (string, error) {
+	return c.PreviewURLContext(context.Background(), themeID)
+}
+
+// PreviewURLContext is the same as PreviewURL but the request is bound to the
+// context so it can be cancelled.
+func (c Client) PreviewURLContext(ctx context.Context, themeID int64) (string, error) {
+	domain, err := c.shopDomain(ctx)
+	if err != nil {
+		return "", err
+	}
+	return ThemePreviewURL(domain, themeID), nil
+}
+
+// ThemePreviewURL will return the url to preview the theme on the domain, which may
+// be given as a url like https://my-shop.myshopify.com/
+func ThemePreviewURL(domain string, themeID int64) string {
+	return fmt.Sprintf("https://%s/?preview_theme_id=%d", adminDomain(domain), themeID)
+}
+
+// EditorURL will return the url of the theme editor for the theme. The admin is
+// served from the domain the client was configured with, which is the shop's
+// myshopify domain, rather than its primary domain.
+func (c Client) EditorURL(themeID int64) string {
+	return fmt.Sprintf("https://%s/admin/themes/%d/editor", c.domain, themeID)
+}
+
+// adminDomain will return the host of the configured domain, which may have been
+// given as a url like https://my-shop.myshopify.com/
+func adminDomain(domain string) string {
+	domain = strings.TrimSpace(domain)
+	if u, err := url.Parse(domain); err == nil && u.Host != "" {
+		domain = u.Host
+	}
+	return strings.ToLower(strings.TrimRight(domain, "/"))
+}
+
+// shopDomain will return the primary domain of the shop, or its myshopify domain
+// if it does not have one.
+func (c Client) shopDomain(ctx context.Context) (string, error) {
+	if c.shop != nil {
+		c.shop.mu.Lock()
+		defer c.shop.mu.Unlock()
+		if c.shop.domain != "" {
+			return c.shop.domain, nil
+		}
+	}
+
+	shop, err := c.GetShopContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	domain := shop.Domain
+	if domain == "" {
+		domain = shop.MyshopifyDomain
+	}
+	if domain == "" {
+		return "", ErrMalformedResponse
+	}
+
+	if c.shop != nil {
+		c.shop.domain = domain
+	}
+	return domain, nil
 }
 
 // Themes will return all the available themes on a domain.
 func (c Client) Themes() ([]Theme, error) {
-	resp, err := c.http.Get("/admin/themes.json")
+	return c.ThemesContext(context.Background())
+}
+
+// ThemesContext is the same as Themes but the request is bound to the context so it
+// can be cancelled.
+func (c Client) ThemesContext(ctx context.Context) ([]Theme, error) {
+	resp, err := c.http.Get(ctx, c.adminPath("/themes.json"))
 	if err != nil {
 		return []Theme{}, err
 	}
 
 	var r themesResponse
-	if err := unmarshalResponse(resp.Body, &r); err != nil {
+	if err := c.unmarshalResponse(resp, &r); err != nil {
 		return []Theme{}, err
 	}
 
 	return r.Themes, nil
 }
 
+// FilterThemes will return the themes on a domain that match the filter.
+func (c Client) FilterThemes(filter ThemeFilter) ([]Theme, error) {
+	return c.FilterThemesContext(context.Background(), filter)
+}
+
+// FilterThemesContext is the same as FilterThemes but the request is bound to the
+// context so it can be cancelled.
+func (c Client) FilterThemesContext(ctx context.Context, filter ThemeFilter) ([]Theme, error) {
+	themes, err := c.ThemesContext(ctx)
+	if err != nil {
+		return []Theme{}, err
+	}
+
+	matches := []Theme{}
+	for _, theme := range themes {
+		if filter.Match(theme) {
+			matches = append(matches, theme)
+		}
+	}
+	return matches, nil
+}
+
 // CreateNewTheme will create a unpublished new theme on your shopify store and then
 // set the theme id on this theme client to the one recently created.
 func (c *Client) CreateNewTheme(name, zipLocation string) (theme Theme, err error) {
+	return c.CreateNewThemeContext(context.Background(), name, zipLocation)
+}
+
+// CreateNewThemeContext is the same as CreateNewTheme but the request is bound to
+// the context so it can be cancelled.
+func (c *Client) CreateNewThemeContext(ctx context.Context, name, zipLocation string) (theme Theme, err error) {
 	if zipLocation == "" {
 		return Theme{}, ErrZipPathRequired
 	}
 
-	resp, err := c.http.Post("/admin/themes.json", map[string]interface{}{"theme": Theme{Name: name, Source: zipLocation}})
+	return c.createNewTheme(ctx, Theme{Name: name, Source: zipLocation})
+}
+
+// CreateDevelopmentTheme will create a new theme with the development role on your
+// shopify store and then set the theme id on this theme client to the one recently
+// created. Development themes are hidden from the theme library and cannot be
+// published. Shopify deletes them once they have not been used for a while, the
+// api does not report when that will be so it is not part of the theme info.
+// The zip location is optional, without it the theme is created empty.
+func (c *Client) CreateDevelopmentTheme(name, zipLocation string) (Theme, error) {
+	return c.CreateDevelopmentThemeContext(context.Background(), name, zipLocation)
+}
+
+// CreateDevelopmentThemeContext is the same as CreateDevelopmentTheme but the
+// request is bound to the context so it can be cancelled.
+func (c *Client) CreateDevelopmentThemeContext(ctx context.Context, name, zipLocation string) (Theme, error) {
+	return c.createNewTheme(ctx, Theme{Name: name, Source: zipLocation, Role: "development"})
+}
+
+// createNewTheme will create the theme and point the client at it
+func (c *Client) createNewTheme(ctx context.Context, theme Theme) (Theme, error) {
+	theme, err := c.createTheme(ctx, theme)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	*c = c.forTheme(theme.ID)
+	return theme, nil
+}
+
+// forTheme will return a copy of the client that works on another theme, with the
+// asset cache of that theme so that the cache of the configured theme is kept.
+func (c Client) forTheme(themeID int64) Client {
+	c.themeID = fmt.Sprintf("%d", themeID)
+	c.cache = c.cache.forTheme(c.themeID)
+	return c
+}
+
+func (c Client) createTheme(ctx context.Context, theme Theme) (Theme, error) {
+	resp, err := c.http.Post(ctx, c.adminPath("/themes.json"), map[string]interface{}{"theme": theme})
 	if err != nil {
 		return Theme{}, err
 	}
 
 	var r themeResponse
-	if err = unmarshalResponse(resp.Body, &r); err != nil {
+	if err = c.unmarshalResponse(resp, &r); err != nil {
 		return Theme{}, err
 	}
 
 	if len(r.Errors) > 0 {
-		return Theme{}, errors.New(toSentence(toMessages(r.Errors)))
+		return Theme{}, newAPIMessagesError(resp, r.Errors)
 	}
 
-	c.themeID = fmt.Sprintf("%d", r.Theme.ID)
-	return r.Theme, err
+	return r.Theme, nil
 }
 
-// GetInfo will return the theme data for the clients theme.
-func (c Client) GetInfo() (Theme, error) {
-	if c.themeID == "" {
-		return Theme{}, ErrInfoWithoutThemeID
-	}
+// DuplicateTheme will create a new unpublished theme and copy every asset of the
+// source theme into it. The assets are downloaded from the source theme, reporting
+// progress as they are, and then uploaded in batches. Assets that are generated
+// from a .liquid file are not copied because they are generated again in the new
+// theme. Ignore and include patterns from the config do not apply to the copy.
+// If the copy fails the new theme is still returned along with the error so that
+// it can be removed or the copy retried.
+func (c Client) DuplicateTheme(sourceID int64, name string) (Theme, error) {
+	return c.DuplicateThemeContext(context.Background(), sourceID, name)
+}
 
-	resp, err := c.http.Get(fmt.Sprintf("/admin/themes/%s.json", c.themeID))
+// DuplicateThemeContext is the same as DuplicateTheme but the requests are bound to
+// the context so they can be cancelled.
+func (c Client) DuplicateThemeContext(ctx context.Context, sourceID int64, name string) (Theme, error) {
+	source := c.forTheme(sourceID)
+
+	listed, err := source.listAssets(ctx, "key")
 	if err != nil {
 		return Theme{}, err
-	} else if resp.StatusCode == 404 {
-		return Theme{}, ErrThemeNotFound
 	}
 
-	var r themeResponse
-	if err := unmarshalResponse(resp.Body, &r); err != nil {
-		return Theme{}, err
+	keys := []string{}
+	sort.Slice(listed, func(i, j int) bool { return listed[i].Key < listed[j].Key })
+	for index, asset := range listed {
+		if index == len(listed)-1 || listed[index+1].Key != asset.Key+".liquid" {
+			keys = append(keys, asset.Key)
+		}
 	}
 
-	return r.Theme, nil
-}
+	if c.dryRun {
+		c.log.Printf("[dry-run] would duplicate theme %d as %s with %d assets", sourceID, name, len(keys))
+		return Theme{}, nil
+	}
 
-// GetAllAssets will return a slice of remote assets from the shopify servers. The
-// assets are sorted and any ignored files based on your config are filtered out.
-// The assets returned will not have any data, only ID and filenames. This is because
-// fetching all the assets at one time is not a good idea.
-func (c Client) GetAllAssets() ([]string, error) {
-	resp, err := c.http.Get(c.assetPath(map[string]string{"fields": "key"}))
+	downloaded, err := source.DownloadAssetsContext(ctx, keys, 0)
 	if err != nil {
-		return []string{}, err
-	} else if resp.StatusCode == 404 {
-		return []string{}, ErrThemeNotFound
+		return Theme{}, err
 	}
 
-	var r assetsResponse
-	if err := unmarshalResponse(resp.Body, &r); err != nil {
-		return []string{}, err
+	theme, err := c.createTheme(ctx, Theme{Name: name, Role: "unpublished"})
+	if err != nil {
+		return Theme{}, err
 	}
 
-	filteredAssets := []string{}
-	sort.Slice(r.Assets, func(i, j int) bool { return r.Assets[i].Key < r.Assets[j].Key })
-	for index, asset := range r.Assets {
-		if !c.filter.Match(asset.Key) && (index == len(r.Assets)-1 || r.Assets[index+1].Key != asset.Key+".liquid") {
-			filteredAssets = append(filteredAssets, asset.Key)
-		}
+	assets := make([]Asset, 0, len(keys))
+	for _, key := range keys {
+		asset := downloaded[key]
+		assets = append(assets, Asset{Key: asset.Key, Value: asset.Value, Attachment: asset.Attachment})
 	}
 
-	return filteredAssets, nil
+	target := c.forTheme(theme.ID)
+	target.guard = nil
+	return theme, target.BulkUpdateAssetsContext(ctx, assets)
 }
 
-// GetAsset will fetch a single remote asset from the remote shopify servers.
-func (c Client) GetAsset(filename string) (Asset, error) {
-	resp, err := c.http.Get(c.assetPath(map[string]string{"asset[key]": filename}))
-	if err != nil {
-		return Asset{}, err
-	} else if resp.StatusCode == 404 {
-		return Asset{}, ErrNotPartOfTheme
-	}
+// GetInfo will return the theme data for the clients theme.
+func (c Client) GetInfo() (Theme, error) {
+	return c.GetInfoContext(context.Background())
+}
 
-	var r assetResponse
-	if err := unmarshalResponse(resp.Body, &r); err != nil {
-		return Asset{}, err
+// GetInfoContext is the same as GetInfo but the request is bound to the context so
+// it can be cancelled.
+func (c Client) GetInfoContext(ctx context.Context) (Theme, error) {
+	if c.themeID == "" {
+		return Theme{}, ErrInfoWithoutThemeID
 	}
+	return c.getTheme(ctx, c.themeID)
+}
 
-	return r.Asset, nil
+// WaitForProcessing will check the theme until shopify is done processing it, for
+// example after it was created from a zip, waiting longer between every check. If
+// the theme is still processing once the timeout has passed an error wrapping
+// ErrThemeProcessing is returned so that a theme that never becomes ready does not
+// wait forever.
+func (c Client) WaitForProcessing(themeID int64, timeout time.Duration) error {
+	return c.WaitForProcessingContext(context.Background(), themeID, timeout)
 }
 
-// CreateAsset will take an asset and will return  when the asset has been created.
-// If there was an error, in the request then error will be defined otherwise the
-//response will have the appropropriate data for usage.
-func (c Client) CreateAsset(asset Asset) error {
-	return c.UpdateAsset(asset)
+// WaitForProcessingContext is the same as WaitForProcessing but the requests are
+// bound to the context so they can be cancelled.
+func (c Client) WaitForProcessingContext(ctx context.Context, themeID int64, timeout time.Duration) error {
+	id := fmt.Sprintf("%d", themeID)
+	deadline := time.Now().Add(timeout)
+	wait := c.pollDelay
+	for {
+		theme, err := c.getTheme(ctx, id)
+		if err != nil {
+			return err
+		} else if !theme.Processing {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w after %s", ErrThemeProcessing, timeout)
+		} else if wait > remaining {
+			wait = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > maxProcessingPollDelay {
+			wait = maxProcessingPollDelay
+		}
+	}
 }
 
-// UpdateAsset will take an asset and will return  when the asset has been updated.
-// If there was an error, in the request then error will be defined otherwise the
-//response will have the appropropriate data for usage.
-func (c Client) UpdateAsset(asset Asset) error {
-	resp, err := c.http.Put(c.assetPath(map[string]string{}), map[string]Asset{"asset": asset})
+// Publish will set the theme's role to main so that it becomes the live theme
+// on the shop. Publishing the theme that is already live does nothing and a
+// development theme cannot be published.
+func (c Client) Publish(themeID int64) error {
+	return c.PublishContext(context.Background(), themeID)
+}
+
+// PublishContext is the same as Publish but the request is bound to the context so
+// it can be cancelled.
+func (c Client) PublishContext(ctx context.Context, themeID int64) error {
+	id := fmt.Sprintf("%d", themeID)
+	theme, err := c.getTheme(ctx, id)
 	if err != nil {
 		return err
-	} else if resp.StatusCode == 404 {
-		return ErrNotPartOfTheme
+	} else if theme.Role == "main" {
+		return nil
+	} else if theme.Role == "development" {
+		return ErrPublishDevelopment
 	}
 
-	var r assetResponse
-	if err := unmarshalResponse(resp.Body, &r); err != nil {
+	resp, err := c.whileLocked(ctx, func() (*http.Response, error) {
+		return c.http.Put(ctx, c.themePath(id), map[string]map[string]string{"theme": {"role": "main"}})
+	})
+	if err != nil {
 		return err
+	} else if resp.StatusCode == 404 {
+		return newAPIError(resp, ErrThemeNotFound)
 	}
 
-	if len(r.Errors) > 0 {
-		if _, ok := r.Errors["asset"]; ok {
-			if resp.StatusCode == 422 && strings.Contains(r.Errors["asset"][0], "Cannot overwrite generated asset") {
-				// No need to check the error because if it fails then remove will be tried again.
-				c.DeleteAsset(Asset{Key: asset.Key + ".liquid"})
-				return c.UpdateAsset(asset)
-			}
-			return errors.New(toSentence(r.Errors["asset"]))
-		}
-		return errors.New(toSentence(toMessages(r.Errors)))
+	var r themeResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return err
+	} else if len(r.Errors) > 0 {
+		return newAPIMessagesError(resp, r.Errors)
+	} else if r.Theme.Role != "main" {
+		return newAPIError(resp, ErrPublishFailed)
 	}
 
 	return nil
 }
 
-// DeleteAsset will take an asset and will return  when the asset has been deleted.
-// If there was an error, in the request then error will be defined otherwise the
-//response will have the appropropriate data for usage.
-func (c Client) DeleteAsset(asset Asset) error {
-	resp, err := c.http.Delete(c.assetPath(map[string]string{"asset[key]": asset.Key}))
+// DeleteTheme will remove the theme from the shop. The main theme cannot be removed
+// so another theme has to be published before it can be deleted.
+func (c Client) DeleteTheme(themeID int64) error {
+	return c.DeleteThemeContext(context.Background(), themeID)
+}
+
+// DeleteThemeContext is the same as DeleteTheme but the request is bound to the
+// context so it can be cancelled.
+func (c Client) DeleteThemeContext(ctx context.Context, themeID int64) error {
+	id := fmt.Sprintf("%d", themeID)
+	theme, err := c.getTheme(ctx, id)
 	if err != nil {
 		return err
-	} else if resp.StatusCode == 403 {
-		return ErrCriticalFile
-	} else if resp.StatusCode == 404 {
-		return ErrNotPartOfTheme
-	} else if resp.StatusCode == 406 {
-		return ErrMissingAssetName
+	} else if theme.Role == "main" {
+		return ErrDeleteMainTheme
 	}
 
-	var r assetResponse
-	if err := unmarshalResponse(resp.Body, &r); err != nil {
+	resp, err := c.http.Delete(ctx, c.themePath(id))
+	if err != nil {
 		return err
+	} else if resp.StatusCode == 403 {
+		return newAPIError(resp, ErrDeleteMainTheme)
+	} else if resp.StatusCode == 404 {
+		return newAPIError(resp, ErrThemeNotFound)
 	}
 
-	if len(r.Errors) > 0 {
-		return errors.New(toSentence(toMessages(r.Errors)))
+	var r themeResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return err
+	} else if len(r.Errors) > 0 {
+		return newAPIMessagesError(resp, r.Errors)
 	}
 
 	return nil
 }
 
-func (c Client) assetPath(query map[string]string) string {
-	formatted := "/admin/assets.json"
-	if c.themeID != "" {
-		formatted = fmt.Sprintf("/admin/themes/%s/assets.json", c.themeID)
+func (c Client) getTheme(ctx context.Context, id string) (Theme, error) {
+	resp, err := c.http.Get(ctx, c.themePath(id))
+	if err != nil {
+		return Theme{}, err
+	} else if resp.StatusCode == 404 {
+		return Theme{}, newAPIError(resp, ErrThemeNotFound)
 	}
 
-	if len(query) > 0 {
-		queryParams := url.Values{}
-		for key, value := range query {
-			queryParams.Set(key, value)
-		}
-		formatted = fmt.Sprintf("%s?%s", formatted, queryParams.Encode())
+	var r themeResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return Theme{}, err
 	}
 
-	return formatted
+	return r.Theme, nil
 }
 
-func unmarshalResponse(body io.ReadCloser, data interface{}) error {
-	reqBody, err := ioutil.ReadAll(body)
+// DiffThemes will compare the assets of two themes, for example to report what
+// publishing a theme would change. Assets are compared by the checksums shopify
+// reports and only the assets without a checksum are downloaded, reporting
+// progress as they are. Assets that are generated from a .liquid file are left out
+// because they differ when their source does. Ignore and include patterns from the
+// config do not apply.
+func (c Client) DiffThemes(idA, idB int64) (ThemeDiff, error) {
+	return c.DiffThemesContext(context.Background(), idA, idB)
+}
+
+// DiffThemesContext is the same as DiffThemes but the requests are bound to the
+// context so they can be cancelled.
+func (c Client) DiffThemesContext(ctx context.Context, idA, idB int64) (ThemeDiff, error) {
+	themeA, themeB := c.forTheme(idA), c.forTheme(idB)
+
+	listedA, err := themeA.listAssets(ctx, "key,checksum")
 	if err != nil {
-		return ErrMalformedResponse
+		return ThemeDiff{}, err
 	}
-	err = body.Close()
+	listedB, err := themeB.listAssets(ctx, "key,checksum")
 	if err != nil {
-		return err
+		return ThemeDiff{}, err
 	}
-	var re reqErr
-	mainErr := json.Unmarshal(reqBody, data)
-	basicErr := json.Unmarshal(reqBody, &re)
-	if mainErr != nil && basicErr != nil {
-		return ErrMalformedResponse
+	assetsA, assetsB := withoutGenerated(listedA), withoutGenerated(listedB)
+
+	diff := ThemeDiff{OnlyInA: []string{}, OnlyInB: []string{}, Differing: []string{}, Identical: []string{}}
+	shared, missingA, missingB := []string{}, []string{}, []string{}
+	for key, asset := range assetsA {
+		other, found := assetsB[key]
+		if !found {
+			diff.OnlyInA = append(diff.OnlyInA, key)
+			continue
+		}
+		shared = append(shared, key)
+		if asset.Checksum == "" {
+			missingA = append(missingA, key)
+		}
+		if other.Checksum == "" {
+			missingB = append(missingB, key)
+		}
 	}
-	if len(re.Errors) > 0 {
-		return errors.New(re.Errors)
+	for key := range assetsB {
+		if _, found := assetsA[key]; !found {
+			diff.OnlyInB = append(diff.OnlyInB, key)
+		}
 	}
-	return nil
-}
 
-func toMessages(a map[string][]string) []string {
-	out := []string{}
-	for attr, errs := range a {
-		for _, err := range errs {
-			out = append(out, attr+" "+err)
+	for _, fetch := range []struct {
+		theme  Client
+		keys   []string
+		assets map[string]Asset
+	}{{themeA, missingA, assetsA}, {themeB, missingB, assetsB}} {
+		if len(fetch.keys) == 0 {
+			continue
+		}
+		downloaded, err := fetch.theme.DownloadAssetsContext(ctx, fetch.keys, 0)
+		if err != nil {
+			return ThemeDiff{}, err
+		}
+		for key, asset := range downloaded {
+			// an undecodable asset gets no checksum so that it is always seen as differing
+			asset.Checksum, _ = asset.ComputeChecksum()
+			fetch.assets[key] = asset
 		}
 	}
-	return out
-}
 
-func toSentence(a []string) string {
-	switch len(a) {
-	case 0:
-		return ""
-	case 1:
-		return a[0]
-	case 2:
-		return a[0] + " and " + a[1]
+	for _, key := range shared {
+		if checksum := assetsA[key].Checksum; checksum != "" && checksum == assetsB[key].Checksum {
+			diff.Identical = append(diff.Identical, key)
+		} else {
+			diff.Differing = append(diff.Differing, key)
+		}
 	}
-	return strings.Join(a[:len(a)-1], ", ") + ", and " + a[len(a)-1]
+
+	sort.Strings(diff.OnlyInA)
+	sort.Strings(diff.OnlyInB)
+	sort.Strings(diff.Differing)
+	sort.Strings(diff.Identical)
+	return diff, nil
+}
+
+// GetAllAssets will return a slice of remote assets from the shopify servers. The
+// assets are sorted and any ignored files based on your config are filtered out.
+// If includes are configured only the assets matching them are returned. Assets
+// generated from a .liquid file are left out unless list_generated is set.
+// The assets returned will not have any data, only ID and filenames. This is because
+// fetching all the assets at one time is not a good idea. If the assets are paginated
+// every page will be fetched before the assets are filtered.
+func (c Client) GetAllAssets() ([]string, error) {
+	return c.GetAllAssetsContext(context.Background())
+}
+
+// GetAllAssetsContext is the same as GetAllAssets but the request is bound to the
+// context so it can be cancelled.
+func (c Client) GetAllAssetsContext(ctx context.Context) ([]string, error) {
+	assets, err := c.listAssets(ctx, "key")
+	if err != nil {
+		return []string{}, err
+	}
+	return assetsToFilenames(c.filterListed(assets)), nil
+}
+
+// GetAllAssetsDetailed is the same as GetAllAssets but the assets are returned with
+// all of the metadata shopify lists, like their size, content type, checksum and
+// when they were updated, so that they can be compared without fetching every
+// asset. The assets do not have their contents.
+func (c Client) GetAllAssetsDetailed() ([]Asset, error) {
+	return c.GetAllAssetsDetailedContext(context.Background())
+}
+
+// GetAllAssetsDetailedContext is the same as GetAllAssetsDetailed but the requests
+// are bound to the context so they can be cancelled.
+func (c Client) GetAllAssetsDetailedContext(ctx context.Context) ([]Asset, error) {
+	assets, err := c.listAssets(ctx, "")
+	if err != nil {
+		return []Asset{}, err
+	}
+
+	filtered := c.filterListed(assets)
+	for i := range filtered {
+		filtered[i].Value, filtered[i].Attachment = "", ""
+	}
+	return filtered, nil
+}
+
+// filterListed will sort the listed assets and leave out the ignored ones and the
+// generated ones, reporting the progress for each asset that is kept.
+func (c Client) filterListed(assets []Asset) []Asset {
+	filtered := []Asset{}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Key < assets[j].Key })
+	for index, asset := range assets {
+		if c.filter.Included(asset.Key) && !c.filter.Match(asset.Key) && (c.generated || index == len(assets)-1 || assets[index+1].Key != asset.Key+".liquid") {
+			filtered = append(filtered, asset)
+		}
+	}
+
+	for index, asset := range filtered {
+		c.reportProgress(asset.Key, index+1, len(filtered))
+	}
+	return filtered
+}
+
+// SelectAssets will return the keys that are named by the arguments, in the order
+// of the keys. An argument selects the key it names or every key in the directory
+// it names, so assets selects all of the assets and . selects every key. Arguments
+// may be paths that include the project directory, like theme/snippets/a.liquid
+// when the project directory is theme. Since only the given keys can be selected,
+// passing the keys from FindAssets or GetAllAssets keeps the ignored files out.
+func SelectAssets(root string, keys []string, args ...string) []string {
+	root = filepath.ToSlash(filepath.Clean(root))
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		name := filepath.ToSlash(filepath.Clean(arg))
+		if name == root {
+			name = "."
+		}
+		name = strings.TrimPrefix(name, root+"/")
+		if strings.HasPrefix(name, "../") {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	selected := []string{}
+	for _, key := range keys {
+		for _, name := range names {
+			if name == "." || key == name || strings.HasPrefix(key, name+"/") {
+				selected = append(selected, key)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// GetAssetChecksums will return a map of asset keys to the checksum of their content
+// on the shopify servers. Assets that shopify did not report a checksum for are not
+// included so they should be treated as changed.
+func (c Client) GetAssetChecksums() (map[string]string, error) {
+	return c.GetAssetChecksumsContext(context.Background())
+}
+
+// GetAssetChecksumsContext is the same as GetAssetChecksums but the request is bound
+// to the context so it can be cancelled.
+func (c Client) GetAssetChecksumsContext(ctx context.Context) (map[string]string, error) {
+	checksums := map[string]string{}
+	assets, err := c.listAssets(ctx, "key,checksum")
+	if err != nil {
+		return checksums, err
+	}
+
+	for _, asset := range assets {
+		if asset.Checksum != "" {
+			checksums[asset.Key] = asset.Checksum
+		}
+	}
+
+	return checksums, nil
+}
+
+// GetAssetsSince will return the assets that were updated on shopify after the time
+// given, sorted by when they were updated, so that only the assets changed remotely
+// need to be downloaded. The assets only have their key and updated at populated.
+// Assets that shopify did not report a valid update time for are included, first,
+// since it is not known whether they changed.
+func (c Client) GetAssetsSince(since time.Time) ([]Asset, error) {
+	return c.GetAssetsSinceContext(context.Background(), since)
+}
+
+// GetAssetsSinceContext is the same as GetAssetsSince but the request is bound to
+// the context so it can be cancelled.
+func (c Client) GetAssetsSinceContext(ctx context.Context, since time.Time) ([]Asset, error) {
+	assets, err := c.listAssets(ctx, "key,updated_at")
+	if err != nil {
+		return []Asset{}, err
+	}
+
+	keys := map[string]bool{}
+	for _, asset := range assets {
+		keys[asset.Key] = true
+	}
+
+	changed := []Asset{}
+	for _, asset := range assets {
+		if !c.filter.Included(asset.Key) || c.filter.Match(asset.Key) || (!c.generated && keys[asset.Key+".liquid"]) {
+			continue
+		}
+		updatedAt := parseTimestamp(asset.UpdatedAt)
+		if updatedAt.IsZero() || updatedAt.After(since) {
+			changed = append(changed, asset)
+		}
+	}
+
+	sort.SliceStable(changed, func(i, j int) bool {
+		a, b := parseTimestamp(changed[i].UpdatedAt), parseTimestamp(changed[j].UpdatedAt)
+		if a.Equal(b) {
+			return changed[i].Key < changed[j].Key
+		}
+		return a.Before(b)
+	})
+
+	return changed, nil
+}
+
+// AssetStats will return the amount of assets in the theme and their total size so
+// that the theme limits can be checked before deploying. Ignore patterns are not
+// applied because the limits apply to every file in the theme.
+func (c Client) AssetStats() (AssetStats, error) {
+	return c.AssetStatsContext(context.Background())
+}
+
+// AssetStatsContext is the same as AssetStats but the request is bound to the
+// context so it can be cancelled.
+func (c Client) AssetStatsContext(ctx context.Context) (AssetStats, error) {
+	assets, err := c.listAssets(ctx, "key,size")
+	if err != nil {
+		return AssetStats{}, err
+	}
+
+	stats := AssetStats{Count: len(assets), SizeAvailable: true}
+	for _, asset := range assets {
+		if asset.Size == nil {
+			stats.SizeAvailable = false
+			continue
+		}
+		stats.TotalBytes += *asset.Size
+	}
+
+	return stats, nil
+}
+
+// listAssets will fetch every page of assets with only the requested fields populated,
+// or every field shopify lists if no fields are requested
+func (c Client) listAssets(ctx context.Context, fields string) ([]Asset, error) {
+	query := map[string]string{}
+	if fields != "" {
+		query["fields"] = fields
+	}
+
+	assets := []Asset{}
+	for path := c.assetPath(query); path != ""; {
+		resp, err := c.http.Get(ctx, path)
+		if err != nil {
+			return assets, err
+		} else if resp.StatusCode == 404 {
+			return assets, newAPIError(resp, ErrThemeNotFound)
+		}
+
+		var r assetsResponse
+		if err := c.unmarshalResponse(resp, &r); err != nil {
+			return assets, err
+		}
+
+		assets = append(assets, r.Assets...)
+		path = nextPagePath(resp.Header.Get("Link"))
+	}
+	return assets, nil
+}
+
+// GetAsset will fetch a single remote asset from the remote shopify servers.
+func (c Client) GetAsset(filename string) (Asset, error) {
+	return c.GetAssetContext(context.Background(), filename)
+}
+
+// GetAssetContext is the same as GetAsset but the request is bound to the context so
+// it can be cancelled.
+func (c Client) GetAssetContext(ctx context.Context, filename string) (Asset, error) {
+	resp, err := c.http.Get(ctx, c.assetPath(map[string]string{"asset[key]": filename}))
+	if err != nil {
+		return Asset{}, err
+	} else if resp.StatusCode == 404 {
+		return Asset{}, newAPIError(resp, ErrNotPartOfTheme)
+	}
+
+	var r assetResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return Asset{}, err
+	}
+
+	if r.Asset.ContentType == "" {
+		r.Asset.ContentType = detectContentType(r.Asset)
+	}
+	if c.cache != nil {
+		c.cache.put(r.Asset)
+	}
+
+	return r.Asset, nil
+}
+
+// GetAssetStream will fetch a single remote asset and write its contents to w as
+// they are received, decoding binary attachments on the fly. Unlike GetAsset the
+// asset is never held in memory so it is better suited for large files.
+func (c Client) GetAssetStream(key string, w io.Writer) error {
+	return c.GetAssetStreamContext(context.Background(), key, w)
+}
+
+// GetAssetStreamContext is the same as GetAssetStream but the request is bound to
+// the context so it can be cancelled.
+func (c Client) GetAssetStreamContext(ctx context.Context, key string, w io.Writer) error {
+	resp, err := c.http.Get(ctx, c.assetPath(map[string]string{"asset[key]": key}))
+	if err != nil {
+		return err
+	} else if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return newAPIError(resp, ErrNotPartOfTheme)
+	} else if resp.StatusCode >= 400 {
+		var r assetResponse
+		if err := c.unmarshalResponse(resp, &r); err != nil {
+			return err
+		} else if len(r.Errors) == 0 {
+			return newAPIError(resp, fmt.Errorf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)))
+		}
+		return newAPIMessagesError(resp, r.Errors)
+	}
+	defer resp.Body.Close()
+
+	err = streamAsset(resp.Body, w)
+	if msg, ok := err.(shopifyMessage); ok {
+		return newAPIMessageError(resp, string(msg))
+	} else if err == ErrMalformedResponse {
+		return newAPIError(resp, err)
+	}
+	return err
+}
+
+// GetSettingsSchema will fetch config/settings_schema.json from the theme so that
+// the settings data can be validated against it. If the schema is not a list of
+// setting groups, each with settings that have a type, a *SchemaError is returned.
+func (c Client) GetSettingsSchema() ([]byte, error) {
+	return c.GetSettingsSchemaContext(context.Background())
+}
+
+// GetSettingsSchemaContext is the same as GetSettingsSchema but the request is bound
+// to the context so it can be cancelled.
+func (c Client) GetSettingsSchemaContext(ctx context.Context) ([]byte, error) {
+	asset, err := c.GetAssetContext(ctx, SettingsSchemaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := asset.Bytes()
+	if err != nil {
+		return nil, err
+	} else if err := validateSettingsSchema(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// AssetExists will check if the asset is part of the theme. Only the key of the asset
+// is requested so that large assets do not have to be downloaded.
+func (c Client) AssetExists(key string) (bool, error) {
+	return c.AssetExistsContext(context.Background(), key)
+}
+
+// AssetExistsContext is the same as AssetExists but the request is bound to the
+// context so it can be cancelled.
+func (c Client) AssetExistsContext(ctx context.Context, key string) (bool, error) {
+	resp, err := c.http.Get(ctx, c.assetPath(map[string]string{"asset[key]": key, "fields": "key"}))
+	if err != nil {
+		return false, err
+	} else if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return false, nil
+	}
+
+	var r assetResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return false, err
+	}
+
+	return r.Asset.Key != "", nil
+}
+
+// DownloadAssets will fetch all of the assets for the keys provided using a pool of
+// workers. If concurrency is not positive the concurrency from the config is used.
+// Assets that failed to download are left out of the result and their errors are
+// returned together once every asset has been requested.
+func (c Client) DownloadAssets(keys []string, concurrency int) (map[string]Asset, error) {
+	return c.DownloadAssetsContext(context.Background(), keys, concurrency)
+}
+
+// DownloadAssetsContext is the same as DownloadAssets but the requests are bound to
+// the context so they can be cancelled.
+func (c Client) DownloadAssetsContext(ctx context.Context, keys []string, concurrency int) (map[string]Asset, error) {
+	if concurrency <= 0 {
+		concurrency = c.concurrency
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		queue    = make(chan string)
+		assets   = map[string]Asset{}
+		failures = []string{}
+		done     int
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range queue {
+				asset, err := c.GetAssetContext(ctx, key)
+				mu.Lock()
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%s (%s)", key, err))
+				} else {
+					assets[key] = asset
+				}
+				done++
+				c.reportProgress(key, done, len(keys))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		queue <- key
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return assets, errors.New("could not download " + toSentence(failures))
+	}
+	return assets, nil
+}
+
+// CreateAsset will take an asset and will return  when the asset has been created.
+// If there was an error, in the request then error will be defined otherwise the
+//response will have the appropropriate data for usage.
+func (c Client) CreateAsset(asset Asset) error {
+	return c.CreateAssetContext(context.Background(), asset)
+}
+
+// CreateAssetContext is the same as CreateAsset but the request is bound to the
+// context so it can be cancelled.
+func (c Client) CreateAssetContext(ctx context.Context, asset Asset) error {
+	return c.UpdateAssetContext(ctx, asset)
+}
+
+// UpdateAsset will take an asset and will return  when the asset has been updated.
+// If there was an error, in the request then error will be defined otherwise the
+//response will have the appropropriate data for usage. Assets listed in retry_keys,
+// config/settings_data.json by default, are retried a few times if shopify rejects
+// them because the assets they refer to are not ready yet.
+func (c Client) UpdateAsset(asset Asset) error {
+	return c.UpdateAssetContext(context.Background(), asset)
+}
+
+// UpdateAssetContext is the same as UpdateAsset but the request is bound to the
+// context so it can be cancelled.
+func (c Client) UpdateAssetContext(ctx context.Context, asset Asset) error {
+	if err := c.checkLiquid(asset); err != nil {
+		return err
+	} else if c.dryRun {
+		c.log.Printf("[dry-run] would update %s", asset.Key)
+		return nil
+	} else if err := c.checkWritable(ctx); err != nil {
+		return err
+	}
+	_, err := c.updateAsset(ctx, asset.Key, map[string]Asset{"asset": asset}, 0)
+	return err
+}
+
+// UpdateAssetResult is the same as UpdateAsset but it will return the asset as it
+// was stored by shopify, which includes the PublicURL of files uploaded to assets/.
+// During a dry run the asset is returned as is.
+func (c Client) UpdateAssetResult(asset Asset) (Asset, error) {
+	return c.UpdateAssetResultContext(context.Background(), asset)
+}
+
+// UpdateAssetResultContext is the same as UpdateAssetResult but the request is bound
+// to the context so it can be cancelled.
+func (c Client) UpdateAssetResultContext(ctx context.Context, asset Asset) (Asset, error) {
+	if err := c.checkLiquid(asset); err != nil {
+		return Asset{}, err
+	} else if c.dryRun {
+		c.log.Printf("[dry-run] would update %s", asset.Key)
+		return asset, nil
+	} else if err := c.checkWritable(ctx); err != nil {
+		return Asset{}, err
+	}
+	return c.updateAsset(ctx, asset.Key, map[string]Asset{"asset": asset}, 0)
+}
+
+// UpdateAssetFromReader will update the asset with the contents read from r. Unlike
+// UpdateAsset the contents do not have to be loaded into an asset first, they are
+// encoded as they are read, which helps with large files like videos and fonts.
+// Binary contents are sent as an attachment and text as the value of the asset.
+func (c Client) UpdateAssetFromReader(key string, r io.Reader) error {
+	return c.UpdateAssetFromReaderContext(context.Background(), key, r)
+}
+
+// UpdateAssetFromReaderContext is the same as UpdateAssetFromReader but the request
+// is bound to the context so it can be cancelled.
+func (c Client) UpdateAssetFromReaderContext(ctx context.Context, key string, r io.Reader) error {
+	return c.updateAssetFromReader(ctx, key, func() (json.RawMessage, error) { return encodeAsset(key, r) })
+}
+
+// UpdateAssetFromReaderAs is the same as UpdateAssetFromReader but the contents are
+// sent as an attachment if binary is set and as the value of the asset otherwise
+// instead of being detected. This is for readers like stdin where the key does not
+// tell what the contents are.
+func (c Client) UpdateAssetFromReaderAs(key string, r io.Reader, binary bool) error {
+	return c.UpdateAssetFromReaderAsContext(context.Background(), key, r, binary)
+}
+
+// UpdateAssetFromReaderAsContext is the same as UpdateAssetFromReaderAs but the
+// request is bound to the context so it can be cancelled.
+func (c Client) UpdateAssetFromReaderAsContext(ctx context.Context, key string, r io.Reader, binary bool) error {
+	return c.updateAssetFromReader(ctx, key, func() (json.RawMessage, error) { return encodeAssetAs(key, r, binary) })
+}
+
+func (c Client) updateAssetFromReader(ctx context.Context, key string, encode func() (json.RawMessage, error)) error {
+	if c.dryRun {
+		c.log.Printf("[dry-run] would update %s", key)
+		return nil
+	} else if err := c.checkWritable(ctx); err != nil {
+		return err
+	}
+
+	body, err := encode()
+	if err != nil {
+		return err
+	}
+	_, err = c.updateAsset(ctx, key, body, 0)
+	return err
+}
+
+// UpdateAssetFromURL will update the asset with the contents that shopify downloads
+// from srcURL, so that a file hosted elsewhere, like an image, can be copied into
+// the theme without downloading it first. ErrInvalidSrc is returned if the url is
+// not an http or https url.
+func (c Client) UpdateAssetFromURL(key, srcURL string) error {
+	return c.UpdateAssetFromURLContext(context.Background(), key, srcURL)
+}
+
+// UpdateAssetFromURLContext is the same as UpdateAssetFromURL but the request is
+// bound to the context so it can be cancelled.
+func (c Client) UpdateAssetFromURLContext(ctx context.Context, key, srcURL string) error {
+	if src, err := url.Parse(srcURL); err != nil || (src.Scheme != "http" && src.Scheme != "https") || src.Host == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidSrc, srcURL)
+	} else if c.dryRun {
+		c.log.Printf("[dry-run] would update %s from %s", key, srcURL)
+		return nil
+	} else if err := c.checkWritable(ctx); err != nil {
+		return err
+	}
+	_, err := c.updateAsset(ctx, key, map[string]Asset{"asset": {Key: key, Src: srcURL}}, 0)
+	return err
+}
+
+// whileLocked will send the request again while shopify responds with 423 Locked,
+// which it does for changes to a theme that is still processing, for example right
+// after it was created from a zip. ErrThemeLocked is returned if the theme is still
+// locked once the locked timeout elapsed.
+func (c Client) whileLocked(ctx context.Context, send func() (*http.Response, error)) (*http.Response, error) {
+	deadline := time.Now().Add(c.lockedWait)
+	for {
+		resp, err := send()
+		if err != nil || resp.StatusCode != http.StatusLocked {
+			return resp, err
+		}
+		resp.Body.Close()
+		if time.Now().Add(c.lockedDelay).After(deadline) {
+			return nil, newAPIError(resp, ErrThemeLocked)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.lockedDelay):
+		}
+	}
+}
+
+// checkLiquid will validate the liquid of the asset if validate_liquid is set
+func (c Client) checkLiquid(asset Asset) error {
+	if !c.validate {
+		return nil
+	}
+	return validateLiquid(asset)
+}
+
+func (c Client) updateAsset(ctx context.Context, key string, body interface{}, attempt int) (Asset, error) {
+	resp, err := c.whileLocked(ctx, func() (*http.Response, error) {
+		return c.http.Put(ctx, c.assetPath(map[string]string{}), body)
+	})
+	if err != nil {
+		return Asset{}, err
+	} else if resp.StatusCode == 404 {
+		return Asset{}, newAPIError(resp, ErrNotPartOfTheme)
+	}
+
+	var r assetResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return Asset{}, err
+	}
+
+	if len(r.Errors) > 0 {
+		if _, ok := r.Errors["asset"]; ok {
+			if generated := generatedAssetKeys(key, r.Errors["asset"]); resp.StatusCode == 422 && len(generated) > 0 {
+				if attempt >= maxGeneratedAssetRetries {
+					return Asset{}, newAPIMessageError(resp, fmt.Sprintf("could not overwrite generated asset %s: %s", toSentence(generated), toSentence(r.Errors["asset"])))
+				}
+				for _, key := range generated {
+					// No need to check the error because if it fails the update will fail again.
+					c.DeleteAssetContext(ctx, Asset{Key: key + ".liquid"})
+				}
+				return c.updateAsset(ctx, key, body, attempt+1)
+			} else if resp.StatusCode == 422 && c.retryKeys[key] && attempt < maxValidationRetries {
+				// the assets this asset refers to may not have been processed yet
+				select {
+				case <-ctx.Done():
+					return Asset{}, ctx.Err()
+				case <-time.After(c.retryDelay):
+				}
+				return c.updateAsset(ctx, key, body, attempt+1)
+			}
+			return Asset{}, newAPIMessageError(resp, toSentence(r.Errors["asset"]))
+		}
+		return Asset{}, newAPIMessagesError(resp, r.Errors)
+	}
+
+	return r.Asset, nil
+}
+
+// UpdateAssetIfUnmodified will update the asset only if the remote asset has not been
+// modified after base, which is the updated_at time of the version the changes are
+// based on. ErrAssetConflict is returned if the remote asset is newer. Assets that do
+// not exist yet are created and a zero base updates the asset unconditionally.
+func (c Client) UpdateAssetIfUnmodified(asset Asset, base time.Time) error {
+	return c.UpdateAssetIfUnmodifiedContext(context.Background(), asset, base)
+}
+
+// UpdateAssetIfUnmodifiedContext is the same as UpdateAssetIfUnmodified but the
+// requests are bound to the context so they can be cancelled.
+func (c Client) UpdateAssetIfUnmodifiedContext(ctx context.Context, asset Asset, base time.Time) error {
+	if base.IsZero() {
+		return c.UpdateAssetContext(ctx, asset)
+	}
+
+	resp, err := c.http.Get(ctx, c.assetPath(map[string]string{"asset[key]": asset.Key, "fields": "key,updated_at"}))
+	if err != nil {
+		return err
+	} else if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return c.UpdateAssetContext(ctx, asset)
+	}
+
+	var r assetResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return err
+	}
+
+	if r.Asset.UpdatedAt != "" {
+		updatedAt, err := time.Parse(time.RFC3339, r.Asset.UpdatedAt)
+		if err != nil {
+			return newAPIError(resp, ErrMalformedResponse)
+		} else if updatedAt.After(base) {
+			return newAPIError(resp, ErrAssetConflict)
+		}
+	}
+
+	return c.UpdateAssetContext(ctx, asset)
+}
+
+// BulkUpdateAssets will upload the assets in batches using the bulk assets endpoint
+// which is much faster than updating the assets one at a time. All the batches
+// will be sent even if some assets fail, the returned error will name every asset
+// that could not be updated. The assets are sent in their upload priority so that
+// the files that other files depend on are created first.
+func (c Client) BulkUpdateAssets(assets []Asset) error {
+	return c.BulkUpdateAssetsContext(context.Background(), assets)
+}
+
+// BulkUpdateAssetsContext is the same as BulkUpdateAssets but the request is bound
+// to the context so it can be cancelled.
+func (c Client) BulkUpdateAssetsContext(ctx context.Context, assets []Asset) error {
+	_, err := c.BulkUpdateAssetsResultsContext(ctx, assets)
+	return err
+}
+
+// BulkUpdateAssetsResults is the same as BulkUpdateAssets but it will also return
+// the result of every asset, in the same order as the assets, so that a summary of
+// the upload can be reported. If any of the assets failed the error will be a
+// *BulkError.
+func (c Client) BulkUpdateAssetsResults(assets []Asset) ([]AssetResult, error) {
+	return c.BulkUpdateAssetsResultsContext(context.Background(), assets)
+}
+
+// BulkUpdateAssetsResultsContext is the same as BulkUpdateAssetsResults but the
+// request is bound to the context so it can be cancelled.
+func (c Client) BulkUpdateAssetsResultsContext(ctx context.Context, assets []Asset) ([]AssetResult, error) {
+	results := make([]AssetResult, len(assets))
+	failed := false
+	valid := []int{}
+	for i, asset := range assets {
+		if err := c.checkLiquid(asset); err != nil {
+			results[i] = AssetResult{Key: asset.Key, Error: err}
+			failed = true
+		} else {
+			valid = append(valid, i)
+		}
+	}
+
+	if c.dryRun {
+		for _, i := range valid {
+			c.log.Printf("[dry-run] would update %s", assets[i].Key)
+			results[i] = AssetResult{Key: assets[i].Key, Success: true}
+		}
+		// nothing is sent in a dry run
+		valid = nil
+	} else if err := c.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		return UploadPriority(assets[valid[i]].Key) < UploadPriority(assets[valid[j]].Key)
+	})
+	for start := 0; start < len(valid); start += c.batchSize {
+		end := start + c.batchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		batch := make([]Asset, 0, end-start)
+		for _, i := range valid[start:end] {
+			batch = append(batch, assets[i])
+		}
+		for j, err := range c.updateBatch(ctx, batch) {
+			i := valid[start+j]
+			results[i] = AssetResult{Key: assets[i].Key, Success: err == nil, Error: err}
+			failed = failed || err != nil
+		}
+	}
+
+	if failed {
+		return results, &BulkError{Results: results}
+	}
+	return results, nil
+}
+
+// updateBatch will send a single bulk request and return an error for each asset
+// in the batch, in the same order as the assets.
+func (c Client) updateBatch(ctx context.Context, batch []Asset) []error {
+	errs := make([]error, len(batch))
+	fail := func(err error) []error {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	resp, err := c.whileLocked(ctx, func() (*http.Response, error) {
+		return c.http.Put(ctx, c.bulkAssetPath(), map[string][]Asset{"assets": batch})
+	})
+	if err != nil {
+		return fail(err)
+	} else if resp.StatusCode == 404 {
+		return fail(newAPIError(resp, ErrThemeNotFound))
+	}
+
+	var r bulkAssetsResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return fail(err)
+	} else if len(r.Results) != len(batch) {
+		return fail(newAPIError(resp, ErrMalformedResponse))
+	}
+
+	for i, result := range r.Results {
+		message := ""
+		messages := result.Body.Errors["asset"]
+		if len(messages) > 0 {
+			message = toSentence(messages)
+		} else if len(result.Body.Errors) > 0 {
+			message = toSentence(toMessages(result.Body.Errors))
+		} else if result.Code < 200 || result.Code >= 300 {
+			message = fmt.Sprintf("unexpected response code %d", result.Code)
+		}
+		if message == "" {
+			continue
+		} else if retried, err := c.retryRejected(ctx, batch[i], result.Code, messages); retried {
+			errs[i] = err
+		} else {
+			errs[i] = &APIError{StatusCode: result.Code, ShopifyMessage: message, RequestID: requestID(resp)}
+		}
+	}
+	return errs
+}
+
+// retryRejected will update an asset that shopify rejected in a bulk update on its
+// own, the way updateAsset retries it, if the asset conflicts with a generated asset
+// or is one of the retry keys and failed validation. It reports if the asset was
+// retried so that other rejections keep the error from the bulk update.
+func (c Client) retryRejected(ctx context.Context, asset Asset, code int, messages []string) (bool, error) {
+	if code != 422 || len(messages) == 0 {
+		return false, nil
+	}
+
+	if generated := generatedAssetKeys(asset.Key, messages); len(generated) > 0 {
+		for _, key := range generated {
+			// No need to check the error because if it fails the update will fail again.
+			c.DeleteAssetContext(ctx, Asset{Key: key + ".liquid"})
+		}
+	} else if c.retryKeys[asset.Key] {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-time.After(c.retryDelay):
+		}
+	} else {
+		return false, nil
+	}
+
+	_, err := c.updateAsset(ctx, asset.Key, map[string]Asset{"asset": asset}, 1)
+	return true, err
+}
+
+// DeleteAsset will take an asset and will return  when the asset has been deleted.
+// If there was an error, in the request then error will be defined otherwise the
+//response will have the appropropriate data for usage.
+func (c Client) DeleteAsset(asset Asset) error {
+	return c.DeleteAssetContext(context.Background(), asset)
+}
+
+// DeleteAssetContext is the same as DeleteAsset but the request is bound to the
+// context so it can be cancelled.
+func (c Client) DeleteAssetContext(ctx context.Context, asset Asset) error {
+	if c.dryRun {
+		c.log.Printf("[dry-run] would delete %s", asset.Key)
+		return nil
+	} else if err := c.checkWritable(ctx); err != nil {
+		return err
+	}
+
+	resp, err := c.whileLocked(ctx, func() (*http.Response, error) {
+		return c.http.Delete(ctx, c.assetPath(map[string]string{"asset[key]": asset.Key}))
+	})
+	if err != nil {
+		return err
+	} else if resp.StatusCode == 403 {
+		return newAPIError(resp, ErrCriticalFile)
+	} else if resp.StatusCode == 404 {
+		return newAPIError(resp, ErrNotPartOfTheme)
+	} else if resp.StatusCode == 406 {
+		return newAPIError(resp, ErrMissingAssetName)
+	}
+
+	var r assetResponse
+	if err := c.unmarshalResponse(resp, &r); err != nil {
+		return err
+	}
+
+	if len(r.Errors) > 0 {
+		return newAPIMessagesError(resp, r.Errors)
+	}
+
+	return nil
+}
+
+// BulkDeleteAssets will delete the assets concurrently, using as many requests at
+// a time as the concurrency setting allows while staying in the rate limit. All of
+// the assets are deleted even if some fail, the returned error will name every
+// asset that could not be deleted. The assets a theme requires, like
+// layout/theme.liquid, are never deleted, see BulkDeleteAssetsResults to force it.
+func (c Client) BulkDeleteAssets(keys []string) error {
+	return c.BulkDeleteAssetsContext(context.Background(), keys)
+}
+
+// BulkDeleteAssetsContext is the same as BulkDeleteAssets but the requests are bound
+// to the context so they can be cancelled.
+func (c Client) BulkDeleteAssetsContext(ctx context.Context, keys []string) error {
+	_, err := c.BulkDeleteAssetsResultsContext(ctx, keys, false)
+	return err
+}
+
+// BulkDeleteAssetsResults is the same as BulkDeleteAssets but it will also return
+// the result of every asset, in the same order as the keys. Required assets fail
+// with ErrCriticalFile unless force is set. If any of the assets failed the error
+// will be a *BulkError.
+func (c Client) BulkDeleteAssetsResults(keys []string, force bool) ([]AssetResult, error) {
+	return c.BulkDeleteAssetsResultsContext(context.Background(), keys, force)
+}
+
+// BulkDeleteAssetsResultsContext is the same as BulkDeleteAssetsResults but the
+// requests are bound to the context so they can be cancelled.
+func (c Client) BulkDeleteAssetsResultsContext(ctx context.Context, keys []string, force bool) ([]AssetResult, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		queue   = make(chan int)
+		results = make([]AssetResult, len(keys))
+		failed  = false
+		done    int
+	)
+
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				key := keys[index]
+				var err error
+				if requiredAssets[key] && !force {
+					err = ErrCriticalFile
+				} else {
+					err = c.DeleteAssetContext(ctx, Asset{Key: key})
+				}
+				mu.Lock()
+				results[index] = AssetResult{Key: key, Success: err == nil, Error: err}
+				failed = failed || err != nil
+				done++
+				c.reportProgress(key, done, len(keys))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for index := range keys {
+		queue <- index
+	}
+	close(queue)
+	wg.Wait()
+
+	if failed {
+		return results, &BulkError{Results: results, Action: "delete"}
+	}
+	return results, nil
+}
+
+// Mirror will make the remote theme match the local assets by uploading every local
+// asset and then deleting the remote assets that are not part of the local set. The
+// amount of deleted assets is returned. Ignored files are neither uploaded nor
+// deleted and generated assets are kept if their .liquid source is part of the
+// local set. Nothing is deleted if an upload fails and the assets a theme requires
+// are kept even if they are not part of the local set.
+func (c Client) Mirror(local map[string]Asset) (int, error) {
+	return c.MirrorContext(context.Background(), local)
+}
+
+// MirrorContext is the same as Mirror but the requests are bound to the context so
+// they can be cancelled.
+func (c Client) MirrorContext(ctx context.Context, local map[string]Asset) (int, error) {
+	remote, err := c.GetAllAssetsContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	uploads := []Asset{}
+	for key, asset := range local {
+		if c.filter.Included(key) && !c.filter.Match(key) {
+			uploads = append(uploads, asset)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Key < uploads[j].Key })
+
+	if err := c.BulkUpdateAssetsContext(ctx, uploads); err != nil {
+		return 0, err
+	}
+
+	stale := []string{}
+	for _, key := range remote {
+		if _, ok := local[key]; ok || requiredAssets[key] {
+			continue
+		} else if _, ok := local[key+".liquid"]; ok {
+			continue
+		}
+		stale = append(stale, key)
+	}
+
+	results, err := c.BulkDeleteAssetsResultsContext(ctx, stale, false)
+	deleted := 0
+	for _, result := range results {
+		if result.Success {
+			deleted++
+		}
+	}
+	return deleted, err
+}
+
+// checkWritable will return ErrThemePublished if the client requires an unpublished
+// theme and the theme is the published one. Only a successful lookup is remembered
+// so that a failed request is tried again on the next write.
+func (c Client) checkWritable(ctx context.Context) error {
+	if c.guard == nil {
+		return nil
+	}
+
+	c.guard.mu.Lock()
+	defer c.guard.mu.Unlock()
+	if c.guard.checked {
+		return c.guard.err
+	}
+
+	if c.themeID == "" {
+		c.guard.err = ErrThemePublished
+	} else if theme, err := c.GetInfoContext(ctx); err != nil {
+		return err
+	} else if theme.Role == "main" {
+		c.guard.err = ErrThemePublished
+	}
+	c.guard.checked = true
+	return c.guard.err
+}
+
+// generatedAssetKeys will find the keys of the generated assets that shopify refused
+// to overwrite. The key of the asset being updated is used if a message does not
+// name the generated asset.
+func generatedAssetKeys(key string, messages []string) []string {
+	keys := []string{}
+	for _, message := range messages {
+		if match := generatedAssetRegexp.FindStringSubmatch(message); match != nil {
+			generated := strings.TrimRight(match[1], ".,;")
+			if generated == "" {
+				generated = key
+			}
+			keys = append(keys, generated)
+		}
+	}
+	return keys
+}
+
+// adminPath will prefix the path with the admin api path, including the api version
+// if one was configured.
+func (c Client) adminPath(path string) string {
+	if c.apiVersion == "" {
+		return "/admin" + path
+	}
+	return "/admin/api/" + c.apiVersion + path
+}
+
+func (c Client) themePath(id string) string {
+	return c.adminPath(fmt.Sprintf("/themes/%s.json", id))
+}
+
+func (c Client) bulkAssetPath() string {
+	if c.themeID == "" {
+		return c.adminPath("/assets/bulk.json")
+	}
+	return c.adminPath(fmt.Sprintf("/themes/%s/assets/bulk.json", c.themeID))
+}
+
+func (c Client) assetPath(query map[string]string) string {
+	formatted := c.adminPath("/assets.json")
+	if c.themeID != "" {
+		formatted = c.adminPath(fmt.Sprintf("/themes/%s/assets.json", c.themeID))
+	}
+
+	if len(query) > 0 {
+		queryParams := url.Values{}
+		for key, value := range query {
+			queryParams.Set(key, value)
+		}
+		formatted = fmt.Sprintf("%s?%s", formatted, queryParams.Encode())
+	}
+
+	return formatted
+}
+
+// nextPagePath will find the url with rel="next" in a Link header and return
+// its path so that it can be requested. If there is no next page an empty string
+// is returned.
+func nextPagePath(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) != `rel="next"` {
+				continue
+			}
+			next, err := url.Parse(strings.Trim(strings.TrimSpace(segments[0]), "<>"))
+			if err != nil {
+				return ""
+			}
+			return next.RequestURI()
+		}
+	}
+	return ""
+}
+
+func (c Client) unmarshalResponse(resp *http.Response, data interface{}) error {
+	body := io.Reader(resp.Body)
+	if c.maxBody > 0 {
+		// read one byte past the limit to tell a body of exactly the limit apart
+		// from one that was cut off
+		body = io.LimitReader(resp.Body, c.maxBody+1)
+	}
+	reqBody, err := ioutil.ReadAll(body)
+	if err != nil {
+		resp.Body.Close()
+		return newAPIError(resp, ErrMalformedResponse)
+	} else if c.maxBody > 0 && int64(len(reqBody)) > c.maxBody {
+		resp.Body.Close()
+		return newAPIError(resp, fmt.Errorf("%w, the body is over the limit of %d bytes", ErrResponseTooLarge, c.maxBody))
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	var re reqErr
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		// the body only has a generic message, like Not Found, that would hide
+		// that the password is the problem
+		json.Unmarshal(reqBody, &re)
+		err := ErrUnauthorized
+		if re.Errors != "" {
+			err = fmt.Errorf("%w (%s)", ErrUnauthorized, re.Errors)
+		}
+		return &APIError{StatusCode: resp.StatusCode, ShopifyMessage: re.Errors, RequestID: requestID(resp), Err: err}
+	}
+	mainErr := json.Unmarshal(reqBody, data)
+	basicErr := json.Unmarshal(reqBody, &re)
+	if mainErr != nil && basicErr != nil {
+		return newAPIError(resp, newParseError(reqBody, data, mainErr))
+	}
+	if len(re.Errors) > 0 {
+		return newAPIMessageError(resp, re.Errors)
+	}
+	if _, ok := mainErr.(*json.UnmarshalTypeError); ok {
+		return newAPIError(resp, newParseError(reqBody, data, mainErr))
+	}
+	return nil
+}
+
+// toMessages will prefix every error with its attribute. Attributes are sorted so
+// that the messages are always in the same order.
+func toMessages(a map[string][]string) []string {
+	attrs := make([]string, 0, len(a))
+	for attr := range a {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+
+	out := []string{}
+	for _, attr := range attrs {
+		for _, err := range a[attr] {
+			out = append(out, attr+" "+err)
+		}
+	}
+	return out
+}
+
+// SentenceStyle describes how a list of messages is joined into a sentence.
+type SentenceStyle struct {
+	// Conjunction joins the last message to the rest, like "and".
+	Conjunction string
+	// Separator is placed between the other messages, like ", ".
+	Separator string
+	// SerialComma places the separator before the conjunction when there are more
+	// than two messages.
+	SerialComma bool
+}
+
+var (
+	// EnglishSentence joins messages like "a, b, and c"
+	EnglishSentence = SentenceStyle{Conjunction: "and", Separator: ", ", SerialComma: true}
+	// Sentences is the style used to join lists in error messages. It can be
+	// changed to localize them but it should be set before any client is used.
+	Sentences = EnglishSentence
+)
+
+// Join will join the messages into a single sentence.
+func (style SentenceStyle) Join(a []string) string {
+	switch len(a) {
+	case 0:
+		return ""
+	case 1:
+		return a[0]
+	case 2:
+		return a[0] + " " + style.Conjunction + " " + a[1]
+	}
+	last := " " + style.Conjunction + " "
+	if style.SerialComma {
+		last = strings.TrimRight(style.Separator, " ") + last
+	}
+	return strings.Join(a[:len(a)-1], style.Separator) + last + a[len(a)-1]
+}
+
+func toSentence(a []string) string {
+	return Sentences.Join(a)
 }
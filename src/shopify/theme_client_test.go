@@ -1,12 +1,21 @@
 package shopify
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/Shopify/themekit/src/env"
 	"github.com/Shopify/themekit/src/shopify/_mocks"
@@ -37,6 +46,20 @@ func TestNewThemeClient(t *testing.T) {
 	}
 }
 
+type utilizationAdapter struct {
+	*mocks.HttpAdapter
+}
+
+func (utilizationAdapter) Utilization() float64 { return 0.5 }
+
+func TestThemeClient_APIUtilization(t *testing.T) {
+	client, _ := NewClient(&env.Env{})
+	client.http = new(mocks.HttpAdapter)
+	assert.Equal(t, 0.0, client.APIUtilization())
+	client.http = utilizationAdapter{new(mocks.HttpAdapter)}
+	assert.Equal(t, 0.5, client.APIUtilization())
+}
+
 func TestThemeClient_GetShop(t *testing.T) {
 	testcases := []struct {
 		themeID, resp, resperr, err string
@@ -44,7 +67,7 @@ func TestThemeClient_GetShop(t *testing.T) {
 	}{
 		{resp: `{"errors": "Not Found"}`, code: 200, err: "Not Found"},
 		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
-		{resp: `{"id": 123456}`, code: 200},
+		{resp: `{"shop": {"id": 123456}}`, code: 200},
 		{resp: "{}", code: 404, err: ErrShopDomainNotFound.Error()},
 	}
 
@@ -53,7 +76,7 @@ func TestThemeClient_GetShop(t *testing.T) {
 		client, _ := NewClient(&env.Env{ThemeID: testcase.themeID})
 		client.http = m
 
-		expectation := m.On("Get", "/meta.json")
+		expectation := m.On("Get", mock.Anything, "/admin/shop.json")
 		if testcase.resperr != "" {
 			expectation.Return(nil, errors.New(testcase.resperr))
 		} else {
@@ -75,6 +98,134 @@ func TestThemeClient_GetShop(t *testing.T) {
 	}
 }
 
+func TestThemeClient_PreviewURL(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{Domain: "my-shop.myshopify.com"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/shop.json").Return(jsonResponse(`{"shop":{"domain":"shop.example.com","myshopify_domain":"my-shop.myshopify.com"}}`, 200), nil).Once()
+
+	url, err := client.PreviewURL(123)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://shop.example.com/?preview_theme_id=123", url)
+	url, err = client.PreviewURL(456)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://shop.example.com/?preview_theme_id=456", url)
+	m.AssertNumberOfCalls(t, "Get", 1)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{Domain: "my-shop.myshopify.com"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/shop.json").Return(jsonResponse(`{"shop":{"myshopify_domain":"my-shop.myshopify.com"}}`, 200), nil)
+	url, err = client.PreviewURL(123)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://my-shop.myshopify.com/?preview_theme_id=123", url)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{Domain: "my-shop.myshopify.com"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/shop.json").Return(jsonResponse(`{}`, 404), nil)
+	_, err = client.PreviewURL(123)
+	assert.True(t, errors.Is(err, ErrShopDomainNotFound))
+}
+
+func TestThemePreviewURL(t *testing.T) {
+	assert.Equal(t, "https://my-shop.myshopify.com/?preview_theme_id=123", ThemePreviewURL("my-shop.myshopify.com", 123))
+	assert.Equal(t, "https://my-shop.myshopify.com/?preview_theme_id=123", ThemePreviewURL("https://My-Shop.myshopify.com/", 123))
+}
+
+func TestThemeClient_EditorURL(t *testing.T) {
+	testcases := []struct {
+		domain, expected string
+	}{
+		{domain: "my-shop.myshopify.com", expected: "https://my-shop.myshopify.com/admin/themes/123/editor"},
+		{domain: "My-Shop.myshopify.com/", expected: "https://my-shop.myshopify.com/admin/themes/123/editor"},
+		{domain: "https://my-shop.myshopify.com/", expected: "https://my-shop.myshopify.com/admin/themes/123/editor"},
+		{domain: " my-shop.myshopify.com ", expected: "https://my-shop.myshopify.com/admin/themes/123/editor"},
+	}
+
+	for _, testcase := range testcases {
+		client, _ := NewClient(&env.Env{Domain: testcase.domain})
+		assert.Equal(t, testcase.expected, client.EditorURL(123), testcase.domain)
+	}
+}
+
+func TestThemeClient_Unauthorized(t *testing.T) {
+	for _, code := range []int{401, 403} {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{ThemeID: "123"})
+		client.http = m
+		m.On("Get", mock.Anything, "/admin/shop.json").Return(jsonResponse(`{"errors":"[API] Invalid API key or access token (unrecognized login or wrong password)"}`, code), nil)
+		m.On("Get", mock.Anything, "/admin/themes.json").Return(jsonResponse(`{"errors":"Not Found"}`, code), nil)
+		m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{}`, code), nil)
+		m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything).Return(jsonResponse(`{"errors":"Not Found"}`, code), nil)
+
+		_, err := client.GetShop()
+		assert.True(t, errors.Is(err, ErrUnauthorized), code)
+		assert.False(t, errors.Is(err, ErrShopDomainNotFound))
+		assert.Contains(t, err.Error(), "theme configure")
+		assert.Contains(t, err.Error(), "Invalid API key or access token")
+		var apiErr *APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, code, apiErr.StatusCode)
+		}
+
+		_, err = client.Themes()
+		assert.True(t, errors.Is(err, ErrUnauthorized), code)
+		_, err = client.GetAllAssets()
+		assert.True(t, errors.Is(err, ErrUnauthorized), code)
+		assert.False(t, errors.Is(err, ErrThemeNotFound))
+		assert.EqualError(t, err, ErrUnauthorized.Error())
+		err = client.UpdateAsset(Asset{Key: "assets/app.js", Value: "app()"})
+		assert.True(t, errors.Is(err, ErrUnauthorized), code)
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(jsonResponse(`{}`, 403), nil)
+	err := client.DeleteAsset(Asset{Key: "layout/theme.liquid"})
+	assert.True(t, errors.Is(err, ErrCriticalFile))
+}
+
+func TestThemeClient_GetShopDetails(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{})
+	client.http = m
+
+	m.On("Get", mock.Anything, "/admin/shop.json").Return(jsonResponse(`{"shop": {
+		"id": 690933842,
+		"name": "Super Toys",
+		"email": "steve@supertoys.com",
+		"domain": "shop.supertoys.com",
+		"province": "Ontario",
+		"country": "CA",
+		"city": "Ottawa",
+		"currency": "CAD",
+		"iana_timezone": "America/Toronto",
+		"plan_name": "shopify_plus",
+		"plan_display_name": "Shopify Plus",
+		"myshopify_domain": "supertoys.myshopify.com",
+		"primary_locale": "en",
+		"password_enabled": false
+	}}`, 200), nil)
+
+	shop, err := client.GetShop()
+	assert.Nil(t, err)
+	assert.Equal(t, Shop{
+		ID:              690933842,
+		Name:            "Super Toys",
+		City:            "Ottawa",
+		Country:         "CA",
+		Domain:          "shop.supertoys.com",
+		MyshopifyDomain: "supertoys.myshopify.com",
+		PlanName:        "shopify_plus",
+		PlanDisplayName: "Shopify Plus",
+		Currency:        "CAD",
+		Timezone:        "America/Toronto",
+		PrimaryLocale:   "en",
+	}, shop)
+}
+
 func TestThemeClient_Themes(t *testing.T) {
 	testcases := []struct {
 		resp, resperr, err string
@@ -90,7 +241,7 @@ func TestThemeClient_Themes(t *testing.T) {
 		client, _ := NewClient(&env.Env{})
 		client.http = m
 
-		expectation := m.On("Get", "/admin/themes.json")
+		expectation := m.On("Get", mock.Anything, "/admin/themes.json")
 		if testcase.resperr != "" {
 			expectation.Return(nil, errors.New(testcase.resperr))
 		} else {
@@ -112,6 +263,86 @@ func TestThemeClient_Themes(t *testing.T) {
 	}
 }
 
+func TestThemeClient_FilterThemes(t *testing.T) {
+	testcases := []struct {
+		filter ThemeFilter
+		ids    []int64
+	}{
+		{filter: ThemeFilter{}, ids: []int64{1, 2, 3, 4}},
+		{filter: ThemeFilter{Role: "unpublished"}, ids: []int64{2, 3}},
+		{filter: ThemeFilter{Role: "development"}, ids: []int64{4}},
+		{filter: ThemeFilter{Name: "staging"}, ids: []int64{2, 4}},
+		{filter: ThemeFilter{Role: "unpublished", Name: "Staging"}, ids: []int64{2}},
+		{filter: ThemeFilter{Role: "main", Name: "staging"}, ids: []int64{}},
+		{filter: ThemeFilter{Role: "demo"}, ids: []int64{}},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{})
+		client.http = m
+
+		m.On("Get", mock.Anything, "/admin/themes.json").Return(jsonResponse(`{"themes":[
+			{"id":1,"name":"Debut","role":"main"},
+			{"id":2,"name":"Debut Staging","role":"unpublished"},
+			{"id":3,"name":"Debut Backup","role":"unpublished"},
+			{"id":4,"name":"staging dev","role":"development"}
+		]}`, 200), nil)
+
+		themes, err := client.FilterThemes(testcase.filter)
+		assert.Nil(t, err)
+		ids := []int64{}
+		for _, theme := range themes {
+			ids = append(ids, theme.ID)
+		}
+		assert.Equal(t, testcase.ids, ids, "filter %+v", testcase.filter)
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes.json").Return(nil, errors.New("server error"))
+	_, err := client.FilterThemes(ThemeFilter{Role: "main"})
+	assert.EqualError(t, err, "server error")
+}
+
+func TestThemeClient_ThemeTimestamps(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+
+	m.On("Get", mock.Anything, "/admin/themes.json").Return(jsonResponse(`{"themes":[
+		{"id":123,"name":"preview","role":"unpublished","created_at":"2018-05-01T10:00:00-04:00","updated_at":"2018-06-01T12:30:00-04:00"},
+		{"id":456,"name":"old","created_at":null},
+		{"id":789,"name":"broken","created_at":"yesterday","updated_at":""}
+	]}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123.json").Return(jsonResponse(`{"theme":{"id":123,"created_at":"2018-05-01T10:00:00-04:00","updated_at":"2018-06-01T12:30:00-04:00"}}`, 200), nil)
+
+	created, _ := time.Parse(time.RFC3339, "2018-05-01T10:00:00-04:00")
+	updated, _ := time.Parse(time.RFC3339, "2018-06-01T12:30:00-04:00")
+
+	themes, err := client.Themes()
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(themes)) {
+		assert.Equal(t, "preview", themes[0].Name)
+		assert.Equal(t, "unpublished", themes[0].Role)
+		assert.True(t, created.Equal(themes[0].CreatedAt))
+		assert.True(t, updated.Equal(themes[0].UpdatedAt))
+		assert.True(t, themes[1].CreatedAt.IsZero())
+		assert.True(t, themes[1].UpdatedAt.IsZero())
+		assert.True(t, themes[2].CreatedAt.IsZero())
+		assert.True(t, themes[2].UpdatedAt.IsZero())
+	}
+
+	theme, err := client.GetInfo()
+	assert.Nil(t, err)
+	assert.True(t, created.Equal(theme.CreatedAt))
+	assert.True(t, updated.Equal(theme.UpdatedAt))
+
+	data, _ := json.Marshal(Theme{Name: "new", CreatedAt: created})
+	assert.Equal(t, `{"name":"new"}`, string(data))
+}
+
 func TestThemeClient_CreateNewTheme(t *testing.T) {
 	testcases := []struct {
 		in                 []string
@@ -132,9 +363,9 @@ func TestThemeClient_CreateNewTheme(t *testing.T) {
 		query := map[string]interface{}{"theme": Theme{Name: testcase.in[0], Source: testcase.in[1]}}
 
 		if testcase.resp != "" {
-			m.On("Post", "/admin/themes.json", query).Return(jsonResponse(testcase.resp, 200), nil)
+			m.On("Post", mock.Anything, "/admin/themes.json", query).Return(jsonResponse(testcase.resp, 200), nil)
 		} else if testcase.resperr != "" {
-			m.On("Post", "/admin/themes.json", query).Return(nil, errors.New(testcase.resperr))
+			m.On("Post", mock.Anything, "/admin/themes.json", query).Return(nil, errors.New(testcase.resperr))
 		}
 
 		theme, err := client.CreateNewTheme(testcase.in[0], testcase.in[1])
@@ -153,6 +384,138 @@ func TestThemeClient_CreateNewTheme(t *testing.T) {
 	}
 }
 
+func TestThemeClient_CreateDevelopmentTheme(t *testing.T) {
+	testcases := []struct {
+		name, zip, resp, err string
+	}{
+		{name: "dev", resp: `{"theme":{"id":123456,"name":"dev","role":"development"}}`},
+		{name: "dev", zip: "https://githubz.com/shopify/timberlands", resp: `{"theme":{"id":123456,"name":"dev","role":"development"}}`},
+		{name: "", resp: `{"errors":{"name":["can't be blank"]}}`, err: "name can't be blank"},
+	}
+
+	for _, testcase := range testcases {
+		client, _ := NewClient(&env.Env{})
+		m := new(mocks.HttpAdapter)
+		client.http = m
+		query := map[string]interface{}{"theme": Theme{Name: testcase.name, Source: testcase.zip, Role: "development"}}
+		m.On("Post", mock.Anything, "/admin/themes.json", query).Return(jsonResponse(testcase.resp, 200), nil)
+
+		theme, err := client.CreateDevelopmentTheme(testcase.name, testcase.zip)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, int64(123456), theme.ID)
+			assert.Equal(t, "development", theme.Role)
+			assert.Equal(t, "123456", client.themeID)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+			assert.Equal(t, "", client.themeID)
+		}
+		m.AssertExpectations(t)
+	}
+}
+
+func TestThemeClient_DuplicateTheme(t *testing.T) {
+	listed := `{"assets":[{"key":"layout/theme.liquid"},{"key":"assets/logo.png"},{"key":"assets/app.css.liquid"},{"key":"assets/app.css"}]}`
+	newTheme := map[string]interface{}{"theme": Theme{Name: "copy", Role: "unpublished"}}
+	copied := []Asset{
+		{Key: "assets/app.css.liquid", Value: "body {}"},
+		{Key: "assets/logo.png", Attachment: "iVBORw0KGgo="},
+		{Key: "layout/theme.liquid", Value: "{{ content_for_layout }}"},
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: []string{"*.png"}, BatchSize: 2})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?fields=key").Return(jsonResponse(listed, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(jsonResponse(`{"asset":{"key":"layout/theme.liquid","value":"{{ content_for_layout }}","theme_id":456}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=assets%2Flogo.png").Return(jsonResponse(`{"asset":{"key":"assets/logo.png","attachment":"iVBORw0KGgo=","content_type":"image/png"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=assets%2Fapp.css.liquid").Return(jsonResponse(`{"asset":{"key":"assets/app.css.liquid","value":"body {}"}}`, 200), nil)
+	m.On("Post", mock.Anything, "/admin/themes.json", newTheme).Return(jsonResponse(`{"theme":{"id":789,"name":"copy","role":"unpublished"}}`, 201), nil)
+	m.On("Put", mock.Anything, "/admin/themes/789/assets/bulk.json", map[string][]Asset{"assets": copied[:2]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/app.css.liquid"}}},{"code":200,"body":{"asset":{"key":"assets/logo.png"}}}]}`, 207), nil)
+	m.On("Put", mock.Anything, "/admin/themes/789/assets/bulk.json", map[string][]Asset{"assets": copied[2:]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"layout/theme.liquid"}}}]}`, 207), nil)
+
+	recorder := &progressRecorder{}
+	client.SetProgressReporter(recorder)
+	theme, err := client.DuplicateTheme(456, "copy")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(789), theme.ID)
+	assert.Equal(t, "123", client.themeID)
+	assert.Equal(t, 3, len(recorder.calls))
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=assets%2Fapp.css")
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"layout/theme.liquid"}]}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(nil, errors.New("connection reset"))
+	_, err = client.DuplicateTheme(456, "copy")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not download layout/theme.liquid")
+	}
+	m.AssertNotCalled(t, "Post", mock.Anything, mock.Anything, mock.Anything)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"layout/theme.liquid"}]}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(jsonResponse(`{"asset":{"key":"layout/theme.liquid","value":"{{ content_for_layout }}"}}`, 200), nil)
+	m.On("Post", mock.Anything, "/admin/themes.json", newTheme).Return(jsonResponse(`{"theme":{"id":789,"name":"copy","role":"unpublished"}}`, 201), nil)
+	m.On("Put", mock.Anything, "/admin/themes/789/assets/bulk.json", mock.Anything).
+		Return(jsonResponse(`{"results":[{"code":422,"body":{"errors":{"asset":["Liquid syntax error"]}}}]}`, 207), nil)
+	theme, err = client.DuplicateTheme(456, "copy")
+	assert.Equal(t, int64(789), theme.ID)
+	assert.EqualError(t, err, "could not update layout/theme.liquid (Liquid syntax error)")
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456/assets.json?fields=key").Return(jsonResponse(`{}`, 404), nil)
+	_, err = client.DuplicateTheme(456, "copy")
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+}
+
+func TestThemeClient_DiffThemes(t *testing.T) {
+	listedA := `{"assets":[{"key":"layout/theme.liquid","checksum":"aaa"},{"key":"assets/app.css.liquid","checksum":"bbb"},{"key":"assets/app.css","checksum":"ccc"},{"key":"snippets/old.liquid","checksum":"ddd"},{"key":"templates/index.liquid"},{"key":"templates/page.liquid"}]}`
+	listedB := `{"assets":[{"key":"layout/theme.liquid","checksum":"aaa"},{"key":"assets/app.css.liquid","checksum":"eee"},{"key":"assets/app.css","checksum":"fff"},{"key":"sections/new.liquid","checksum":"ggg"},{"key":"templates/index.liquid","checksum":"0cc175b9c0f1b6a831c399e269772661"},{"key":"templates/page.liquid"}]}`
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: []string{"snippets/*"}})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/1/assets.json?fields=key%2Cchecksum").Return(jsonResponse(listedA, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/2/assets.json?fields=key%2Cchecksum").Return(jsonResponse(listedB, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/1/assets.json?asset%5Bkey%5D=templates%2Findex.liquid").Return(jsonResponse(`{"asset":{"key":"templates/index.liquid","value":"a"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/1/assets.json?asset%5Bkey%5D=templates%2Fpage.liquid").Return(jsonResponse(`{"asset":{"key":"templates/page.liquid","value":"a"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/2/assets.json?asset%5Bkey%5D=templates%2Fpage.liquid").Return(jsonResponse(`{"asset":{"key":"templates/page.liquid","value":"b"}}`, 200), nil)
+
+	diff, err := client.DiffThemes(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, ThemeDiff{
+		OnlyInA:   []string{"snippets/old.liquid"},
+		OnlyInB:   []string{"sections/new.liquid"},
+		Differing: []string{"assets/app.css.liquid", "templates/page.liquid"},
+		Identical: []string{"layout/theme.liquid", "templates/index.liquid"},
+	}, diff)
+	m.AssertExpectations(t)
+	m.AssertNumberOfCalls(t, "Get", 5)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/1/assets.json?fields=key%2Cchecksum").Return(jsonResponse(listedA, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/2/assets.json?fields=key%2Cchecksum").Return(jsonResponse(`{}`, 404), nil)
+	_, err = client.DiffThemes(1, 2)
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/1/assets.json?fields=key%2Cchecksum").Return(jsonResponse(`{"assets":[{"key":"templates/index.liquid"}]}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/2/assets.json?fields=key%2Cchecksum").Return(jsonResponse(`{"assets":[{"key":"templates/index.liquid","checksum":"aaa"}]}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/1/assets.json?asset%5Bkey%5D=templates%2Findex.liquid").Return(nil, errors.New("connection reset"))
+	_, err = client.DiffThemes(1, 2)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not download templates/index.liquid")
+	}
+}
+
 func TestThemeClient_GetInfo(t *testing.T) {
 	testcases := []struct {
 		themeID, resp, resperr, err string
@@ -165,12 +528,22 @@ func TestThemeClient_GetInfo(t *testing.T) {
 		{themeID: "123456", resp: "{}", code: 404, err: ErrThemeNotFound.Error()},
 	}
 
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123456"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123456.json").Return(jsonResponse(`{"theme":{"id":123456,"name":"Dawn","role":"unpublished","previewable":false,"processing":true,"theme_store_id":887,"admin_graphql_api_id":"gid://shopify/OnlineStoreTheme/123456"}}`, 200), nil)
+	theme, err := client.GetInfo()
+	assert.Nil(t, err)
+	assert.True(t, theme.Processing)
+	assert.Equal(t, int64(887), theme.ThemeStoreID)
+	assert.Equal(t, "gid://shopify/OnlineStoreTheme/123456", theme.AdminGraphQLAPIID)
+
 	for _, testcase := range testcases {
 		m := new(mocks.HttpAdapter)
 		client, _ := NewClient(&env.Env{ThemeID: testcase.themeID})
 		client.http = m
 
-		expectation := m.On("Get", fmt.Sprintf("/admin/themes/%s.json", testcase.themeID))
+		expectation := m.On("Get", mock.Anything, fmt.Sprintf("/admin/themes/%s.json", testcase.themeID))
 		if testcase.resperr != "" {
 			expectation.Return(nil, errors.New(testcase.resperr))
 		} else {
@@ -193,6 +566,145 @@ func TestThemeClient_GetInfo(t *testing.T) {
 	}
 }
 
+func TestThemeClient_RequestID(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+
+	resp := jsonResponse(`{"errors":{"asset":["Liquid syntax error"]}}`, 422)
+	resp.Header = http.Header{"X-Request-Id": {"5a9e3c1f-1b2d"}}
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything).Return(resp, nil)
+
+	err := client.UpdateAsset(Asset{Key: "templates/index.liquid"})
+	assert.EqualError(t, err, "Liquid syntax error (request id: 5a9e3c1f-1b2d)")
+	if apiErr, ok := err.(*APIError); assert.True(t, ok) {
+		assert.Equal(t, "5a9e3c1f-1b2d", apiErr.RequestID)
+	}
+}
+
+func TestThemeClient_WaitForProcessing(t *testing.T) {
+	processing := func(context.Context, string) *http.Response {
+		return jsonResponse(`{"theme":{"id":456,"processing":true}}`, 200)
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	client.pollDelay = time.Millisecond
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(processing, nil).Twice()
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(jsonResponse(`{"theme":{"id":456,"processing":false}}`, 200), nil).Once()
+	assert.Nil(t, client.WaitForProcessing(456, time.Minute))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(processing, nil)
+	err := client.WaitForProcessing(456, 10*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrThemeProcessing))
+	assert.EqualError(t, err, "theme is still processing after 10ms")
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(processing, nil).Once()
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(nil, errors.New("server error")).Once()
+	assert.EqualError(t, client.WaitForProcessing(456, time.Minute), "server error")
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(jsonResponse("{}", 404), nil)
+	assert.True(t, errors.Is(client.WaitForProcessing(456, time.Minute), ErrThemeNotFound))
+
+	client.pollDelay = time.Hour
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/456.json").Return(processing, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, client.WaitForProcessingContext(ctx, 456, time.Minute))
+	m.AssertNumberOfCalls(t, "Get", 1)
+}
+
+func TestThemeClient_Publish(t *testing.T) {
+	testcases := []struct {
+		getResp, putResp, resperr, err string
+		getCode                        int
+	}{
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, putResp: `{"theme":{"id":123,"role":"main"}}`},
+		{getResp: `{"theme":{"id":123,"role":"main"}}`, getCode: 200},
+		{getResp: `{"theme":{"id":123,"role":"development"}}`, getCode: 200, err: ErrPublishDevelopment.Error()},
+		{getResp: `{}`, getCode: 404, err: ErrThemeNotFound.Error()},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, putResp: `{"theme":{"id":123,"role":"unpublished"}}`, err: ErrPublishFailed.Error()},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, putResp: `{"errors":{"role":["cannot be main while processing"]}}`, err: "role cannot be main while processing"},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{})
+		client.http = m
+
+		m.On("Get", mock.Anything, "/admin/themes/123.json").Return(jsonResponse(testcase.getResp, testcase.getCode), nil)
+		put := m.On("Put", mock.Anything, "/admin/themes/123.json", map[string]map[string]string{"theme": {"role": "main"}})
+		if testcase.resperr != "" {
+			put.Return(nil, errors.New(testcase.resperr))
+		} else if testcase.putResp != "" {
+			put.Return(jsonResponse(testcase.putResp, 200), nil)
+		}
+
+		err := client.Publish(123)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+
+		if testcase.putResp == "" && testcase.resperr == "" {
+			m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestThemeClient_DeleteTheme(t *testing.T) {
+	testcases := []struct {
+		getResp, delResp, resperr, err string
+		getCode, delCode               int
+	}{
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, delResp: `{"theme":{"id":123}}`, delCode: 200},
+		{getResp: `{"theme":{"id":123,"role":"main"}}`, getCode: 200, err: ErrDeleteMainTheme.Error()},
+		{getResp: `{}`, getCode: 404, err: ErrThemeNotFound.Error()},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, delResp: `{}`, delCode: 404, err: ErrThemeNotFound.Error()},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, delResp: `{}`, delCode: 403, err: ErrDeleteMainTheme.Error()},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, delResp: `{"errors":{"theme":["is locked"]}}`, delCode: 422, err: "theme is locked"},
+		{getResp: `{"theme":{"id":123,"role":"unpublished"}}`, getCode: 200, resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{})
+		client.http = m
+
+		m.On("Get", mock.Anything, "/admin/themes/123.json").Return(jsonResponse(testcase.getResp, testcase.getCode), nil)
+		del := m.On("Delete", mock.Anything, "/admin/themes/123.json")
+		if testcase.resperr != "" {
+			del.Return(nil, errors.New(testcase.resperr))
+		} else if testcase.delResp != "" {
+			del.Return(jsonResponse(testcase.delResp, testcase.delCode), nil)
+		}
+
+		err := client.DeleteTheme(123)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+
+		if testcase.delResp == "" && testcase.resperr == "" {
+			m.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		}
+	}
+}
+
 func TestThemeClient_GetAllAssets(t *testing.T) {
 	testcases := []struct {
 		resp, resperr, err string
@@ -209,7 +721,7 @@ func TestThemeClient_GetAllAssets(t *testing.T) {
 		client, _ := NewClient(&env.Env{ThemeID: "123"})
 		client.http = m
 
-		expectation := m.On("Get", "/admin/themes/123/assets.json?fields=key")
+		expectation := m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key")
 		if testcase.resperr != "" {
 			expectation.Return(nil, errors.New(testcase.resperr))
 		} else {
@@ -229,8 +741,8 @@ func TestThemeClient_GetAllAssets(t *testing.T) {
 	}
 
 	filtertestcases := []struct {
-		input            string
-		ignore, expected []string
+		input                     string
+		ignore, include, expected []string
 	}{
 		{
 			input:    `{"assets":[{"key":"templates/foo.json.liquid"},{"key":"templates/foo.json"}]}`,
@@ -245,73 +757,547 @@ func TestThemeClient_GetAllAssets(t *testing.T) {
 			expected: []string{"templates/other.liquid"},
 			ignore:   []string{"templates/ignore.html.liquid"},
 		},
+		{
+			input:    `{"assets":[{"key":"templates/index.json"},{"key":"templates/product.liquid"},{"key":"sections/header.liquid"}]}`,
+			expected: []string{"sections/header.liquid", "templates/index.json"},
+			include:  []string{"templates/*.json", "sections/*.liquid"},
+		},
+		{
+			input:    `{"assets":[{"key":"templates/index.json"},{"key":"templates/product.json"},{"key":"sections/header.liquid"}]}`,
+			expected: []string{"templates/index.json"},
+			include:  []string{"templates/*.json"},
+			ignore:   []string{"templates/product.json"},
+		},
+		{
+			input:    `{"assets":[{"key":"templates/index.json"},{"key":"sections/header.liquid"}]}`,
+			expected: []string{},
+			include:  []string{"templates/*.json"},
+			ignore:   []string{"templates/"},
+		},
 	}
 
 	for _, testcase := range filtertestcases {
 		m := new(mocks.HttpAdapter)
-		client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: testcase.ignore})
+		client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: testcase.ignore, Includes: testcase.include})
 		client.http = m
-		m.On("Get", "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(testcase.input, 200), nil)
+		m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(testcase.input, 200), nil)
 		assets, err := client.GetAllAssets()
 		assert.Nil(t, err)
 		assert.Equal(t, testcase.expected, assets)
 	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", ListGenerated: true})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"templates/foo.json.liquid"},{"key":"templates/foo.json"},{"key":"assets/app.css"}]}`, 200), nil)
+	assets, err := client.GetAllAssets()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"assets/app.css", "templates/foo.json", "templates/foo.json.liquid"}, assets)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{ThemeID: "123", Directory: filepath.Join("..", "env", "_testdata", "ignoredir")})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"assets/app.js"},{"key":"assets/app.js.map"},{"key":"assets/keep.map"},{"key":"assets/node/index.js"}]}`, 200), nil)
+	assets, err = client.GetAllAssets()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"assets/app.js", "assets/keep.map"}, assets)
 }
 
-func TestThemeClient_GetAsset(t *testing.T) {
+func TestThemeClient_GetAllAssetsDetailed(t *testing.T) {
+	detailed := `{"assets":[
+		{"key":"assets/logo.png","public_url":"https://cdn.shopify.com/s/files/1/logo.png","created_at":"2018-05-01T10:00:00-04:00","updated_at":"2018-05-02T10:00:00-04:00","content_type":"image/png","size":2048,"checksum":"f8b1e4","theme_id":123},
+		{"key":"templates/index.liquid","value":"{{ content_for_index }}","updated_at":"2018-05-03T10:00:00-04:00","content_type":"application/x-liquid","size":23,"checksum":"a1c3d5","theme_id":123},
+		{"key":"templates/index","content_type":"text/html","size":0,"theme_id":123},
+		{"key":"config/ignored.json","content_type":"application/json","size":10,"theme_id":123}
+	]}`
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: []string{"config/ignored.json"}})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json").Return(jsonResponse(detailed, 200), nil)
+
+	assets, err := client.GetAllAssetsDetailed()
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(assets)) {
+		size := int64(2048)
+		assert.Equal(t, Asset{
+			Key:         "assets/logo.png",
+			PublicURL:   "https://cdn.shopify.com/s/files/1/logo.png",
+			UpdatedAt:   "2018-05-02T10:00:00-04:00",
+			ContentType: "image/png",
+			Size:        &size,
+			Checksum:    "f8b1e4",
+			ThemeID:     123,
+		}, assets[0])
+		assert.Equal(t, "templates/index.liquid", assets[1].Key)
+		assert.Equal(t, "application/x-liquid", assets[1].ContentType)
+		assert.Equal(t, "", assets[1].Value)
+	}
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json").Return(jsonResponse(`{}`, 404), nil)
+	_, err = client.GetAllAssetsDetailed()
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+}
+
+func TestThemeClient_GetAllAssetsPaginated(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+
+	firstPage := jsonResponse(`{"assets":[{"key":"assets/a.txt"},{"key":"templates/foo.json"}]}`, 200)
+	firstPage.Header = http.Header{"Link": {`<https://shop.myshopify.com/admin/themes/123/assets.json?fields=key&page_info=two>; rel="next"`}}
+	secondPage := jsonResponse(`{"assets":[{"key":"assets/b.txt"},{"key":"templates/foo.json.liquid"}]}`, 200)
+	secondPage.Header = http.Header{"Link": {`<https://shop.myshopify.com/admin/themes/123/assets.json?fields=key>; rel="previous", <https://shop.myshopify.com/admin/themes/123/assets.json?fields=key&page_info=three>; rel="next"`}}
+	lastPage := jsonResponse(`{"assets":[{"key":"assets/c.txt"}]}`, 200)
+	lastPage.Header = http.Header{"Link": {`<https://shop.myshopify.com/admin/themes/123/assets.json?fields=key&page_info=two>; rel="previous"`}}
+
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(firstPage, nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key&page_info=two").Return(secondPage, nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key&page_info=three").Return(lastPage, nil)
+
+	assets, err := client.GetAllAssets()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"assets/a.txt", "assets/b.txt", "assets/c.txt", "templates/foo.json.liquid"}, assets)
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	firstPage = jsonResponse(`{"assets":[{"key":"assets/a.txt"}]}`, 200)
+	firstPage.Header = http.Header{"Link": {`<https://shop.myshopify.com/admin/themes/123/assets.json?fields=key&page_info=two>; rel="next"`}}
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(firstPage, nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key&page_info=two").Return(nil, errors.New("server error"))
+	_, err = client.GetAllAssets()
+	assert.EqualError(t, err, "server error")
+}
+
+func TestSelectAssets(t *testing.T) {
+	keys := []string{"assets/app.js", "assets/logo.png", "layout/theme.liquid", "snippets/header.liquid", "snippets/header.liquid.bak"}
+
 	testcases := []struct {
-		resp, resperr, err string
-		code               int
+		root     string
+		args     []string
+		expected []string
 	}{
-		{resp: `{"errors": "Not Found"}`, code: 200, err: "Not Found"},
-		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
-		{resp: `{"asset":{"key":"assets/hello.txt"}}`, code: 200},
-		{code: 404, err: ErrNotPartOfTheme.Error()},
+		{args: []string{"snippets/header.liquid"}, expected: []string{"snippets/header.liquid"}},
+		{args: []string{"assets"}, expected: []string{"assets/app.js", "assets/logo.png"}},
+		{args: []string{"assets/", "layout/theme.liquid"}, expected: []string{"assets/app.js", "assets/logo.png", "layout/theme.liquid"}},
+		{args: []string{"./snippets/header.liquid"}, expected: []string{"snippets/header.liquid"}},
+		{root: "theme", args: []string{"theme/layout/theme.liquid"}, expected: []string{"layout/theme.liquid"}},
+		{root: "theme", args: []string{"theme"}, expected: keys},
+		{args: []string{"."}, expected: keys},
+		{args: []string{"snippets/head"}, expected: []string{}},
+		{args: []string{"../assets/app.js"}, expected: []string{}},
+		{args: []string{"config/settings_data.json"}, expected: []string{}},
 	}
 
 	for _, testcase := range testcases {
-		m := new(mocks.HttpAdapter)
-		client, _ := NewClient(&env.Env{ThemeID: "123"})
-		client.http = m
+		assert.Equal(t, testcase.expected, SelectAssets(testcase.root, keys, testcase.args...), strings.Join(testcase.args, ","))
+	}
+}
 
-		expectation := m.On("Get", "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt")
-		if testcase.resperr != "" {
-			expectation.Return(nil, errors.New(testcase.resperr))
-		} else if testcase.code != 0 {
-			expectation.Return(jsonResponse(testcase.resp, testcase.code), nil)
-		}
+func TestThemeClient_GetAssetChecksums(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key%2Cchecksum").Return(jsonResponse(`{"assets":[{"key":"assets/a.txt","checksum":"abc"},{"key":"assets/b.txt"}]}`, 200), nil)
+	checksums, err := client.GetAssetChecksums()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"assets/a.txt": "abc"}, checksums)
+	m.AssertExpectations(t)
 
-		asset, err := client.GetAsset("filename.txt")
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key%2Cchecksum").Return(jsonResponse("{}", 404), nil)
+	_, err = client.GetAssetChecksums()
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+}
 
-		if testcase.err == "" {
-			assert.Nil(t, err)
-			assert.Equal(t, asset.Key, "assets/hello.txt")
-		} else if assert.NotNil(t, err, testcase.err) {
-			assert.Contains(t, err.Error(), testcase.err)
-		}
+func TestNextPagePath(t *testing.T) {
+	testcases := []struct {
+		link, expected string
+	}{
+		{link: "", expected: ""},
+		{link: `<https://shop.myshopify.com/admin/assets.json?page_info=abc>; rel="previous"`, expected: ""},
+		{link: `<https://shop.myshopify.com/admin/assets.json?page_info=abc>; rel="next"`, expected: "/admin/assets.json?page_info=abc"},
+		{link: `<https://shop.myshopify.com/admin/assets.json?page_info=a>; rel="previous", <https://shop.myshopify.com/admin/assets.json?page_info=b>; rel="next"`, expected: "/admin/assets.json?page_info=b"},
+	}
 
-		m.AssertExpectations(t)
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.expected, nextPagePath(testcase.link))
 	}
 }
 
-func TestThemeClient_UpdateAsset(t *testing.T) {
+func TestThemeClient_AssetStats(t *testing.T) {
 	testcases := []struct {
-		resp, resperr, err string
-		code               int
+		resp, err string
+		code      int
+		stats     AssetStats
 	}{
-		{resp: `{"errors": "Not Found"}`, code: 200, err: "Not Found"},
-		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
-		{resp: `{"asset":{"key":"assets/hello.txt"}}`, code: 200},
-		{resp: "{}", code: 404, err: ErrNotPartOfTheme.Error()},
+		{resp: `{"assets":[{"key":"assets/a.js","size":120},{"key":"assets/b.png","size":2048},{"key":"layout/theme.liquid","size":0}]}`, code: 200, stats: AssetStats{Count: 3, TotalBytes: 2168, SizeAvailable: true}},
+		{resp: `{"assets":[{"key":"assets/a.js","size":120},{"key":"assets/b.png"}]}`, code: 200, stats: AssetStats{Count: 2, TotalBytes: 120}},
+		{resp: `{"assets":[]}`, code: 200, stats: AssetStats{SizeAvailable: true}},
+		{resp: `{}`, code: 404, err: ErrThemeNotFound.Error()},
 	}
 
 	for _, testcase := range testcases {
 		m := new(mocks.HttpAdapter)
 		client, _ := NewClient(&env.Env{ThemeID: "123"})
 		client.http = m
+		m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key%2Csize").Return(jsonResponse(testcase.resp, testcase.code), nil)
 
-		expectation := m.On("Put", "/admin/themes/123/assets.json", map[string]Asset{"asset": {Key: "filename.txt"}})
-		if testcase.resperr != "" {
+		stats, err := client.AssetStats()
+		if testcase.err == "" && assert.Nil(t, err) {
+			assert.Equal(t, testcase.stats, stats)
+		} else if testcase.err != "" && assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+	}
+}
+
+func TestThemeClient_GetAssetsSince(t *testing.T) {
+	remote := `{"assets":[
+		{"key":"assets/new.js","updated_at":"2018-06-01T12:00:00-04:00"},
+		{"key":"assets/old.js","updated_at":"2018-04-01T12:00:00-04:00"},
+		{"key":"layout/theme.liquid","updated_at":"2018-05-15T12:00:00-04:00"},
+		{"key":"assets/exact.js","updated_at":"2018-05-01T12:00:00-04:00"},
+		{"key":"assets/app.css","updated_at":"2018-06-02T12:00:00-04:00"},
+		{"key":"assets/app.css.liquid","updated_at":"2018-06-02T12:00:00-04:00"},
+		{"key":"config/settings_data.json","updated_at":"2018-06-03T12:00:00-04:00"},
+		{"key":"snippets/unknown.liquid"}
+	]}`
+	since, _ := time.Parse(time.RFC3339, "2018-05-01T12:00:00-04:00")
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: []string{"config/settings_data.json"}})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key%2Cupdated_at").Return(jsonResponse(remote, 200), nil)
+
+	assets, err := client.GetAssetsSince(since)
+	assert.Nil(t, err)
+	keys := []string{}
+	for _, asset := range assets {
+		keys = append(keys, asset.Key)
+	}
+	assert.Equal(t, []string{"snippets/unknown.liquid", "layout/theme.liquid", "assets/new.js", "assets/app.css.liquid"}, keys)
+	assert.Equal(t, "2018-06-01T12:00:00-04:00", assets[2].UpdatedAt)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key%2Cupdated_at").Return(jsonResponse(`{}`, 404), nil)
+	_, err = client.GetAssetsSince(since)
+	assert.True(t, errors.Is(err, ErrThemeNotFound))
+}
+
+func TestThemeClient_GetAsset(t *testing.T) {
+	testcases := []struct {
+		resp, resperr, err string
+		code               int
+	}{
+		{resp: `{"errors": "Not Found"}`, code: 200, err: "Not Found"},
+		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+		{resp: `{"asset":{"key":"assets/hello.txt","public_url":"https://cdn.shopify.com/assets/hello.txt"}}`, code: 200},
+		{code: 404, err: ErrNotPartOfTheme.Error()},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{ThemeID: "123"})
+		client.http = m
+
+		expectation := m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt")
+		if testcase.resperr != "" {
+			expectation.Return(nil, errors.New(testcase.resperr))
+		} else if testcase.code != 0 {
+			expectation.Return(jsonResponse(testcase.resp, testcase.code), nil)
+		}
+
+		asset, err := client.GetAsset("filename.txt")
+
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, asset.Key, "assets/hello.txt")
+			assert.Equal(t, "https://cdn.shopify.com/assets/hello.txt", asset.PublicURL)
+		} else if assert.NotNil(t, err, testcase.err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestThemeClient_GetSettingsSchema(t *testing.T) {
+	schema := `[{"name":"theme_info","theme_name":"Debut"},{"name":"Colors","settings":[{"type":"color","id":"color_text","label":"Text"}]}]`
+	path := "/admin/themes/123/assets.json?asset%5Bkey%5D=config%2Fsettings_schema.json"
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	body, _ := json.Marshal(map[string]Asset{"asset": {Key: SettingsSchemaKey, Value: schema}})
+	m.On("Get", mock.Anything, path).Return(jsonResponse(string(body), 200), nil).Once()
+	data, err := client.GetSettingsSchema()
+	assert.Nil(t, err)
+	assert.Equal(t, schema, string(data))
+
+	body, _ = json.Marshal(map[string]Asset{"asset": {Key: SettingsSchemaKey, Value: `[{"name":"Colors","settings":[{"id":"color_text"}]}]`}})
+	m.On("Get", mock.Anything, path).Return(jsonResponse(string(body), 200), nil).Once()
+	_, err = client.GetSettingsSchema()
+	var schemaErr *SchemaError
+	if assert.True(t, errors.As(err, &schemaErr)) {
+		assert.Equal(t, "[0].settings[0]", schemaErr.Path)
+	}
+
+	m.On("Get", mock.Anything, path).Return(jsonResponse(`{}`, 404), nil).Once()
+	_, err = client.GetSettingsSchema()
+	assert.True(t, errors.Is(err, ErrNotPartOfTheme))
+	m.AssertExpectations(t)
+}
+
+func TestThemeClient_GetAssetStream(t *testing.T) {
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	testcases := []struct {
+		resp, resperr, err, expected string
+		code                         int
+	}{
+		{resp: `{"errors": "Not Found"}`, code: 200, err: "Not Found"},
+		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+		{resp: `{"asset":{"key":"filename.txt","value":"hello\nworld"}}`, code: 200, expected: "hello\nworld"},
+		{resp: `{"asset":{"key":"filename.txt","attachment":"` + base64.StdEncoding.EncodeToString(data) + `"}}`, code: 200, expected: string(data)},
+		{resp: `{"errors":{"asset":["is too large"]}}`, code: 422, err: "asset is too large"},
+		{resp: `{}`, code: 500, err: "500 Internal Server Error"},
+		{resp: `{"asset":`, code: 200, err: ErrMalformedResponse.Error()},
+		{code: 404, err: ErrNotPartOfTheme.Error()},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{ThemeID: "123"})
+		client.http = m
+
+		expectation := m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt")
+		if testcase.resperr != "" {
+			expectation.Return(nil, errors.New(testcase.resperr))
+		} else {
+			expectation.Return(jsonResponse(testcase.resp, testcase.code), nil)
+		}
+
+		var buf bytes.Buffer
+		err := client.GetAssetStream("filename.txt", &buf)
+
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expected, buf.String())
+		} else if assert.NotNil(t, err, testcase.err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestThemeClient_ContextMethods(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Get", ctx, "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt").Return(jsonResponse(`{"asset":{"key":"filename.txt"}}`, 200), nil)
+	m.On("Put", ctx, "/admin/themes/123/assets.json", map[string]Asset{"asset": {Key: "filename.txt", ContentType: "text/plain; charset=utf-8"}}).Return(jsonResponse(`{"asset":{"key":"filename.txt"}}`, 200), nil)
+	m.On("Delete", ctx, "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt").Return(jsonResponse(`{}`, 200), nil)
+
+	asset, err := client.GetAssetContext(ctx, "filename.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "filename.txt", asset.Key)
+	assert.Nil(t, client.UpdateAssetContext(ctx, asset))
+	assert.Nil(t, client.DeleteAssetContext(ctx, asset))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", context.Background(), "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt").Return(nil, context.Canceled)
+	_, err = client.GetAsset("filename.txt")
+	assert.Equal(t, context.Canceled, err)
+	m.AssertExpectations(t)
+}
+
+type progressRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *progressRecorder) OnAsset(key string, index, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, fmt.Sprintf("%s %d/%d", key, index, total))
+}
+
+func TestThemeClient_ProgressReporter(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"assets/b.js"},{"key":"assets/a.js"}]}`, 200), nil).Once()
+	_, err := client.GetAllAssets()
+	assert.Nil(t, err)
+
+	recorder := &progressRecorder{}
+	client.SetProgressReporter(recorder)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"assets/b.js"},{"key":"assets/a.js"}]}`, 200), nil).Once()
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fa.js").Return(jsonResponse(`{"asset":{"key":"assets/a.js"}}`, 200), nil)
+	_, err = client.GetAllAssets()
+	assert.Nil(t, err)
+	_, err = client.DownloadAssets([]string{"assets/a.js"}, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"assets/a.js 1/2", "assets/b.js 2/2", "assets/a.js 1/1"}, recorder.calls)
+}
+
+func TestThemeClient_AssetExists(t *testing.T) {
+	testcases := []struct {
+		resp, resperr, err string
+		code               int
+		exists             bool
+	}{
+		{resp: `{"asset":{"key":"assets/app.js"}}`, code: 200, exists: true},
+		{resp: `{"errors":"Not Found"}`, code: 404, exists: false},
+		{resp: `{}`, code: 200, exists: false},
+		{resp: `{"errors": "Unavailable"}`, code: 503, err: "Unavailable"},
+		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{ThemeID: "123"})
+		client.http = m
+
+		expectation := m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.js&fields=key")
+		if testcase.resperr != "" {
+			expectation.Return(nil, errors.New(testcase.resperr))
+		} else {
+			expectation.Return(jsonResponse(testcase.resp, testcase.code), nil)
+		}
+
+		exists, err := client.AssetExists("assets/app.js")
+		assert.Equal(t, testcase.exists, exists)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+		m.AssertExpectations(t)
+	}
+}
+
+func TestThemeClient_RequireUnpublished(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", RequireUnpublished: true})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123.json").Return(jsonResponse(`{"theme":{"id":123,"role":"main"}}`, 200), nil).Once()
+
+	assert.Equal(t, ErrThemePublished, client.UpdateAsset(Asset{Key: "assets/a.js"}))
+	assert.Equal(t, ErrThemePublished, client.DeleteAsset(Asset{Key: "assets/b.js"}))
+	assert.Equal(t, ErrThemePublished, client.BulkUpdateAssets([]Asset{{Key: "assets/c.js"}}))
+	assert.Equal(t, ErrThemePublished, client.UpdateAssetIfUnmodified(Asset{Key: "assets/d.js"}, time.Time{}))
+	m.AssertNumberOfCalls(t, "Get", 1)
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+	m.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{ThemeID: "123", RequireUnpublished: true})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123.json").Return(nil, errors.New("connection reset")).Once()
+	m.On("Get", mock.Anything, "/admin/themes/123.json").Return(jsonResponse(`{"theme":{"id":123,"role":"unpublished"}}`, 200), nil).Once()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything).Return(jsonResponse(`{"asset":{"key":"assets/a.js"}}`, 200), nil).Once()
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fb.js").Return(jsonResponse(`{}`, 200), nil).Once()
+
+	assert.EqualError(t, client.UpdateAsset(Asset{Key: "assets/a.js"}), "connection reset")
+	assert.Nil(t, client.UpdateAsset(Asset{Key: "assets/a.js"}))
+	assert.Nil(t, client.DeleteAsset(Asset{Key: "assets/b.js"}))
+	m.AssertExpectations(t)
+
+	client, _ = NewClient(&env.Env{RequireUnpublished: true})
+	client.http = new(mocks.HttpAdapter)
+	assert.Equal(t, ErrThemePublished, client.UpdateAsset(Asset{Key: "assets/a.js"}))
+
+	client, _ = NewClient(&env.Env{ThemeID: "123", RequireUnpublished: true, DryRun: true})
+	client.http = new(mocks.HttpAdapter)
+	client.log = log.New(&bytes.Buffer{}, "", 0)
+	assert.Nil(t, client.UpdateAsset(Asset{Key: "assets/a.js"}))
+}
+
+func TestThemeClient_DryRun(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", DryRun: true})
+	client.http = m
+	var buf bytes.Buffer
+	client.log = log.New(&buf, "", 0)
+
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"assets/a.js"}]}`, 200), nil)
+
+	assets, err := client.GetAllAssets()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"assets/a.js"}, assets)
+	assert.Nil(t, client.UpdateAsset(Asset{Key: "assets/a.js"}))
+	assert.Nil(t, client.DeleteAsset(Asset{Key: "assets/b.js"}))
+	assert.Nil(t, client.BulkUpdateAssets([]Asset{{Key: "assets/c.js"}}))
+
+	assert.Equal(t, "[dry-run] would update assets/a.js\n[dry-run] would delete assets/b.js\n[dry-run] would update assets/c.js\n", buf.String())
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+	m.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestThemeClient_DownloadAssets(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	assert.Equal(t, defaultConcurrency, client.concurrency)
+
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fa.js").Return(jsonResponse(`{"asset":{"key":"assets/a.js","value":"a","content_type":"application/javascript"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fb.js").Return(jsonResponse(`{"asset":{"key":"assets/b.js","value":"b","content_type":"application/javascript"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fc.js").Return(jsonResponse(`{}`, 404), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fd.js").Return(nil, errors.New("connection reset"))
+
+	assets, err := client.DownloadAssets([]string{"assets/a.js", "assets/b.js", "assets/c.js", "assets/d.js"}, 2)
+	assert.Equal(t, map[string]Asset{
+		"assets/a.js": {Key: "assets/a.js", Value: "a", ContentType: "application/javascript"},
+		"assets/b.js": {Key: "assets/b.js", Value: "b", ContentType: "application/javascript"},
+	}, assets)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "could not download assets/c.js ("+ErrNotPartOfTheme.Error()+") and assets/d.js (connection reset)", err.Error())
+	}
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{ThemeID: "123", Concurrency: 10})
+	client.http = m
+	assert.Equal(t, 10, client.concurrency)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fa.js").Return(jsonResponse(`{"asset":{"key":"assets/a.js","value":"a"}}`, 200), nil)
+	assets, err = client.DownloadAssets([]string{"assets/a.js"}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(assets))
+}
+
+func TestThemeClient_UpdateAsset(t *testing.T) {
+	testcases := []struct {
+		resp, resperr, err string
+		code               int
+	}{
+		{resp: `{"errors": "Not Found"}`, code: 200, err: "Not Found"},
+		{resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+		{resp: `{"asset":{"key":"assets/hello.txt"}}`, code: 200},
+		{resp: "{}", code: 404, err: ErrNotPartOfTheme.Error()},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{ThemeID: "123"})
+		client.http = m
+
+		expectation := m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": {Key: "filename.txt"}})
+		if testcase.resperr != "" {
 			expectation.Return(nil, errors.New(testcase.resperr))
 		} else if testcase.code != 0 {
 			expectation.Return(jsonResponse(testcase.resp, testcase.code), nil)
@@ -335,7 +1321,7 @@ func TestThemeClient_UpdateAsset(t *testing.T) {
 
 	count := 0
 	m.On(
-		"Put",
+		"Put", mock.Anything,
 		mock.MatchedBy(func(path string) bool {
 			if count == 0 {
 				count++
@@ -350,12 +1336,12 @@ func TestThemeClient_UpdateAsset(t *testing.T) {
 	}, nil)
 
 	m.On(
-		"Delete",
+		"Delete", mock.Anything,
 		"/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt.liquid",
 	).Return(jsonResponse("{}", 200), nil)
 
 	m.On(
-		"Put",
+		"Put", mock.Anything,
 		"/admin/themes/123/assets.json",
 		map[string]Asset{"asset": asset},
 	).Return(jsonResponse(`{"asset":{"key":"assets/hello.txt"}}`, 200), nil)
@@ -364,6 +1350,428 @@ func TestThemeClient_UpdateAsset(t *testing.T) {
 	m.AssertExpectations(t)
 }
 
+func TestThemeClient_UpdateAssetValidationRetry(t *testing.T) {
+	invalid := `{"errors":{"asset":["Setting 'header' refers to a section that does not exist"]}}`
+	asset := Asset{Key: SettingsDataKey, Value: "{}"}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	client.retryDelay = 0
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(invalid, 422), nil).Once()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(`{"asset":{"key":"config/settings_data.json"}}`, 200), nil).Once()
+	assert.Nil(t, client.UpdateAsset(asset))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	for i := 0; i <= maxValidationRetries; i++ {
+		m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(invalid, 422), nil).Once()
+	}
+	assert.EqualError(t, client.UpdateAsset(asset), "Setting 'header' refers to a section that does not exist")
+	m.AssertNumberOfCalls(t, "Put", maxValidationRetries+1)
+
+	other := Asset{Key: "templates/index.json", Value: "{}"}
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": other}).Return(jsonResponse(invalid, 422), nil).Once()
+	assert.NotNil(t, client.UpdateAsset(other))
+	m.AssertNumberOfCalls(t, "Put", 1)
+
+	client, _ = NewClient(&env.Env{ThemeID: "123", RetryKeys: []string{"templates/index.json"}})
+	client.retryDelay = 0
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": other}).Return(jsonResponse(invalid, 422), nil).Once()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": other}).Return(jsonResponse(`{"asset":{"key":"templates/index.json"}}`, 200), nil).Once()
+	assert.Nil(t, client.UpdateAsset(other))
+	m.AssertExpectations(t)
+
+	client.retryDelay = time.Hour
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": other}).Return(jsonResponse(invalid, 422), nil).Once()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, client.UpdateAssetContext(ctx, other))
+}
+
+func TestThemeClient_UpdateAssetResult(t *testing.T) {
+	asset := Asset{Key: "assets/logo.png", Attachment: "aGVsbG8="}
+	publicURL := "https://cdn.shopify.com/s/files/1/0001/t/123/assets/logo.png?v=1"
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(`{"asset":{"key":"assets/logo.png","public_url":"`+publicURL+`"}}`, 200), nil)
+	updated, err := client.UpdateAssetResult(asset)
+	assert.Nil(t, err)
+	assert.Equal(t, publicURL, updated.PublicURL)
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(`{"errors":{"asset":["is invalid"]}}`, 422), nil)
+	_, err = client.UpdateAssetResult(asset)
+	assert.EqualError(t, err, "is invalid")
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{ThemeID: "123", DryRun: true})
+	client.http = m
+	updated, err = client.UpdateAssetResult(asset)
+	assert.Nil(t, err)
+	assert.Equal(t, asset, updated)
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestThemeClient_ThemeLocked(t *testing.T) {
+	asset := Asset{Key: "templates/index.liquid", Value: "hello"}
+	locked := `{"errors":"Locked"}`
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	client.lockedDelay = 0
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(locked, 423), nil).Twice()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(`{"asset":{"key":"templates/index.liquid"}}`, 200), nil).Once()
+	assert.Nil(t, client.UpdateAsset(asset))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	client.lockedDelay = time.Millisecond
+	client.lockedWait = 5 * time.Millisecond
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=templates%2Findex.liquid").Return(jsonResponse(locked, 423), nil)
+	err := client.DeleteAsset(asset)
+	assert.True(t, errors.Is(err, ErrThemeLocked))
+	assert.Contains(t, err.Error(), "try again once it is ready")
+
+	client.lockedDelay = time.Hour
+	client.lockedWait = 2 * time.Hour
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(locked, 423), nil).Once()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, client.UpdateAssetContext(ctx, asset))
+}
+
+func TestThemeClient_UpdateAssetGenerated(t *testing.T) {
+	conflict := `{"errors":{"asset":["Cannot overwrite generated asset assets/a.css","Cannot overwrite generated asset assets/b.css."]}}`
+	asset := Asset{Key: "assets/bundle.css"}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(conflict, 422), nil).Once()
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fa.css.liquid").Return(jsonResponse("{}", 200), nil).Once()
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fb.css.liquid").Return(jsonResponse("{}", 200), nil).Once()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(`{"asset":{"key":"assets/bundle.css"}}`, 200), nil).Once()
+	assert.Nil(t, client.UpdateAsset(asset))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	for i := 0; i <= maxGeneratedAssetRetries; i++ {
+		m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": asset}).Return(jsonResponse(conflict, 422), nil).Once()
+	}
+	m.On("Delete", mock.Anything, mock.Anything).Return(jsonResponse("{}", 200), nil)
+	err := client.UpdateAsset(asset)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not overwrite generated asset assets/a.css and assets/b.css")
+	}
+	m.AssertNumberOfCalls(t, "Put", maxGeneratedAssetRetries+1)
+	m.AssertNumberOfCalls(t, "Delete", maxGeneratedAssetRetries*2)
+}
+
+func TestGeneratedAssetKeys(t *testing.T) {
+	assert.Equal(t, []string{"filename.txt"}, generatedAssetKeys("filename.txt", []string{"Cannot overwrite generated asset"}))
+	assert.Equal(t, []string{"assets/a.css"}, generatedAssetKeys("filename.txt", []string{"Cannot overwrite generated asset assets/a.css", "is too big"}))
+	assert.Equal(t, []string{}, generatedAssetKeys("filename.txt", []string{"is too big"}))
+}
+
+func TestThemeClient_UpdateAssetFromReader(t *testing.T) {
+	video := append([]byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p'}, bytes.Repeat([]byte{0xde, 0xad}, 4096)...)
+	expected := json.RawMessage(`{"asset":{"key":"assets/intro.mp4","attachment":"` + base64.StdEncoding.EncodeToString(video) + `"}}`)
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", expected).Return(jsonResponse(`{"asset":{"key":"assets/intro.mp4"}}`, 200), nil)
+	assert.Nil(t, client.UpdateAssetFromReader("assets/intro.mp4", bytes.NewReader(video)))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", json.RawMessage(`{"asset":{"key":"snippets/bad.liquid","value":"{% if %}"}}`)).
+		Return(jsonResponse(`{"errors":{"asset":["Liquid syntax error"]}}`, 422), nil)
+	assert.EqualError(t, client.UpdateAssetFromReader("snippets/bad.liquid", strings.NewReader("{% if %}")), "Liquid syntax error")
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	err := client.UpdateAssetFromReader("assets/app.js", iotest.ErrReader(errors.New("disk error")))
+	assert.EqualError(t, err, "disk error")
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+
+	client, _ = NewClient(&env.Env{ThemeID: "123", DryRun: true})
+	client.http = m
+	assert.Nil(t, client.UpdateAssetFromReader("assets/app.js", strings.NewReader("alert()")))
+}
+
+func TestThemeClient_UpdateAssetFromURL(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.MatchedBy(func(body interface{}) bool {
+		data, _ := json.Marshal(body)
+		return string(data) == `{"asset":{"key":"assets/logo.png","src":"https://example.com/images/logo.png"}}`
+	})).Return(jsonResponse(`{"asset":{"key":"assets/logo.png","public_url":"https://cdn.shopify.com/s/files/1/logo.png"}}`, 200), nil)
+	assert.Nil(t, client.UpdateAssetFromURL("assets/logo.png", "https://example.com/images/logo.png"))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything).
+		Return(jsonResponse(`{"errors":{"asset":["src could not be downloaded"]}}`, 422), nil)
+	assert.EqualError(t, client.UpdateAssetFromURL("assets/logo.png", "http://example.com/missing.png"), "src could not be downloaded")
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	for _, src := range []string{"ftp://example.com/logo.png", "file:///etc/passwd", "/images/logo.png", "https://", "%zz"} {
+		err := client.UpdateAssetFromURL("assets/logo.png", src)
+		assert.True(t, errors.Is(err, ErrInvalidSrc), src)
+	}
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+
+	client, _ = NewClient(&env.Env{ThemeID: "123", DryRun: true})
+	client.http = m
+	assert.Nil(t, client.UpdateAssetFromURL("assets/logo.png", "https://example.com/images/logo.png"))
+}
+
+func TestThemeClient_UpdateAssetFromReaderAs(t *testing.T) {
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", json.RawMessage(`{"asset":{"key":"assets/data","attachment":"aGVsbG8="}}`)).
+		Return(jsonResponse(`{"asset":{"key":"assets/data"}}`, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", json.RawMessage(`{"asset":{"key":"assets/logo.png","value":"\u0000png"}}`)).
+		Return(jsonResponse(`{"asset":{"key":"assets/logo.png"}}`, 200), nil)
+	assert.Nil(t, client.UpdateAssetFromReaderAs("assets/data", strings.NewReader("hello"), true))
+	assert.Nil(t, client.UpdateAssetFromReaderAs("assets/logo.png", strings.NewReader("\x00png"), false))
+	m.AssertExpectations(t)
+}
+
+func TestThemeClient_UpdateAssetIfUnmodified(t *testing.T) {
+	base, _ := time.Parse(time.RFC3339, "2018-05-01T10:00:00-04:00")
+	testcases := []struct {
+		resp, err string
+		code      int
+		base      time.Time
+		updates   bool
+	}{
+		{resp: `{"asset":{"key":"assets/app.js","updated_at":"2018-05-01T09:00:00-04:00"}}`, code: 200, base: base, updates: true},
+		{resp: `{"asset":{"key":"assets/app.js","updated_at":"2018-05-01T10:00:00-04:00"}}`, code: 200, base: base, updates: true},
+		{resp: `{"asset":{"key":"assets/app.js","updated_at":"2018-05-01T11:00:00-04:00"}}`, code: 200, base: base, err: ErrAssetConflict.Error()},
+		{resp: `{"asset":{"key":"assets/app.js","updated_at":"yesterday"}}`, code: 200, base: base, err: ErrMalformedResponse.Error()},
+		{resp: `{}`, code: 404, base: base, updates: true},
+		{updates: true},
+	}
+
+	for _, testcase := range testcases {
+		m := new(mocks.HttpAdapter)
+		client, _ := NewClient(&env.Env{ThemeID: "123"})
+		client.http = m
+
+		if testcase.resp != "" {
+			m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.js&fields=key%2Cupdated_at").Return(jsonResponse(testcase.resp, testcase.code), nil)
+		}
+		m.On("Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything).Return(jsonResponse(`{"asset":{"key":"assets/app.js"}}`, 200), nil)
+
+		err := client.UpdateAssetIfUnmodified(Asset{Key: "assets/app.js", Value: "new"}, testcase.base)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+			assert.True(t, errors.Is(err, ErrAssetConflict) == (testcase.err == ErrAssetConflict.Error()))
+		}
+
+		if testcase.updates {
+			m.AssertCalled(t, "Put", mock.Anything, "/admin/themes/123/assets.json", mock.Anything)
+		} else {
+			m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestThemeClient_BulkUpdateAssets(t *testing.T) {
+	assets := []Asset{{Key: "assets/a.js"}, {Key: "assets/b.js"}, {Key: "assets/c.js"}}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", BatchSize: 2})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[:2]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/a.js"}}},{"code":200,"body":{"asset":{"key":"assets/b.js"}}}]}`, 207), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[2:]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/c.js"}}}]}`, 207), nil)
+	assert.Nil(t, client.BulkUpdateAssets(assets))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[:2]}).
+		Return(jsonResponse(`{"results":[{"code":422,"body":{"errors":{"asset":["Liquid syntax error"]}}},{"code":200,"body":{"asset":{"key":"assets/b.js"}}}]}`, 207), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[2:]}).
+		Return(nil, errors.New("(Client.Timeout exceeded while awaiting headers)"))
+	err := client.BulkUpdateAssets(assets)
+	assert.EqualError(t, err, "could not update assets/a.js (Liquid syntax error) and assets/c.js ((Client.Timeout exceeded while awaiting headers))")
+	m.AssertExpectations(t)
+
+	client, _ = NewClient(&env.Env{ThemeID: "123"})
+	assert.Equal(t, defaultBatchSize, client.batchSize)
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets}).
+		Return(jsonResponse(`{"results":[]}`, 207), nil)
+	err = client.BulkUpdateAssets(assets)
+	assert.Contains(t, err.Error(), ErrMalformedResponse.Error())
+}
+
+func TestThemeClient_BulkUpdateAssetsRetry(t *testing.T) {
+	settings := Asset{Key: SettingsDataKey, Value: "{}"}
+	css := Asset{Key: "assets/app.css", Value: "body {}"}
+	js := Asset{Key: "assets/app.js", Value: "alert()"}
+	rejected := `{"results":[
+		{"code":422,"body":{"errors":{"asset":["Cannot overwrite generated asset assets/app.css"]}}},
+		{"code":422,"body":{"errors":{"asset":["Liquid syntax error"]}}},
+		{"code":422,"body":{"errors":{"asset":["Setting 'header' refers to a section that does not exist"]}}}
+	]}`
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	client.retryDelay = 0
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": {css, js, settings}}).Return(jsonResponse(rejected, 207), nil)
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.css.liquid").Return(jsonResponse(`{}`, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": css}).Return(jsonResponse(`{"asset":{"key":"assets/app.css"}}`, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": settings}).Return(jsonResponse(`{"asset":{"key":"config/settings_data.json"}}`, 200), nil)
+
+	results, err := client.BulkUpdateAssetsResults([]Asset{css, js, settings})
+	assert.EqualError(t, err, "could not update assets/app.js (Liquid syntax error)")
+	if assert.Equal(t, 3, len(results)) {
+		assert.True(t, results[0].Success)
+		assert.False(t, results[1].Success)
+		assert.True(t, results[2].Success)
+	}
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": js})
+}
+
+func TestThemeClient_BulkUpdateAssetsOrder(t *testing.T) {
+	assets := []Asset{
+		{Key: "templates/index.liquid"},
+		{Key: "config/settings_data.json"},
+		{Key: "sections/header.liquid"},
+		{Key: "config/settings_schema.json"},
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", BatchSize: 2})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": {assets[3], assets[2]}}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{}},{"code":422,"body":{"errors":{"asset":["Liquid syntax error"]}}}]}`, 207), nil).Once()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": {assets[0], assets[1]}}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{}},{"code":200,"body":{}}]}`, 207), nil).Once()
+	results, err := client.BulkUpdateAssetsResults(assets)
+	assert.EqualError(t, err, "could not update sections/header.liquid (Liquid syntax error)")
+	m.AssertExpectations(t)
+
+	// the results keep the order of the assets
+	if assert.Equal(t, 4, len(results)) {
+		assert.Equal(t, "templates/index.liquid", results[0].Key)
+		assert.False(t, results[2].Success)
+		assert.True(t, results[3].Success)
+	}
+}
+
+func TestThemeClient_ValidateLiquid(t *testing.T) {
+	invalid := Asset{Key: "templates/index.liquid", Value: "{% if product %}\n{{ product.title }}"}
+	valid := Asset{Key: "templates/page.liquid", Value: "{% if page %}{{ page.title }}{% endif %}"}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", ValidateLiquid: true})
+	client.http = m
+	err := client.UpdateAsset(invalid)
+	assert.EqualError(t, err, "invalid liquid: templates/index.liquid:1: if is not closed, expected endif")
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": {valid}}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"templates/page.liquid"}}}]}`, 207), nil)
+	results, err := client.BulkUpdateAssetsResults([]Asset{invalid, valid})
+	assert.Contains(t, err.Error(), "could not update templates/index.liquid")
+	assert.False(t, results[0].Success)
+	assert.True(t, errors.Is(results[0].Error, ErrInvalidLiquid))
+	assert.True(t, results[1].Success)
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client, _ = NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets.json", map[string]Asset{"asset": invalid}).Return(jsonResponse(`{"asset":{"key":"templates/index.liquid"}}`, 200), nil)
+	assert.Nil(t, client.UpdateAsset(invalid))
+}
+
+func TestThemeClient_BulkUpdateAssetsResults(t *testing.T) {
+	assets := []Asset{{Key: "assets/a.js"}, {Key: "assets/bad.liquid"}, {Key: "assets/c.js"}}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", BatchSize: 2})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[:2]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/a.js"}}},{"code":422,"body":{"errors":{"asset":["Liquid syntax error"]}}}]}`, 207), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[2:]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/c.js"}}}]}`, 207), nil)
+	results, err := client.BulkUpdateAssetsResults(assets)
+	m.AssertExpectations(t)
+
+	assert.EqualError(t, err, "could not update assets/bad.liquid (Liquid syntax error)")
+	var bulkErr *BulkError
+	if assert.True(t, errors.As(err, &bulkErr)) {
+		assert.Equal(t, results, bulkErr.Results)
+		assert.Equal(t, 1, len(bulkErr.Failed()))
+	}
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, "Liquid syntax error", apiErr.ShopifyMessage)
+	}
+
+	if assert.Equal(t, 3, len(results)) {
+		assert.Equal(t, AssetResult{Key: "assets/a.js", Success: true}, results[0])
+		assert.Equal(t, "assets/bad.liquid", results[1].Key)
+		assert.False(t, results[1].Success)
+		assert.NotNil(t, results[1].Error)
+		assert.Equal(t, AssetResult{Key: "assets/c.js", Success: true}, results[2])
+	}
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[:2]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/a.js"}}},{"code":200,"body":{"asset":{"key":"assets/bad.liquid"}}}]}`, 207), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": assets[2:]}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/c.js"}}}]}`, 207), nil)
+	results, err = client.BulkUpdateAssetsResults(assets)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	for _, result := range results {
+		assert.True(t, result.Success)
+	}
+
+	client, _ = NewClient(&env.Env{ThemeID: "123", DryRun: true})
+	results, err = client.BulkUpdateAssetsResults(assets)
+	assert.Nil(t, err)
+	assert.Equal(t, AssetResult{Key: "assets/c.js", Success: true}, results[2])
+}
+
 func TestThemeClient_DeleteAsset(t *testing.T) {
 	testcases := []struct {
 		code               int
@@ -380,7 +1788,7 @@ func TestThemeClient_DeleteAsset(t *testing.T) {
 		client, _ := NewClient(&env.Env{ThemeID: "123"})
 		client.http = m
 
-		expectation := m.On("Delete", "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt")
+		expectation := m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=filename.txt")
 		if testcase.resperr != "" {
 			expectation.Return(nil, errors.New(testcase.resperr))
 		} else {
@@ -399,25 +1807,152 @@ func TestThemeClient_DeleteAsset(t *testing.T) {
 	}
 }
 
+func TestThemeClient_BulkDeleteAssets(t *testing.T) {
+	path := func(key string) string {
+		return "/admin/themes/123/assets.json?asset%5Bkey%5D=" + url.QueryEscape(key)
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Delete", mock.Anything, path("assets/a.js")).Return(jsonResponse(`{}`, 200), nil)
+	m.On("Delete", mock.Anything, path("assets/b.js")).Return(jsonResponse(`{}`, 404), nil)
+	m.On("Delete", mock.Anything, path("snippets/c.liquid")).Return(nil, errors.New("server error"))
+	m.On("Delete", mock.Anything, path("snippets/d.liquid")).Return(jsonResponse(`{}`, 200), nil)
+
+	results, err := client.BulkDeleteAssetsResults([]string{"assets/a.js", "assets/b.js", "layout/theme.liquid", "snippets/c.liquid", "snippets/d.liquid"}, false)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "could not delete assets/b.js (this file is not part of your theme), layout/theme.liquid (this file is critical and removing it would cause your theme to become non-functional), and snippets/c.liquid (server error)", err.Error())
+		var bulkErr *BulkError
+		if assert.True(t, errors.As(err, &bulkErr)) {
+			assert.Equal(t, 3, len(bulkErr.Failed()))
+		}
+	}
+	assert.Equal(t, "assets/a.js", results[0].Key)
+	assert.True(t, results[0].Success)
+	assert.True(t, errors.Is(results[1].Error, ErrNotPartOfTheme))
+	assert.Equal(t, ErrCriticalFile, results[2].Error)
+	assert.EqualError(t, results[3].Error, "server error")
+	assert.True(t, results[4].Success)
+	m.AssertNotCalled(t, "Delete", mock.Anything, path("layout/theme.liquid"))
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Delete", mock.Anything, path("layout/theme.liquid")).Return(jsonResponse(`{}`, 200), nil)
+	results, err = client.BulkDeleteAssetsResults([]string{"layout/theme.liquid"}, true)
+	assert.Nil(t, err)
+	assert.True(t, results[0].Success)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Delete", mock.Anything, path("assets/a.js")).Return(jsonResponse(`{}`, 200), nil)
+	assert.Nil(t, client.BulkDeleteAssets([]string{"assets/a.js"}))
+	assert.Nil(t, client.BulkDeleteAssets([]string{}))
+	m.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestThemeClient_Mirror(t *testing.T) {
+	remote := `{"assets":[{"key":"assets/a.js"},{"key":"assets/old.js"},{"key":"assets/gen.css"},{"key":"assets/app.css"},{"key":"assets/app.css.liquid"},{"key":"config/settings_data.json"},{"key":"snippets/keep.liquid"}]}`
+	local := map[string]Asset{
+		"assets/a.js":               {Key: "assets/a.js", Value: "a"},
+		"assets/gen.css.liquid":     {Key: "assets/gen.css.liquid", Value: "gen"},
+		"config/settings_data.json": {Key: "config/settings_data.json", Value: "{}"},
+	}
+	e := &env.Env{ThemeID: "123", IgnoredFiles: []string{"config/settings_data.json", "snippets/keep.liquid"}}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(e)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(remote, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": {local["assets/a.js"], local["assets/gen.css.liquid"]}}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/a.js"}}},{"code":200,"body":{"asset":{"key":"assets/gen.css.liquid"}}}]}`, 200), nil)
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.css.liquid").Return(jsonResponse(`{}`, 200), nil)
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fold.js").Return(jsonResponse(`{}`, 200), nil)
+
+	deleted, err := client.Mirror(local)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, deleted)
+	m.AssertExpectations(t)
+	m.AssertNumberOfCalls(t, "Delete", 2)
+	for _, key := range []string{"config%2Fsettings_data.json", "snippets%2Fkeep.liquid", "assets%2Fgen.css", "assets%2Fapp.css", "assets%2Fa.js"} {
+		m.AssertNotCalled(t, "Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D="+key)
+	}
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(remote, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", mock.Anything).Return(nil, errors.New("connection reset"))
+
+	deleted, err = client.Mirror(local)
+	assert.Equal(t, 0, deleted)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not update")
+	}
+	m.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"assets/a.js"},{"key":"layout/theme.liquid"},{"key":"assets/old.js"}]}`, 200), nil)
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", mock.Anything).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{"asset":{"key":"assets/a.js"}}},{"code":200,"body":{"asset":{"key":"assets/gen.css.liquid"}}}]}`, 200), nil)
+	m.On("Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fold.js").Return(jsonResponse(`{}`, 200), nil)
+
+	deleted, err = client.Mirror(local)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, deleted)
+	m.AssertNumberOfCalls(t, "Delete", 1)
+	m.AssertNotCalled(t, "Delete", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid")
+}
+
 func TestThemeClient_assetPath(t *testing.T) {
 	testcases := []struct {
-		query         map[string]string
-		themeID, path string
+		query                  map[string]string
+		themeID, version, path string
 	}{
 		{themeID: "123", path: "/admin/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid", query: map[string]string{"asset[key]": "layout/theme.liquid"}},
 		{path: "/admin/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid", query: map[string]string{"asset[key]": "layout/theme.liquid"}},
 		{themeID: "123", path: "/admin/themes/123/assets.json"},
 		{path: "/admin/assets.json"},
+		{themeID: "123", version: "2024-01", path: "/admin/api/2024-01/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid", query: map[string]string{"asset[key]": "layout/theme.liquid"}},
+		{version: "2024-01", path: "/admin/api/2024-01/assets.json"},
 	}
 
 	for _, testcase := range testcases {
-		client, _ := NewClient(&env.Env{ThemeID: testcase.themeID})
+		client, _ := NewClient(&env.Env{ThemeID: testcase.themeID, APIVersion: testcase.version})
 		path := client.assetPath(testcase.query)
 		assert.Equal(t, testcase.path, path)
 	}
 }
 
+func TestThemeClient_versionedPaths(t *testing.T) {
+	testcases := []struct {
+		version, themeID   string
+		admin, theme, bulk string
+	}{
+		{themeID: "123", admin: "/admin/themes.json", theme: "/admin/themes/456.json", bulk: "/admin/themes/123/assets/bulk.json"},
+		{admin: "/admin/themes.json", theme: "/admin/themes/456.json", bulk: "/admin/assets/bulk.json"},
+		{version: "2024-01", themeID: "123", admin: "/admin/api/2024-01/themes.json", theme: "/admin/api/2024-01/themes/456.json", bulk: "/admin/api/2024-01/themes/123/assets/bulk.json"},
+		{version: "unstable", admin: "/admin/api/unstable/themes.json", theme: "/admin/api/unstable/themes/456.json", bulk: "/admin/api/unstable/assets/bulk.json"},
+	}
+
+	for _, testcase := range testcases {
+		client, _ := NewClient(&env.Env{ThemeID: testcase.themeID, APIVersion: testcase.version})
+		assert.Equal(t, testcase.admin, client.adminPath("/themes.json"))
+		assert.Equal(t, testcase.theme, client.themePath("456"))
+		assert.Equal(t, testcase.bulk, client.bulkAssetPath())
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{APIVersion: "2024-01"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/api/2024-01/shop.json").Return(jsonResponse(`{"shop":{"id":1}}`, 200), nil)
+	shop, err := client.GetShop()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), shop.ID)
+}
+
 func TestUnmarshalResponse(t *testing.T) {
+	client, _ := NewClient(&env.Env{})
 	testcases := []struct {
 		input, err    string
 		out, expected themeResponse
@@ -429,7 +1964,7 @@ func TestUnmarshalResponse(t *testing.T) {
 	}
 
 	for _, testcase := range testcases {
-		err := unmarshalResponse(&StringReadCloser{strings.NewReader(testcase.input)}, &testcase.out)
+		err := client.unmarshalResponse(jsonResponse(testcase.input, 200), &testcase.out)
 		assert.Equal(t, testcase.expected, testcase.out)
 		if testcase.err == "" {
 			assert.Nil(t, err)
@@ -439,9 +1974,62 @@ func TestUnmarshalResponse(t *testing.T) {
 	}
 
 	out := assetsResponse{}
-	err := unmarshalResponse(&StringReadCloser{strings.NewReader(`{"errors":"oh no"}`)}, &out)
+	err := client.unmarshalResponse(jsonResponse(`{"errors":"oh no"}`, 422), &out)
 	assert.NotNil(t, err)
 	assert.Equal(t, err.Error(), "oh no")
+	if apiErr, ok := err.(*APIError); assert.True(t, ok) {
+		assert.Equal(t, 422, apiErr.StatusCode)
+		assert.Equal(t, "oh no", apiErr.ShopifyMessage)
+	}
+
+	err = client.unmarshalResponse(jsonResponse(`not json`, 502), &out)
+	assert.True(t, errors.Is(err, ErrMalformedResponse))
+	assert.EqualError(t, err, `received a malformed response: could not parse shopify.assetsResponse, invalid character 'o' in literal null (expecting 'u') in "no"...`)
+
+	var parseErr *ParseError
+	err = client.unmarshalResponse(jsonResponse(`{"assets":[{"key":"a.js","theme_id":"123"}]}`, 200), &assetsResponse{})
+	assert.True(t, errors.Is(err, ErrMalformedResponse))
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, "shopify.assetsResponse", parseErr.Type)
+		assert.Equal(t, "assets.0.theme_id", parseErr.Field)
+		assert.Equal(t, "int64 but was string", parseErr.Expected)
+	}
+	assert.EqualError(t, err, `received a malformed response: could not parse shopify.assetsResponse, assets.0.theme_id should be int64 but was string in "{\"assets\":[{\"key\":\"a.js\",\"theme_id\":\"123\""...`)
+
+	err = client.unmarshalResponse(jsonResponse(`{"assets":[{"key":"a.js"},`, 200), &out)
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, "", parseErr.Field)
+		assert.Equal(t, `"{\"assets\":[{\"key\":\"a.js\"},"`, parseErr.Snippet)
+	}
+}
+
+func TestUnmarshalResponse_MaxBody(t *testing.T) {
+	client, _ := NewClient(&env.Env{})
+	assert.Equal(t, int64(defaultMaxResponseBytes), client.maxBody)
+
+	out := themeResponse{}
+	body := `{"theme":{"id":1}}`
+	client, _ = NewClient(&env.Env{MaxResponseBytes: len(body)})
+	assert.Nil(t, client.unmarshalResponse(jsonResponse(body, 200), &out))
+	assert.Equal(t, int64(1), out.Theme.ID)
+
+	html := "<html>" + strings.Repeat("bad gateway ", 1000) + "</html>"
+	err := client.unmarshalResponse(jsonResponse(html, 502), &out)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	assert.EqualError(t, err, "response too large, the body is over the limit of 18 bytes")
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 502, apiErr.StatusCode)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	body := []byte(strings.Repeat("a", 300))
+	assert.Equal(t, `""`, snippet([]byte{}, 0))
+	assert.Equal(t, `"abc"`, snippet([]byte("abc"), 0))
+	assert.Equal(t, `"ab"...`, snippet([]byte("abc"), 2))
+	assert.Equal(t, `..."`+strings.Repeat("a", snippetLength)+`"`, snippet(body, 0))
+	assert.Equal(t, `"`+strings.Repeat("a", 100)+`"...`, snippet(body, 100))
 }
 
 func TestToMessages(t *testing.T) {
@@ -452,11 +2040,14 @@ func TestToMessages(t *testing.T) {
 		{input: map[string][]string{"src": {"is empty"}}, expected: []string{"src is empty"}},
 		{input: map[string][]string{}, expected: []string{}},
 		{input: map[string][]string{"name": {"can't be blank"}}, expected: []string{"name can't be blank"}},
+		{input: map[string][]string{"src": {"is empty"}, "name": {"can't be blank", "is too long"}, "role": {"is invalid"}}, expected: []string{"name can't be blank", "name is too long", "role is invalid", "src is empty"}},
 	}
 
 	for _, testcase := range testcases {
-		actual := toMessages(testcase.input)
-		assert.Equal(t, testcase.expected, actual)
+		for i := 0; i < 20; i++ {
+			actual := toMessages(testcase.input)
+			assert.Equal(t, testcase.expected, actual)
+		}
 	}
 }
 
@@ -475,6 +2066,19 @@ func TestToSentence(t *testing.T) {
 		actual := toSentence(testcase.input)
 		assert.Equal(t, testcase.expected, actual)
 	}
+
+	messages := []string{"src is empty", "name can't be blank", "role is invalid"}
+	assert.Equal(t, "src is empty, name can't be blank and role is invalid", SentenceStyle{Conjunction: "and", Separator: ", "}.Join(messages))
+	assert.Equal(t, "src is empty, name can't be blank et role is invalid", SentenceStyle{Conjunction: "et", Separator: ", "}.Join(messages))
+	assert.Equal(t, "src is empty und name can't be blank", SentenceStyle{Conjunction: "und", Separator: ", "}.Join(messages[:2]))
+	assert.Equal(t, "src is empty; name can't be blank; or role is invalid", SentenceStyle{Conjunction: "or", Separator: "; ", SerialComma: true}.Join(messages))
+
+	defer func() { Sentences = EnglishSentence }()
+	Sentences = SentenceStyle{Conjunction: "y", Separator: ", "}
+	assert.Equal(t, "could not update a.js (timeout) y b.js (timeout)", (&BulkError{Results: []AssetResult{
+		{Key: "a.js", Error: errors.New("timeout")},
+		{Key: "b.js", Error: errors.New("timeout")},
+	}}).Error())
 }
 
 type StringReadCloser struct {
@@ -0,0 +1,98 @@
+package shopify
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ExportZip will download every asset of the theme and write them into a zip with
+// the asset keys as paths, like layout/theme.liquid, which is the same layout as
+// the theme zips shopify accepts. Binary attachments are decoded. The assets are
+// streamed into the zip one at a time, reporting progress as they are, so large
+// themes are never held in memory. Assets that are generated from a .liquid file
+// are left out because they are generated again when the theme is imported.
+func (c Client) ExportZip(w io.Writer) error {
+	return c.ExportZipContext(context.Background(), w)
+}
+
+// ExportZipContext is the same as ExportZip but the requests are bound to the
+// context so they can be cancelled.
+func (c Client) ExportZipContext(ctx context.Context, w io.Writer) error {
+	listed, err := c.listAssets(ctx, "key")
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for key := range withoutGenerated(listed) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	archive := zip.NewWriter(w)
+	for index, key := range keys {
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: key, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if err := c.GetAssetStreamContext(ctx, key, entry); err != nil {
+			return fmt.Errorf("could not export %s: %w", key, err)
+		}
+		c.reportProgress(key, index+1, len(keys))
+	}
+	return archive.Close()
+}
+
+// ImportZip will upload every file in the zip as an asset of the theme, using the
+// file paths as keys, so that a zip written by ExportZip can be restored into any
+// theme. Files matching the ignore patterns of the config are skipped and whether
+// a file is sent as an attachment is detected from its name and contents. The
+// assets are uploaded in bulk, see BulkUpdateAssets. A zip can only be read once it
+// is complete so the whole zip is read into memory first.
+func (c Client) ImportZip(r io.Reader) error {
+	return c.ImportZipContext(context.Background(), r)
+}
+
+// ImportZipContext is the same as ImportZip but the requests are bound to the
+// context so they can be cancelled.
+func (c Client) ImportZipContext(ctx context.Context, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	assets := []Asset{}
+	for _, entry := range archive.File {
+		key := strings.TrimPrefix(entry.Name, "./")
+		if entry.FileInfo().IsDir() || !c.filter.Included(key) || c.filter.Match(key) {
+			continue
+		}
+
+		contents, err := readZipEntry(entry)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", entry.Name, err)
+		}
+		assets = append(assets, NewAsset(key, contents))
+	}
+
+	return c.BulkUpdateAssetsContext(ctx, assets)
+}
+
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	r, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
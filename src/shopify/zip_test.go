@@ -0,0 +1,106 @@
+package shopify
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/shopify/_mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestThemeClient_ExportZip(t *testing.T) {
+	logo := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	listed := `{"assets":[{"key":"layout/theme.liquid"},{"key":"assets/logo.png"},{"key":"assets/app.css.liquid"},{"key":"assets/app.css"}]}`
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123"})
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(listed, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(jsonResponse(`{"asset":{"key":"layout/theme.liquid","value":"{{ content_for_layout }}"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Flogo.png").Return(jsonResponse(`{"asset":{"key":"assets/logo.png","attachment":"`+base64.StdEncoding.EncodeToString(logo)+`"}}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=assets%2Fapp.css.liquid").Return(jsonResponse(`{"asset":{"key":"assets/app.css.liquid","value":"body {}"}}`, 200), nil)
+
+	recorder := &progressRecorder{}
+	client.SetProgressReporter(recorder)
+	var buf bytes.Buffer
+	assert.Nil(t, client.ExportZip(&buf))
+	m.AssertExpectations(t)
+	assert.Equal(t, 3, len(recorder.calls))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if assert.Nil(t, err) {
+		contents := map[string]string{}
+		for _, entry := range archive.File {
+			r, _ := entry.Open()
+			data, _ := ioutil.ReadAll(r)
+			r.Close()
+			contents[entry.Name] = string(data)
+		}
+		assert.Equal(t, map[string]string{
+			"assets/app.css.liquid": "body {}",
+			"assets/logo.png":       string(logo),
+			"layout/theme.liquid":   "{{ content_for_layout }}",
+		}, contents)
+	}
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{"assets":[{"key":"layout/theme.liquid"}]}`, 200), nil)
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?asset%5Bkey%5D=layout%2Ftheme.liquid").Return(jsonResponse(`{}`, 404), nil)
+	err = client.ExportZip(&bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrNotPartOfTheme))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not export layout/theme.liquid")
+	}
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	m.On("Get", mock.Anything, "/admin/themes/123/assets.json?fields=key").Return(jsonResponse(`{}`, 404), nil)
+	assert.True(t, errors.Is(client.ExportZip(&bytes.Buffer{}), ErrThemeNotFound))
+}
+
+func TestThemeClient_ImportZip(t *testing.T) {
+	logo := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	archive.Create("layout/")
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"layout/theme.liquid", []byte("{{ content_for_layout }}")},
+		{"assets/logo.png", logo},
+		{"config/settings_schema.json", []byte("[]")},
+		{"assets/app.scss", []byte("body {}")},
+	} {
+		entry, _ := archive.Create(file.name)
+		entry.Write(file.data)
+	}
+	archive.Close()
+
+	uploads := []Asset{
+		{Key: "config/settings_schema.json", Value: "[]"},
+		{Key: "assets/logo.png", Attachment: base64.StdEncoding.EncodeToString(logo)},
+	}
+
+	m := new(mocks.HttpAdapter)
+	client, _ := NewClient(&env.Env{ThemeID: "123", IgnoredFiles: []string{"*.scss"}, BatchSize: 2})
+	client.http = m
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": uploads}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{}},{"code":200,"body":{}}]}`, 207), nil).Once()
+	m.On("Put", mock.Anything, "/admin/themes/123/assets/bulk.json", map[string][]Asset{"assets": {{Key: "layout/theme.liquid", Value: "{{ content_for_layout }}"}}}).
+		Return(jsonResponse(`{"results":[{"code":200,"body":{}}]}`, 207), nil).Once()
+	assert.Nil(t, client.ImportZip(bytes.NewReader(buf.Bytes())))
+	m.AssertExpectations(t)
+
+	m = new(mocks.HttpAdapter)
+	client.http = m
+	assert.NotNil(t, client.ImportZip(bytes.NewReader([]byte("not a zip"))))
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+}
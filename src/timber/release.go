@@ -2,18 +2,32 @@ package timber
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
 	"text/template"
+	"time"
 
 	"github.com/Shopify/themekit/src/atom"
 )
 
 const (
 	timberFeedPath = "https://github.com/Shopify/Timber/releases.atom"
+	maxAttempts    = 3
 )
 
 var (
+	// ErrRateLimited is returned when github refuses to serve the release feed
+	// because too many requests were made.
+	ErrRateLimited = errors.New("github rate limit exceeded, set GITHUB_TOKEN to raise the limit")
+	// ErrOfflineLatest is returned when the latest version is requested offline and
+	// there is no cached release feed to look it up in.
+	ErrOfflineLatest = errors.New("cannot find the latest version offline, please request a specific release tag")
+
 	invalidVersionTmplt = template.Must(template.New("invalidVersionError").Parse(`Invalid Timber Version: {{ .Requested }}
   Available Versions Are:
   - master
@@ -21,21 +35,52 @@ var (
   {{- range .Versions }}
   - {{ . }}
   {{- end }}`))
+
+	retryDelay = 500 * time.Millisecond
+	sleep      = time.Sleep
 )
 
+// Options change how a version is looked up
+type Options struct {
+	// Offline will skip fetching the release feed. A release tag is used as is and
+	// latest is only available if the release feed was cached before.
+	Offline bool
+	// CacheTTL is how long a downloaded release feed is reused. Zero disables caching.
+	CacheTTL time.Duration
+	// CacheDir is the directory the release feed is cached in. It defaults to the
+	// user's cache directory.
+	CacheDir string
+	// Token is a github token sent with the request to raise the rate limit. It
+	// defaults to the GITHUB_TOKEN environment variable.
+	Token string
+}
+
 // GetVersionPath will return the download path for a requested version
 func GetVersionPath(version string) (string, error) {
-	return getVersionPath(version, timberFeedPath)
+	return GetVersionPathWithOptions(version, Options{})
+}
+
+// GetVersionPathWithOptions is the same as GetVersionPath but allows to change how
+// the version is looked up.
+func GetVersionPathWithOptions(version string, opts Options) (string, error) {
+	if opts.Token == "" {
+		opts.Token = os.Getenv("GITHUB_TOKEN")
+	}
+	return getVersionPath(version, timberFeedPath, opts)
 }
 
-func getVersionPath(version, feedPath string) (string, error) {
+func getVersionPath(version, feedPath string, opts Options) (string, error) {
 	if version == "master" {
 		return "https://github.com/Shopify/Timber/archive/master.zip", nil
+	} else if opts.Offline && version != "latest" {
+		return "https://github.com/Shopify/Timber/archive/" + version + ".zip", nil
 	}
 
-	feed, err := downloadThemeReleaseAtomFeed(feedPath)
+	feed, err := loadFeed(feedPath, opts)
 	if err != nil {
 		return "", err
+	} else if opts.Offline && len(feed.Entries) == 0 {
+		return "", ErrOfflineLatest
 	}
 
 	entry, err := findThemeReleaseWith(feed, version)
@@ -46,19 +91,107 @@ func getVersionPath(version, feedPath string) (string, error) {
 	return "https://github.com/Shopify/Timber/archive/" + entry.Title + ".zip", nil
 }
 
-func downloadThemeReleaseAtomFeed(feedPath string) (atom.Feed, error) {
-	resp, err := http.Get(feedPath)
+// loadFeed will read the release feed from the cache if it is recent enough and
+// download it otherwise. When offline any cached feed is used and an empty feed is
+// returned if there is none.
+func loadFeed(feedPath string, opts Options) (atom.Feed, error) {
+	cachePath := ""
+	if opts.CacheTTL > 0 || opts.Offline {
+		cachePath = feedCachePath(opts.CacheDir)
+	}
+
+	if cachePath != "" {
+		if info, err := os.Stat(cachePath); err == nil && (opts.Offline || time.Since(info.ModTime()) < opts.CacheTTL) {
+			if data, err := ioutil.ReadFile(cachePath); err == nil {
+				if feed, err := atom.LoadFeed(bytes.NewReader(data)); err == nil {
+					return feed, nil
+				}
+			}
+		}
+	}
+
+	if opts.Offline {
+		return atom.Feed{}, nil
+	}
+
+	data, err := downloadThemeReleaseAtomFeed(feedPath, opts.Token)
 	if err != nil {
 		return atom.Feed{}, err
 	}
-	defer resp.Body.Close()
 
-	feed, err := atom.LoadFeed(resp.Body)
+	feed, err := atom.LoadFeed(bytes.NewReader(data))
 	if err != nil {
 		return atom.Feed{}, err
 	}
 
-	return feed, resp.Body.Close()
+	if cachePath != "" {
+		// failing to cache the feed should not stop the theme from being created
+		if os.MkdirAll(filepath.Dir(cachePath), 0755) == nil {
+			ioutil.WriteFile(cachePath, data, 0644)
+		}
+	}
+
+	return feed, nil
+}
+
+func feedCachePath(dir string) string {
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(cacheDir, "themekit")
+	}
+	return filepath.Join(dir, "timber_releases.atom")
+}
+
+// downloadThemeReleaseAtomFeed will fetch the release feed, retrying with a jittered
+// backoff if the request fails or github is temporarily unavailable.
+func downloadThemeReleaseAtomFeed(feedPath, token string) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			delay := retryDelay << uint(attempt-2)
+			sleep(delay + time.Duration(rand.Int63n(int64(delay))))
+		}
+
+		data, retry, err := fetchFeed(feedPath, token)
+		if err == nil || !retry {
+			return data, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func fetchFeed(feedPath, token string) (data []byte, retry bool, err error) {
+	req, err := http.NewRequest("GET", feedPath, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, false, ErrRateLimited
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, true, ErrRateLimited
+	case resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("could not fetch timber releases: %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return nil, false, fmt.Errorf("could not fetch timber releases: %s", resp.Status)
+	}
+
+	data, err = ioutil.ReadAll(resp.Body)
+	return data, true, err
 }
 
 func findThemeReleaseWith(feed atom.Feed, version string) (atom.Entry, error) {
@@ -80,5 +213,5 @@ func findThemeReleaseWith(feed atom.Feed, version string) (atom.Entry, error) {
 		Versions  []string
 	}{version, entries})
 
-	return atom.Entry{Title: "Invalid Feed"}, fmt.Errorf(tpl.String())
+	return atom.Entry{Title: "Invalid Feed"}, errors.New(tpl.String())
 }
@@ -2,10 +2,14 @@ package timber
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -37,7 +41,7 @@ func TestGetVersionPath(t *testing.T) {
 	defer ts.Close()
 
 	for _, testcase := range testcases {
-		path, err := getVersionPath(testcase.version, ts.URL+"/feed")
+		path, err := getVersionPath(testcase.version, ts.URL+"/feed", Options{})
 		if testcase.err == "" {
 			assert.Nil(t, err)
 			assert.Equal(t, path, testcase.path)
@@ -46,31 +50,133 @@ func TestGetVersionPath(t *testing.T) {
 		}
 	}
 
+	defer stubSleep()()
 	ts.Close()
-	_, err := getVersionPath("latest", ts.URL+"/feed")
+	_, err := getVersionPath("latest", ts.URL+"/feed", Options{})
 	assert.NotNil(t, err)
 }
 
+func TestGetVersionPathCached(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, releaseAtom)
+	}))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "timber")
+	defer os.RemoveAll(dir)
+	opts := Options{CacheTTL: time.Hour, CacheDir: dir}
+
+	for i := 0; i < 2; i++ {
+		path, err := getVersionPath("latest", ts.URL+"/feed", opts)
+		assert.Nil(t, err)
+		assert.Equal(t, "https://github.com/Shopify/Timber/archive/v2.0.2.zip", path)
+	}
+	assert.Equal(t, 1, requests)
+
+	stale := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "timber_releases.atom"), stale, stale)
+	_, err := getVersionPath("latest", ts.URL+"/feed", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, requests)
+
+	path, err := getVersionPath("latest", ts.URL+"/feed", Options{Offline: true, CacheDir: dir})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/Shopify/Timber/archive/v2.0.2.zip", path)
+	assert.Equal(t, 2, requests)
+}
+
+func TestGetVersionPathOffline(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "timber")
+	defer os.RemoveAll(dir)
+	opts := Options{Offline: true, CacheDir: dir}
+
+	path, err := getVersionPath("v1.3.0", "http://127.0.0.1:0/feed", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/Shopify/Timber/archive/v1.3.0.zip", path)
+
+	_, err = getVersionPath("latest", "http://127.0.0.1:0/feed", opts)
+	assert.Equal(t, ErrOfflineLatest, err)
+}
+
 func TestDownloadThemeReleaseAtomFeed(t *testing.T) {
+	defer stubSleep()()
+
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, releaseAtom)
 	}))
-	_, err := downloadThemeReleaseAtomFeed(ts.URL)
+	_, err := downloadThemeReleaseAtomFeed(ts.URL, "")
 	ts.Close()
 	assert.Nil(t, err)
-	_, err = downloadThemeReleaseAtomFeed(ts.URL)
+	_, err = downloadThemeReleaseAtomFeed(ts.URL, "")
 	assert.NotNil(t, err)
 
 	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "this is not an atom feed")
 	}))
 	defer ts.Close()
-	_, err = downloadThemeReleaseAtomFeed(ts.URL)
+	_, err = getVersionPath("latest", ts.URL, Options{})
 	if assert.NotNil(t, err) {
 		assert.Contains(t, err.Error(), "EOF")
 	}
 }
 
+func TestDownloadThemeReleaseAtomFeedRetries(t *testing.T) {
+	defer stubSleep()()
+
+	testcases := []struct {
+		codes    []int
+		requests int
+		err      string
+	}{
+		{codes: []int{502, 200}, requests: 2},
+		{codes: []int{500, 503, 502}, requests: 3, err: "502 Bad Gateway"},
+		{codes: []int{429, 429, 429}, requests: 3, err: ErrRateLimited.Error()},
+		{codes: []int{403}, requests: 1, err: "GITHUB_TOKEN"},
+		{codes: []int{404}, requests: 1, err: "404 Not Found"},
+	}
+
+	for _, testcase := range testcases {
+		requests := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			code := testcase.codes[requests]
+			requests++
+			w.WriteHeader(code)
+			fmt.Fprintln(w, releaseAtom)
+		}))
+
+		_, err := downloadThemeReleaseAtomFeed(ts.URL, "")
+		ts.Close()
+		assert.Equal(t, testcase.requests, requests)
+		if testcase.err == "" {
+			assert.Nil(t, err)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+	}
+}
+
+func TestDownloadThemeReleaseAtomFeedToken(t *testing.T) {
+	var auth []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprintln(w, releaseAtom)
+	}))
+	defer ts.Close()
+
+	_, err := downloadThemeReleaseAtomFeed(ts.URL, "secret")
+	assert.Nil(t, err)
+	_, err = downloadThemeReleaseAtomFeed(ts.URL, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"token secret", ""}, auth)
+}
+
+func stubSleep() func() {
+	sleep = func(time.Duration) {}
+	return func() { sleep = time.Sleep }
+}
+
 func TestFindThemeReleaseWith(t *testing.T) {
 	feed, _ := atom.LoadFeed(strings.NewReader(releaseAtom))
 